@@ -0,0 +1,117 @@
+// Package acptest provides helpers for exercising ACP handlers in tests.
+package acptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/sumup/acp"
+	"github.com/sumup/acp/signature"
+)
+
+// RequestBuilder builds server-side requests carrying the headers ACP
+// handlers expect, so tests can drive a provider through the real middleware
+// stack:
+//
+//	req, err := acptest.RequestBuilder{SigningKey: key, APIKey: "sk_test"}.New(http.MethodPost, "/agentic_commerce/delegate_payment", payload)
+//	handler.ServeHTTP(rec, req)
+//
+// The zero value builds unsigned, unauthenticated requests with the
+// API-Version header set.
+type RequestBuilder struct {
+	// SigningKey signs requests for [signature.HMACVerifier]; leave it empty
+	// to send unsigned requests.
+	SigningKey []byte
+	// SignedHeaders also covers these headers in the signature, matching
+	// [acp.WithSignedHeaders].
+	SignedHeaders []string
+	// SignatureEncoding matches [signature.HMACVerifier.Encoding]. Defaults
+	// to [signature.EncodingRawURL].
+	SignatureEncoding signature.Encoding
+	// Clock sets the Timestamp header. Defaults to time.Now.
+	Clock func() time.Time
+	// APIKey is sent as an Authorization bearer token when set.
+	APIKey string
+	// APIVersion overrides the API-Version header. Defaults to [acp.APIVersion].
+	APIVersion string
+	// IdempotencyKey sets the Idempotency-Key header when set.
+	IdempotencyKey string
+}
+
+// New builds a request with a JSON body. The body is marshaled with
+// encoding/json unless it already is a []byte or [json.RawMessage]; a nil
+// body sends no payload.
+func (b RequestBuilder) New(method, path string, body any) (*http.Request, error) {
+	var raw []byte
+	switch v := body.(type) {
+	case nil:
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("acptest: marshal body: %w", err)
+		}
+		raw = encoded
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	if raw != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	version := b.APIVersion
+	if version == "" {
+		version = acp.APIVersion
+	}
+	req.Header.Set("API-Version", version)
+	if b.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", b.IdempotencyKey)
+	}
+	if b.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.APIKey)
+	}
+	if len(b.SigningKey) == 0 {
+		return req, nil
+	}
+
+	canonical, err := signature.CanonicalForm(raw)
+	if err != nil {
+		return nil, fmt.Errorf("acptest: canonicalize body: %w", err)
+	}
+	clock := b.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	material := signature.Material{
+		Timestamp:     clock().UTC(),
+		CanonicalBody: canonical,
+		Headers:       req.Header,
+		SignedHeaders: signature.CanonicalHeaderNames(b.SignedHeaders),
+	}
+	sig, err := signature.HMACSigner{Key: b.SigningKey, Encoding: b.SignatureEncoding}.Sign(material)
+	if err != nil {
+		return nil, fmt.Errorf("acptest: sign request: %w", err)
+	}
+	req.Header.Set("Timestamp", material.Timestamp.Format(time.RFC3339Nano))
+	req.Header.Set("Signature", sig)
+	return req, nil
+}
+
+// NewSignedRequest builds a server-side request with a JSON body and the
+// Signature and Timestamp headers accepted by [signature.HMACVerifier] for key.
+// The body is marshaled with encoding/json unless it already is a []byte or
+// [json.RawMessage]; a nil body sends no payload. clock defaults to time.Now.
+// Use [RequestBuilder] for more headers.
+func NewSignedRequest(key []byte, clock func() time.Time, method, path string, body any) (*http.Request, error) {
+	if len(key) == 0 {
+		return nil, errors.New("acptest: signing key is required")
+	}
+	return RequestBuilder{SigningKey: key, Clock: clock}.New(method, path, body)
+}
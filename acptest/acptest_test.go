@@ -0,0 +1,182 @@
+package acptest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sumup/acp"
+	"github.com/sumup/acp/acptest"
+	"github.com/sumup/acp/signature"
+)
+
+func TestNewSignedRequest(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("test-secret")
+	now := time.Date(2025, 9, 29, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	tests := map[string]struct {
+		key        []byte
+		body       any
+		wantStatus int
+	}{
+		"struct body": {
+			key: key,
+			body: acp.CheckoutSessionCreateRequest{
+				Items: []acp.Item{{ID: "sku_1", Quantity: 1}},
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"raw body": {
+			key:        key,
+			body:       []byte(`{"items":[{"quantity":1, "id":"sku_1"}]}`),
+			wantStatus: http.StatusCreated,
+		},
+		"wrong key": {
+			key:        []byte("other-secret"),
+			body:       acp.CheckoutSessionCreateRequest{Items: []acp.Item{{ID: "sku_1", Quantity: 1}}},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := acp.NewCheckoutHandler(createProvider{},
+				acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}),
+				acp.WithRequireSignedRequests(),
+				acp.WithClock(clock),
+			)
+			req, err := acptest.NewSignedRequest(tt.key, clock, http.MethodPost, "/checkout_sessions", tt.body)
+			if err != nil {
+				t.Fatalf("NewSignedRequest() error = %v", err)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewSignedRequestRequiresKey(t *testing.T) {
+	t.Parallel()
+
+	if _, err := acptest.NewSignedRequest(nil, nil, http.MethodPost, "/checkout_sessions", nil); err == nil {
+		t.Fatalf("expected error without key")
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("test-secret")
+	now := time.Date(2025, 9, 29, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	body := acp.CheckoutSessionCreateRequest{Items: []acp.Item{{ID: "sku_1", Quantity: 1}}}
+
+	tests := map[string]struct {
+		builder    acptest.RequestBuilder
+		opts       []acp.Option
+		wantStatus int
+	}{
+		"unsigned": {
+			wantStatus: http.StatusCreated,
+		},
+		"unsigned rejected when signatures are required": {
+			opts:       []acp.Option{acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}), acp.WithRequireSignedRequests()},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"signed": {
+			builder:    acptest.RequestBuilder{SigningKey: key, Clock: clock},
+			opts:       []acp.Option{acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}), acp.WithRequireSignedRequests(), acp.WithClock(clock)},
+			wantStatus: http.StatusCreated,
+		},
+		"signed headers": {
+			builder: acptest.RequestBuilder{SigningKey: key, Clock: clock, IdempotencyKey: "idem_1", SignedHeaders: []string{"Idempotency-Key"}},
+			opts: []acp.Option{
+				acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}),
+				acp.WithRequireSignedRequests(),
+				acp.WithSignedHeaders("Idempotency-Key"),
+				acp.WithClock(clock),
+			},
+			wantStatus: http.StatusCreated,
+		},
+		"standard base64 signature": {
+			builder:    acptest.RequestBuilder{SigningKey: key, Clock: clock, SignatureEncoding: signature.EncodingStd},
+			opts:       []acp.Option{acp.WithSignatureVerifier(signature.HMACVerifier{Key: key, Encoding: signature.EncodingStd}), acp.WithClock(clock)},
+			wantStatus: http.StatusCreated,
+		},
+		"signature encoding mismatch": {
+			builder:    acptest.RequestBuilder{SigningKey: key, Clock: clock, SignatureEncoding: signature.EncodingStd},
+			opts:       []acp.Option{acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}), acp.WithClock(clock)},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"stale clock": {
+			builder:    acptest.RequestBuilder{SigningKey: key, Clock: func() time.Time { return now.Add(-time.Hour) }},
+			opts:       []acp.Option{acp.WithSignatureVerifier(signature.HMACVerifier{Key: key}), acp.WithRequireSignedRequests(), acp.WithClock(clock)},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := tt.builder.New(http.MethodPost, "/checkout_sessions", body)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := req.Header.Get("API-Version"); got != acp.APIVersion {
+				t.Fatalf("expected API-Version %s got %q", acp.APIVersion, got)
+			}
+			rec := httptest.NewRecorder()
+
+			acp.NewCheckoutHandler(createProvider{}, tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestBuilderAuthenticates(t *testing.T) {
+	t.Parallel()
+
+	var gotKey string
+	handler := acp.NewDelegatedPaymentHandler(delegateProvider{}, acp.WithAuthenticator(acp.AuthenticatorFunc(func(ctx context.Context, key string) error {
+		gotKey = key
+		return nil
+	})))
+	req, err := acptest.RequestBuilder{APIKey: "sk_test"}.New(http.MethodPost, "/agentic_commerce/delegate_payment", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotKey != "sk_test" {
+		t.Fatalf("expected authenticator to receive sk_test got %q", gotKey)
+	}
+}
+
+type delegateProvider struct {
+	acp.DelegatedPaymentProvider
+}
+
+type createProvider struct {
+	acp.CheckoutProvider
+}
+
+func (createProvider) CreateSession(context.Context, acp.CheckoutSessionCreateRequest) (*acp.CheckoutSession, error) {
+	return &acp.CheckoutSession{ID: "cs_123"}, nil
+}
@@ -21,35 +21,138 @@ func (f AuthenticatorFunc) Authenticate(ctx context.Context, apiKey string) erro
 	return f(ctx, apiKey)
 }
 
+// RequestAuthenticator is implemented by authenticators that check
+// credentials outside the Authorization header, such as a TLS client
+// certificate. The handler calls AuthenticateRequest instead of parsing a
+// bearer API key when the configured [Authenticator] implements it.
+type RequestAuthenticator interface {
+	AuthenticateRequest(r *http.Request) error
+}
+
+// RequestAuthenticatorFunc lifts bare functions into [RequestAuthenticator].
+// It satisfies [Authenticator] so it can be passed to [WithAuthenticator] and
+// [MultiAuthenticator]; its Authenticate method reports [ErrNoCredentials].
+type RequestAuthenticatorFunc func(r *http.Request) error
+
+// AuthenticateRequest validates the request using the wrapped function.
+func (f RequestAuthenticatorFunc) AuthenticateRequest(r *http.Request) error {
+	return f(r)
+}
+
+// Authenticate reports [ErrNoCredentials]; the handler always calls
+// AuthenticateRequest instead.
+func (f RequestAuthenticatorFunc) Authenticate(context.Context, string) error {
+	return ErrNoCredentials
+}
+
+// ErrNoCredentials is returned by authenticators that find none of the
+// credentials they check, e.g. a request without a client certificate. The
+// handler reports it with the [MissingAuthorization] code, and
+// [MultiAuthenticator] moves on to the next authenticator.
+var ErrNoCredentials = errors.New("acp: no credentials")
+
+// MultiAuthenticator accepts a request as soon as one of Authenticators
+// succeeds, trying them in order, e.g. to authenticate some callers by API
+// key and others by TLS client certificate:
+//
+//	acp.WithAuthenticator(acp.MultiAuthenticator{
+//		Authenticators: []acp.Authenticator{apiKeys, acp.RequestAuthenticatorFunc(checkClientCert)},
+//	})
+//
+// When no authenticator finds credentials the request is rejected with
+// [MissingAuthorization]. Otherwise the first rejection is returned, e.g.
+// [InvalidAuthorization] for an unknown API key, unless Err is set.
+type MultiAuthenticator struct {
+	Authenticators []Authenticator
+	// Err replaces the first rejection once every authenticator failed and
+	// at least one of them found credentials.
+	Err error
+}
+
+// Authenticate validates the API key with each authenticator in turn.
+func (m MultiAuthenticator) Authenticate(ctx context.Context, apiKey string) error {
+	return m.first(func(auth Authenticator) *Error {
+		return authenticationError(auth.Authenticate(ctx, apiKey), "invalid API key")
+	})
+}
+
+// AuthenticateRequest validates r with each authenticator in turn, parsing
+// the bearer API key for those that are not a [RequestAuthenticator].
+func (m MultiAuthenticator) AuthenticateRequest(r *http.Request) error {
+	return m.first(func(auth Authenticator) *Error {
+		return authenticate(r, auth)
+	})
+}
+
+func (m MultiAuthenticator) first(try func(Authenticator) *Error) error {
+	var rejected error
+	for _, auth := range m.Authenticators {
+		if auth == nil {
+			continue
+		}
+		httpErr := try(auth)
+		if httpErr == nil {
+			return nil
+		}
+		if httpErr.Code != MissingAuthorization && rejected == nil {
+			rejected = httpErr
+		}
+	}
+	if rejected == nil {
+		return ErrNoCredentials
+	}
+	if m.Err != nil {
+		return m.Err
+	}
+	return rejected
+}
+
 func (h *DelegatedPaymentHandler) authenticationMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if h.cfg.authenticator == nil {
 			next(w, r)
 			return
 		}
-		authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
-		if authHeader == "" {
-			writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, MissingAuthorization, "Authorization header is required"))
-			return
-		}
-		schema, apiKey, ok := strings.Cut(authHeader, " ")
-		if !ok || !strings.EqualFold(schema, "Bearer") {
-			writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, "Authorization header must be in the format 'Bearer <api_key>'"))
-			return
-		}
-		if apiKey == "" {
-			writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, "API key is required"))
-			return
-		}
-		if err := h.cfg.authenticator.Authenticate(r.Context(), apiKey); err != nil {
-			var httpErr *Error
-			if errors.As(err, &httpErr) {
-				writeJSONError(w, httpErr)
-				return
-			}
-			writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, "invalid API key"))
+		if httpErr := authenticate(r, h.cfg.authenticator); httpErr != nil {
+			writeJSONError(w, httpErr)
 			return
 		}
 		next(w, r)
 	}
 }
+
+// authenticate checks the credentials of r with auth, preferring
+// [RequestAuthenticator] over the bearer API key.
+func authenticate(r *http.Request, auth Authenticator) *Error {
+	if requestAuth, ok := auth.(RequestAuthenticator); ok {
+		return authenticationError(requestAuth.AuthenticateRequest(r), "invalid credentials")
+	}
+	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+	if authHeader == "" {
+		return NewHTTPError(http.StatusUnauthorized, InvalidRequest, MissingAuthorization, "Authorization header is required")
+	}
+	schema, apiKey, ok := strings.Cut(authHeader, " ")
+	if !ok || !strings.EqualFold(schema, "Bearer") {
+		return NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, "Authorization header must be in the format 'Bearer <api_key>'")
+	}
+	if apiKey == "" {
+		return NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, "API key is required")
+	}
+	return authenticationError(auth.Authenticate(r.Context(), apiKey), "invalid API key")
+}
+
+// authenticationError converts an authenticator failure into the error
+// written to the client, using message for plain rejections.
+func authenticationError(err error, message string) *Error {
+	if err == nil {
+		return nil
+	}
+	var httpErr *Error
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	if errors.Is(err, ErrNoCredentials) {
+		return NewHTTPError(http.StatusUnauthorized, InvalidRequest, MissingAuthorization, "credentials are required")
+	}
+	return NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidAuthorization, message)
+}
@@ -3,6 +3,8 @@ package acp
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -132,6 +134,82 @@ func TestAuthenticationMiddlewareAllowsValidRequests(t *testing.T) {
 	}
 }
 
+func TestMultiAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	apiKeys := AuthenticatorFunc(func(ctx context.Context, key string) error {
+		if key != "valid-key" {
+			return errors.New("invalid")
+		}
+		return nil
+	})
+	clientCert := RequestAuthenticatorFunc(func(r *http.Request) error {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return ErrNoCredentials
+		}
+		if r.TLS.PeerCertificates[0].Subject.CommonName != "agent" {
+			return errors.New("unknown client certificate")
+		}
+		return nil
+	})
+	forbidden := NewHTTPError(http.StatusForbidden, InvalidRequest, ErrorCode("forbidden"), "access denied")
+
+	tests := map[string]struct {
+		authorization string
+		commonName    string
+		err           error
+		wantStatus    int
+		wantCode      ErrorCode
+	}{
+		"api key":                {authorization: "Bearer valid-key", wantStatus: http.StatusCreated},
+		"client certificate":     {commonName: "agent", wantStatus: http.StatusCreated},
+		"no credentials":         {wantStatus: http.StatusUnauthorized, wantCode: MissingAuthorization},
+		"invalid api key":        {authorization: "Bearer other", wantStatus: http.StatusUnauthorized, wantCode: InvalidAuthorization},
+		"malformed header":       {authorization: "Basic abc", wantStatus: http.StatusUnauthorized, wantCode: InvalidAuthorization},
+		"unknown certificate":    {commonName: "intruder", wantStatus: http.StatusUnauthorized, wantCode: InvalidAuthorization},
+		"configured error":       {authorization: "Bearer other", err: forbidden, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		"configured error unset": {err: forbidden, wantStatus: http.StatusUnauthorized, wantCode: MissingAuthorization},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewDelegatedPaymentHandler(successService(), WithAuthenticator(MultiAuthenticator{
+				Authenticators: []Authenticator{apiKeys, clientCert},
+				Err:            tt.err,
+			}))
+
+			req := newDelegatePaymentHTTPRequest(t)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.commonName != "" {
+				cert := &x509.Certificate{}
+				cert.Subject.CommonName = tt.commonName
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Code != tt.wantCode {
+				t.Fatalf("expected error code %s got %s", tt.wantCode, payload.Code)
+			}
+		})
+	}
+}
+
 func newDelegatePaymentHTTPRequest(t *testing.T) *http.Request {
 	t.Helper()
 
@@ -2,11 +2,22 @@ package acp
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"time"
+	"strings"
 )
 
 // CheckoutProvider is implemented by business logic that owns checkout sessions.
+// UpdateSession and CompleteSession should reject completed or canceled
+// sessions with [AssertMutable], or the handler can do it with
+// [WithImmutableSessions]. CompleteSession should also reject a delegated token
+// whose allowance is below the session total with [AssertWithinAllowance].
+// Providers that set [CheckoutSession.Version] can guard both against
+// concurrent changes with [AssertIfMatch]. CompleteSession may return before
+// the payment is captured: an [Order.Status] of created or manual_review
+// makes the handler answer 202 Accepted instead of 200 OK.
 type CheckoutProvider interface {
 	CreateSession(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error)
 	UpdateSession(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error)
@@ -19,73 +30,94 @@ type CheckoutProvider interface {
 type CheckoutHandler struct {
 	service CheckoutProvider
 	mux     *http.ServeMux
+	routes  []Route
 	cfg     config
 }
 
 // NewCheckoutHandler builds a [CheckoutHandler] backed by net/http's ServeMux.
+// It panics on invalid options; see [NewCheckoutHandlerWithError].
 func NewCheckoutHandler(service CheckoutProvider, opts ...Option) *CheckoutHandler {
-	cfg := config{
-		maxClockSkew: 5 * time.Minute,
-		clock:        time.Now,
+	h, err := NewCheckoutHandlerWithError(service, opts...)
+	if err != nil {
+		panic(err)
 	}
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-		opt(&cfg)
+	return h
+}
+
+// NewCheckoutHandlerWithError is like [NewCheckoutHandler] but reports invalid
+// or contradicting options as an error instead of panicking.
+func NewCheckoutHandlerWithError(service CheckoutProvider, opts ...Option) (*CheckoutHandler, error) {
+	if service == nil {
+		return nil, errors.New("checkout: service is required")
 	}
-	if cfg.requireSignedRequests && cfg.signatureVerifier == nil {
-		panic("checkout: signature verifier required when signed requests are enforced")
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 	h := &CheckoutHandler{
 		service: service,
 		mux:     http.NewServeMux(),
 		cfg:     cfg,
 	}
-	var middleware []Middleware
-	if mw := newSignatureMiddleware(signatureMiddlewareConfig{
-		Verifier:      cfg.signatureVerifier,
-		RequireSigned: cfg.requireSignedRequests,
-		MaxClockSkew:  cfg.maxClockSkew,
-		Clock:         cfg.clock,
-	}); mw != nil {
-		middleware = append(middleware, Middleware(mw))
-	}
-	h.registerRoutes(middleware...)
-	return h
+	h.registerRoutes(cfg.handlerMiddleware(nil)...)
+	return h, nil
 }
 
 // ServeHTTP satisfies http.Handler.
 func (h *CheckoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestCtx := requestContextFromRequest(r)
+	echoRequestMetadata(w, requestCtx)
 	ctx := contextWithRequestContext(r.Context(), requestCtx)
-	h.mux.ServeHTTP(w, r.WithContext(ctx))
+	ctx = contextWithResponseHeader(ctx, w.Header())
+	if h.cfg.requestIDGenerator != nil {
+		ctx = contextWithRequestIDGenerator(ctx, h.cfg.requestIDGenerator)
+	}
+	r = r.WithContext(ctx)
+	w = withErrorRendering(w, r, h.cfg)
+	if !limitRequestBody(w, r) {
+		return
+	}
+	serveMux(h.mux, h.cfg, w, r)
 }
 
 func (h *CheckoutHandler) registerRoutes(middleware ...Middleware) {
-	h.mux.HandleFunc("POST /checkout_sessions", applyMiddleware(h.handleCreate, middleware...))
-	h.mux.HandleFunc("GET /checkout_sessions/{id}", applyMiddleware(h.handleGet, middleware...))
-	h.mux.HandleFunc("POST /checkout_sessions/{id}", applyMiddleware(h.handleUpdate, middleware...))
-	h.mux.HandleFunc("POST /checkout_sessions/{id}/complete", applyMiddleware(h.handleComplete, middleware...))
-	h.mux.HandleFunc("POST /checkout_sessions/{id}/cancel", applyMiddleware(h.handleCancel, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodPost, "/checkout_sessions", applyMiddleware(h.handleCreate, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodGet, "/checkout_sessions/{id}", applyMiddleware(h.handleGet, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodPost, "/checkout_sessions/{id}", applyMiddleware(h.handleUpdate, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodPost, "/checkout_sessions/{id}/complete", applyMiddleware(h.handleComplete, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodPost, "/checkout_sessions/{id}/cancel", applyMiddleware(h.handleCancel, middleware...))
+	if _, ok := h.service.(SessionSubscriber); ok {
+		handleRoute(h.mux, &h.routes, http.MethodGet, "/checkout_sessions/{id}/events", applyMiddleware(h.handleEvents, middleware...))
+	}
+	if h.cfg.openAPI {
+		handleOpenAPI(h.mux, &h.routes, "Agentic Commerce Protocol Checkout API", h.cfg.responseEnvelope, middleware...)
+	}
 }
 
 func (h *CheckoutHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 	var req CheckoutSessionCreateRequest
-	if err := decodeJSON(r.Body, &req); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	if err := h.cfg.decodeBody(r.Body, &req); err != nil {
+		writeJSONError(w, decodeError(err))
 		return
 	}
-	if err := req.Validate(); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	if err := req.validate(h.cfg.cartLimits); err != nil {
+		writeJSONError(w, h.cfg.validationError(err))
+		return
+	}
+	if req.Currency == nil && h.cfg.defaultCurrency != "" {
+		currency := h.cfg.defaultCurrency
+		req.Currency = &currency
+	}
+	if req.Currency != nil && !h.cfg.currencyAccepted(*req.Currency) {
+		writeJSONError(w, h.cfg.newValidationError(fmt.Sprintf("currency %q is not accepted", *req.Currency), WithOffendingParam("$.currency")))
 		return
 	}
 	session, err := h.service.CreateSession(r.Context(), req)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, h.cfg, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, session)
+	writeResource(w, h.cfg, http.StatusCreated, envelopeCheckoutSession, session)
 }
 
 func (h *CheckoutHandler) handleGet(w http.ResponseWriter, r *http.Request) {
@@ -96,10 +128,13 @@ func (h *CheckoutHandler) handleGet(w http.ResponseWriter, r *http.Request) {
 	}
 	session, err := h.service.GetSession(r.Context(), id)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+	if h.notModified(w, r, session) {
 		return
 	}
-	writeJSON(w, http.StatusOK, session)
+	writeResource(w, h.cfg, http.StatusOK, envelopeCheckoutSession, session)
 }
 
 func (h *CheckoutHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
@@ -109,20 +144,38 @@ func (h *CheckoutHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req CheckoutSessionUpdateRequest
-	if err := decodeJSON(r.Body, &req); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	if err := h.cfg.decodeBody(r.Body, &req); err != nil {
+		writeJSONError(w, decodeError(err))
 		return
 	}
-	if err := req.Validate(); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	if err := req.validate(h.cfg.cartLimits); err != nil {
+		writeJSONError(w, h.cfg.validationError(err))
+		return
+	}
+	if err := h.checkDiscountSupport(req.DiscountCodes); err != nil {
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+	if err := h.assertMutable(r.Context(), id); err != nil {
+		writeServiceError(w, h.cfg, err)
 		return
 	}
-	session, err := h.service.UpdateSession(r.Context(), id, req)
+	ctx, err := h.resolveFulfillment(r.Context(), id, req.FulfillmentAddress)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, h.cfg, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, session)
+	session, err := h.service.UpdateSession(ctx, id, req)
+	if err != nil {
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+	if err := h.applyDiscounts(r.Context(), session, req.DiscountCodes); err != nil {
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+	setVersionETag(w, session)
+	writeResource(w, h.cfg, http.StatusOK, envelopeCheckoutSession, session)
 }
 
 func (h *CheckoutHandler) handleComplete(w http.ResponseWriter, r *http.Request) {
@@ -132,20 +185,68 @@ func (h *CheckoutHandler) handleComplete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	var req CheckoutSessionCompleteRequest
-	if err := decodeJSON(r.Body, &req); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	if err := h.cfg.decodeBody(r.Body, &req); err != nil {
+		writeJSONError(w, decodeError(err))
 		return
 	}
 	if err := req.Validate(); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+		writeJSONError(w, h.cfg.validationError(err))
+		return
+	}
+	var idempotencyKey, fingerprint string
+	if h.cfg.completionStore != nil {
+		idempotencyKey = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		fingerprint = completionFingerprint(id, req)
+	}
+	if idempotencyKey != "" && h.reserveCompletion(w, r, idempotencyKey, fingerprint) {
+		return
+	}
+	if err := h.assertMutable(r.Context(), id, RequireNoPendingThreeDS); err != nil {
+		if idempotencyKey != "" {
+			h.releaseCompletion(r.Context(), idempotencyKey)
+		}
+		writeServiceError(w, h.cfg, err)
 		return
 	}
 	session, err := h.service.CompleteSession(r.Context(), id, req)
 	if err != nil {
-		writeServiceError(w, err)
+		if idempotencyKey != "" {
+			h.releaseCompletion(r.Context(), idempotencyKey)
+		}
+		var declined *PaymentDeclinedError
+		if errors.As(err, &declined) && declined.Session != nil {
+			writeResource(w, h.cfg, http.StatusOK, envelopeCheckoutSession, declined.checkoutSession())
+			return
+		}
+		writeServiceError(w, h.cfg, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, session)
+	if idempotencyKey != "" {
+		// The order already exists, so a failed write must not turn the
+		// completion into an error that invites the client to pay again.
+		record := CompletionRecord{Fingerprint: fingerprint, Response: session}
+		if err := h.cfg.completionStore.StoreCompletion(r.Context(), idempotencyKey, record); err != nil {
+			h.cfg.log().ErrorContext(r.Context(), "acp: store completion", slog.String("idempotency_key", idempotencyKey), slog.Any("error", err))
+		}
+	}
+	if session != nil {
+		setVersionETag(w, &session.CheckoutSession)
+	}
+	writeResource(w, h.cfg, completionStatus(session), envelopeCheckoutSession, session)
+}
+
+// completionStatus maps the [Order.Status] of a completion to the status
+// code of the response: orders still in payment processing get 202 Accepted,
+// confirmed or otherwise settled orders 200 OK.
+func completionStatus(session *SessionWithOrder) int {
+	if session == nil {
+		return http.StatusOK
+	}
+	switch session.Order.Status {
+	case OrderStatusCreated, OrderStatusManualReview:
+		return http.StatusAccepted
+	}
+	return http.StatusOK
 }
 
 func (h *CheckoutHandler) handleCancel(w http.ResponseWriter, r *http.Request) {
@@ -154,10 +255,67 @@ func (h *CheckoutHandler) handleCancel(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, NewInvalidRequestError("checkout_session_id is required"))
 		return
 	}
-	session, err := h.service.CancelSession(r.Context(), id)
+	var req CheckoutSessionCancelRequest
+	if err := h.cfg.decodeBody(r.Body, &req); err != nil && !errors.Is(err, errRequestBodyRequired) {
+		writeJSONError(w, decodeError(err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeJSONError(w, h.cfg.validationError(err))
+		return
+	}
+	session, err := h.cancelSession(r.Context(), id, req)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, h.cfg, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, session)
+	writeResource(w, h.cfg, http.StatusOK, envelopeCheckoutSession, session)
+}
+
+// PaymentDeclinedError is returned by [CheckoutProvider.CompleteSession] when
+// the payment was declined or needs 3DS. The handler answers with the session
+// carrying a [MessageError] and the ready_for_payment status so the buyer can
+// retry, instead of an ACP error payload.
+type PaymentDeclinedError struct {
+	// Session is the current state of the checkout session.
+	Session *CheckoutSession
+	// Code is either [PaymentDeclined] or [Requires3ds].
+	Code MessageErrorCode
+	// Message is shown to the buyer.
+	Message string
+}
+
+// NewPaymentDeclinedError builds a [PaymentDeclinedError]. Codes other than
+// [Requires3ds] are reported as [PaymentDeclined].
+func NewPaymentDeclinedError(session *CheckoutSession, code MessageErrorCode, message string) *PaymentDeclinedError {
+	if code != Requires3ds {
+		code = PaymentDeclined
+	}
+	return &PaymentDeclinedError{
+		Session: session,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error makes *PaymentDeclinedError satisfy the stdlib error interface.
+func (e *PaymentDeclinedError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return e.Message
+}
+
+func (e *PaymentDeclinedError) checkoutSession() *CheckoutSession {
+	session := *e.Session
+	var msg Message
+	_ = msg.FromMessageError(MessageError{
+		Type:        "error",
+		Code:        e.Code,
+		Content:     e.Message,
+		ContentType: MessageErrorContentTypePlain,
+	})
+	session.Messages = append(append([]Message(nil), e.Session.Messages...), msg)
+	session.Status = CheckoutSessionStatusReadyForPayment
+	return &session
 }
@@ -0,0 +1,103 @@
+package acp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ThreeDSChallenge is a requires_3ds [MessageError] extended with the data an
+// agent needs to run the interactive 3-D Secure step. Clients that only know
+// [MessageError] still see a regular requires_3ds error message.
+type ThreeDSChallenge struct {
+	MessageError
+
+	// ACSURL is the issuer Access Control Server URL the buyer must visit.
+	ACSURL string `json:"acs_url"`
+	// ContinuationToken identifies the challenge when the flow resumes.
+	ContinuationToken string `json:"continuation_token"`
+}
+
+// AsThreeDSChallenge returns the union data inside the CheckoutSessionBase_Messages_Item as a ThreeDSChallenge
+func (t Message) AsThreeDSChallenge() (ThreeDSChallenge, error) {
+	var body ThreeDSChallenge
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromThreeDSChallenge overwrites any union data inside the CheckoutSessionBase_Messages_Item as the provided ThreeDSChallenge
+func (t *Message) FromThreeDSChallenge(v ThreeDSChallenge) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// AttachThreeDSChallenge appends a requires_3ds challenge to the session
+// messages. Type and Code are filled in, and ContentType defaults to plain.
+func AttachThreeDSChallenge(session *CheckoutSession, challenge ThreeDSChallenge) error {
+	if session == nil {
+		return errors.New("acp: session is required")
+	}
+	if challenge.ACSURL == "" || challenge.ContinuationToken == "" {
+		return errors.New("acp: 3DS challenge requires acs_url and continuation_token")
+	}
+	challenge.Type = "error"
+	challenge.Code = Requires3ds
+	if challenge.ContentType == "" {
+		challenge.ContentType = MessageErrorContentTypePlain
+	}
+	var msg Message
+	if err := msg.FromThreeDSChallenge(challenge); err != nil {
+		return err
+	}
+	session.Messages = append(session.Messages, msg)
+	return nil
+}
+
+// PendingThreeDSChallenge returns the first 3DS challenge attached to the session.
+func PendingThreeDSChallenge(session *CheckoutSession) (*ThreeDSChallenge, bool) {
+	if session == nil {
+		return nil, false
+	}
+	for _, msg := range session.Messages {
+		if challenge, ok := threeDSChallenge(msg); ok {
+			return &challenge, true
+		}
+	}
+	return nil, false
+}
+
+// ResolveThreeDSChallenge removes the challenge matching continuationToken
+// from the session messages and reports whether one was found.
+func ResolveThreeDSChallenge(session *CheckoutSession, continuationToken string) bool {
+	if session == nil {
+		return false
+	}
+	for i, msg := range session.Messages {
+		if challenge, ok := threeDSChallenge(msg); ok && challenge.ContinuationToken == continuationToken {
+			session.Messages = append(session.Messages[:i:i], session.Messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RequireNoPendingThreeDS is meant to be called at the start of
+// [CheckoutProvider.CompleteSession]; it rejects completion with
+// [ThreeDSPending] while the session still carries a 3DS challenge. The
+// checkout handler applies it itself whenever it loads the session before a
+// completion, i.e. with [WithImmutableSessions] or an If-Match header.
+func RequireNoPendingThreeDS(session *CheckoutSession) error {
+	if _, ok := PendingThreeDSChallenge(session); ok {
+		return NewHTTPError(http.StatusConflict, InvalidRequest, ThreeDSPending, "3-D Secure authentication must be completed before the session can be completed", WithOffendingParam("$.messages"))
+	}
+	return nil
+}
+
+func threeDSChallenge(msg Message) (ThreeDSChallenge, bool) {
+	challenge, err := msg.AsThreeDSChallenge()
+	if err != nil || challenge.Type != "error" || challenge.Code != Requires3ds || challenge.ContinuationToken == "" {
+		return ThreeDSChallenge{}, false
+	}
+	return challenge, true
+}
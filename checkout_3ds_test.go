@@ -0,0 +1,110 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestThreeDSChallengeLifecycle(t *testing.T) {
+	t.Parallel()
+
+	session := &CheckoutSession{ID: "cs_123", Messages: []Message{}}
+	if err := AttachThreeDSChallenge(session, ThreeDSChallenge{
+		MessageError:      MessageError{Content: "Confirm the payment with your bank"},
+		ACSURL:            "https://acs.example/challenge",
+		ContinuationToken: "3ds_cont_1",
+	}); err != nil {
+		t.Fatalf("AttachThreeDSChallenge() error = %v", err)
+	}
+
+	msg, err := session.Messages[0].AsMessageError()
+	if err != nil {
+		t.Fatalf("decode message error: %v", err)
+	}
+	if msg.Type != "error" || msg.Code != Requires3ds {
+		t.Fatalf("expected requires_3ds error message got %+v", msg)
+	}
+	challenge, ok := PendingThreeDSChallenge(session)
+	if !ok {
+		t.Fatalf("expected pending challenge")
+	}
+	if challenge.ACSURL != "https://acs.example/challenge" || challenge.ContinuationToken != "3ds_cont_1" {
+		t.Fatalf("unexpected challenge %+v", challenge)
+	}
+
+	handler := NewCheckoutHandler(&stubService{
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			if err := RequireNoPendingThreeDS(session); err != nil {
+				return nil, err
+			}
+			return &SessionWithOrder{CheckoutSession: *session}, nil
+		},
+	})
+	complete := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"tok","provider":"sumup"}}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := complete()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while 3DS is pending got %d", rec.Code)
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(ThreeDSPending) {
+		t.Fatalf("expected code %s got %s", ThreeDSPending, got)
+	}
+
+	if ResolveThreeDSChallenge(session, "unknown") {
+		t.Fatalf("expected unknown continuation token not to resolve")
+	}
+	if !ResolveThreeDSChallenge(session, "3ds_cont_1") {
+		t.Fatalf("expected challenge to resolve")
+	}
+	if rec := complete(); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after 3DS got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCheckoutHandlerRejectsCompletionWithPendingThreeDS(t *testing.T) {
+	t.Parallel()
+
+	session := &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusReadyForPayment, Messages: []Message{}}
+	if err := AttachThreeDSChallenge(session, ThreeDSChallenge{
+		ACSURL:            "https://acs.example/challenge",
+		ContinuationToken: "3ds_cont_1",
+	}); err != nil {
+		t.Fatalf("AttachThreeDSChallenge() error = %v", err)
+	}
+	handler := NewCheckoutHandler(&stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return session, nil
+		},
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			t.Errorf("CompleteSession called while 3DS is pending")
+			return nil, nil
+		},
+	}, WithImmutableSessions())
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"tok","provider":"sumup"}}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(ThreeDSPending) {
+		t.Fatalf("expected code %s got %s", ThreeDSPending, got)
+	}
+}
+
+func TestAttachThreeDSChallengeRequiresChallengeData(t *testing.T) {
+	t.Parallel()
+
+	if err := AttachThreeDSChallenge(&CheckoutSession{}, ThreeDSChallenge{ACSURL: "https://acs.example"}); err == nil {
+		t.Fatalf("expected error without continuation token")
+	}
+}
@@ -0,0 +1,79 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+)
+
+// CancellationReason explains why a checkout session was canceled.
+type CancellationReason string
+
+const (
+	CancellationReasonBuyerAbandoned CancellationReason = "buyer_abandoned"
+	CancellationReasonFraudSuspected CancellationReason = "fraud_suspected"
+	CancellationReasonTimeout        CancellationReason = "timeout"
+	CancellationReasonOutOfStock     CancellationReason = "out_of_stock"
+	CancellationReasonOther          CancellationReason = "other"
+)
+
+var cancellationReasonDescriptions = map[CancellationReason]string{
+	CancellationReasonBuyerAbandoned: "the buyer abandoned the checkout",
+	CancellationReasonFraudSuspected: "the payment was flagged as potentially fraudulent",
+	CancellationReasonTimeout:        "the checkout session timed out",
+	CancellationReasonOutOfStock:     "an item is out of stock",
+	CancellationReasonOther:          "the merchant canceled the checkout",
+}
+
+// CheckoutSessionCancelRequest is the optional body of
+// POST /checkout_sessions/{id}/cancel.
+type CheckoutSessionCancelRequest struct {
+	Reason *CancellationReason `json:"reason,omitempty"`
+}
+
+// Validate ensures the cancellation reason, when present, is a known value.
+func (r CheckoutSessionCancelRequest) Validate() error {
+	if r.Reason == nil {
+		return nil
+	}
+	if _, ok := cancellationReasonDescriptions[*r.Reason]; !ok {
+		return NewInvalidRequestError(fmt.Sprintf("reason %q is not a supported cancellation reason", *r.Reason), WithOffendingParam("$.reason"))
+	}
+	return nil
+}
+
+// CheckoutSessionReasonCanceler is implemented by a [CheckoutProvider] that
+// records why sessions are canceled. When present it replaces
+// [CheckoutProvider.CancelSession] for every cancel request.
+type CheckoutSessionReasonCanceler interface {
+	CancelSessionWithReason(ctx context.Context, id string, req CheckoutSessionCancelRequest) (*CheckoutSession, error)
+}
+
+// cancelSession dispatches to the provider and surfaces the cancellation
+// reason as an info message on the returned session.
+func (h *CheckoutHandler) cancelSession(ctx context.Context, id string, req CheckoutSessionCancelRequest) (*CheckoutSession, error) {
+	var (
+		session *CheckoutSession
+		err     error
+	)
+	if canceler, ok := h.service.(CheckoutSessionReasonCanceler); ok {
+		session, err = canceler.CancelSessionWithReason(ctx, id, req)
+	} else {
+		session, err = h.service.CancelSession(ctx, id)
+	}
+	if err != nil || session == nil || req.Reason == nil {
+		return session, err
+	}
+	var msg Message
+	if err := msg.FromMessageInfo(MessageInfo{
+		Type:        "info",
+		Content:     "Checkout canceled: " + cancellationReasonDescriptions[*req.Reason] + ".",
+		ContentType: MessageInfoContentTypePlain,
+	}); err != nil {
+		return nil, err
+	}
+	// Copy the session so that a stored session the provider returned by
+	// pointer does not keep the message.
+	canceled := *session
+	canceled.Messages = append(append([]Message(nil), session.Messages...), msg)
+	return &canceled, nil
+}
@@ -0,0 +1,136 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckoutHandlerCancelReason(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body         string
+		wantStatus   int
+		wantMessages int
+	}{
+		"without body": {
+			wantStatus: http.StatusOK,
+		},
+		"without reason": {
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+		},
+		"with reason": {
+			body:         `{"reason":"buyer_abandoned"}`,
+			wantStatus:   http.StatusOK,
+			wantMessages: 1,
+		},
+		"unknown reason": {
+			body:       `{"reason":"bored"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				cancel: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: id, Status: CheckoutSessionStatusCanceled, Messages: []Message{}}, nil
+				},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/cancel", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var session CheckoutSession
+			if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+				t.Fatalf("decode session: %v", err)
+			}
+			if len(session.Messages) != tt.wantMessages {
+				t.Fatalf("expected %d messages got %d", tt.wantMessages, len(session.Messages))
+			}
+			if tt.wantMessages > 0 {
+				info, err := session.Messages[0].AsMessageInfo()
+				if err != nil {
+					t.Fatalf("decode message: %v", err)
+				}
+				if !strings.Contains(info.Content, "abandoned") {
+					t.Fatalf("expected reason in message got %q", info.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerCancelWithReasonProvider(t *testing.T) {
+	t.Parallel()
+
+	provider := &reasonCancelStub{}
+	handler := NewCheckoutHandler(provider)
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/cancel", strings.NewReader(`{"reason":"fraud_suspected"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if provider.reason == nil || *provider.reason != CancellationReasonFraudSuspected {
+		t.Fatalf("expected provider to receive reason got %v", provider.reason)
+	}
+}
+
+type reasonCancelStub struct {
+	stubService
+	reason *CancellationReason
+}
+
+func (s *reasonCancelStub) CancelSessionWithReason(ctx context.Context, id string, req CheckoutSessionCancelRequest) (*CheckoutSession, error) {
+	s.reason = req.Reason
+	return &CheckoutSession{ID: id, Status: CheckoutSessionStatusCanceled}, nil
+}
+
+func TestCheckoutHandlerCancelReasonLeavesProviderSessionUnchanged(t *testing.T) {
+	t.Parallel()
+
+	stored := &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusCanceled, Messages: make([]Message, 0, 4)}
+	handler := NewCheckoutHandler(&stubService{
+		cancel: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return stored, nil
+		},
+	})
+
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/cancel", strings.NewReader(`{"reason":"timeout"}`))
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 got %d body=%s", i, rec.Code, rec.Body.String())
+		}
+		var session CheckoutSession
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatalf("decode session: %v", err)
+		}
+		if len(session.Messages) != 1 {
+			t.Fatalf("request %d: expected 1 message got %d", i, len(session.Messages))
+		}
+	}
+	if len(stored.Messages) != 0 {
+		t.Fatalf("expected the stored session to stay unchanged got %d messages", len(stored.Messages))
+	}
+}
@@ -0,0 +1,62 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxCartItems is the default number of entries allowed in items.
+	DefaultMaxCartItems = 250
+	// DefaultMaxCartQuantity is the default sum of item quantities allowed in items.
+	DefaultMaxCartQuantity = 10000
+)
+
+// cartLimits bounds the items of create and update requests.
+type cartLimits struct {
+	maxItems    int
+	maxQuantity int
+}
+
+var defaultCartLimits = cartLimits{maxItems: DefaultMaxCartItems, maxQuantity: DefaultMaxCartQuantity}
+
+// WithCartLimits bounds the items of checkout create and update requests to
+// maxItems entries whose quantities add up to at most maxQuantity. Larger
+// carts are rejected with invalid_request on $.items before the provider is
+// called. Defaults to [DefaultMaxCartItems] and [DefaultMaxCartQuantity],
+// which [CheckoutSessionCreateRequest.Validate] and
+// [CheckoutSessionUpdateRequest.Validate] also apply.
+func WithCartLimits(maxItems, maxQuantity int) Option {
+	if maxItems <= 0 || maxQuantity <= 0 {
+		return invalidOption(errors.New("acp: cart limits must be positive"))
+	}
+	return func(cfg *config) {
+		cfg.cartLimits = cartLimits{maxItems: maxItems, maxQuantity: maxQuantity}
+	}
+}
+
+// validateCartSize rejects carts with more entries than allowed; it runs
+// before the items are looked at one by one.
+func (l cartLimits) validateCartSize(items []Item) error {
+	if len(items) > l.maxItems {
+		return NewInvalidRequestError(fmt.Sprintf("items has %d entries, at most %d are allowed", len(items), l.maxItems), WithOffendingParam("$.items"))
+	}
+	return nil
+}
+
+// validateCartQuantity rejects carts whose positive quantities add up to
+// more than allowed.
+func (l cartLimits) validateCartQuantity(items []Item) error {
+	total := 0
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		// Comparing before adding keeps the sum from overflowing.
+		if item.Quantity > l.maxQuantity-total {
+			return NewInvalidRequestError(fmt.Sprintf("items total quantity exceeds %d", l.maxQuantity), WithOffendingParam("$.items"))
+		}
+		total += item.Quantity
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCartLimitsValidate(t *testing.T) {
+	t.Parallel()
+
+	items := func(n, quantity int) []Item {
+		out := make([]Item, n)
+		for i := range out {
+			out[i] = Item{ID: "sku", Quantity: quantity}
+		}
+		return out
+	}
+	tests := map[string]struct {
+		items   []Item
+		wantErr string
+	}{
+		"at item limit":      {items: items(DefaultMaxCartItems, 1)},
+		"too many items":     {items: items(DefaultMaxCartItems+1, 1), wantErr: "items has 251 entries, at most 250 are allowed"},
+		"at quantity limit":  {items: items(2, DefaultMaxCartQuantity/2)},
+		"quantity too large": {items: items(2, DefaultMaxCartQuantity/2+1), wantErr: "items total quantity exceeds 10000"},
+		"overflowing sum":    {items: []Item{{ID: "a", Quantity: int(^uint(0) >> 1)}, {ID: "b", Quantity: 1}}, wantErr: "items total quantity exceeds 10000"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			updateItems := tt.items
+			for kind, err := range map[string]error{
+				"create": CheckoutSessionCreateRequest{Items: tt.items}.Validate(),
+				"update": CheckoutSessionUpdateRequest{Items: &updateItems}.Validate(),
+			} {
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Fatalf("%s: unexpected error %v", kind, err)
+					}
+					continue
+				}
+				var payload *Error
+				if !errors.As(err, &payload) || payload.Message != tt.wantErr || payload.Param == nil || *payload.Param != "$.items" {
+					t.Fatalf("%s: expected %q on $.items got %v", kind, tt.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithCartLimits(t *testing.T) {
+	t.Parallel()
+
+	service := &stubService{
+		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusInProgress, Currency: "usd"}, nil
+		},
+	}
+	tests := map[string]struct {
+		opts     []Option
+		items    int
+		wantCode int
+	}{
+		"lowered limit":  {opts: []Option{WithCartLimits(2, 10)}, items: 3, wantCode: http.StatusBadRequest},
+		"raised limit":   {opts: []Option{WithCartLimits(500, 1000)}, items: 300, wantCode: http.StatusCreated},
+		"default limit":  {items: 300, wantCode: http.StatusBadRequest},
+		"within default": {items: 3, wantCode: http.StatusCreated},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			items := make([]Item, tt.items)
+			for i := range items {
+				items[i] = Item{ID: "sku", Quantity: 1}
+			}
+			body, err := json.Marshal(CheckoutSessionCreateRequest{Items: items})
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewCheckoutHandler(service, tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d body=%s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DiscountApplier is implemented by a [CheckoutProvider] that supports promo
+// codes. When an update request carries discount_codes, the handler calls
+// ApplyDiscounts with the session returned by
+// [CheckoutProvider.UpdateSession] and the codes in request order; an empty
+// list asks to remove every applied code.
+//
+// ApplyDiscounts updates and persists the session, setting
+// [LineItem.Discount] and the [TotalTypeItemsDiscount] or [TotalTypeDiscount]
+// totals for the codes it accepts. Codes it does not accept (unknown,
+// expired, not applicable to the cart) are returned as rejections rather
+// than an error, so the rest of the update still applies; the handler
+// reports each one to the buyer as an [Invalid] error message pointing at
+// the code. A returned error fails the whole request.
+//
+// Without a DiscountApplier, update requests carrying discount codes are
+// rejected with invalid_request on $.discount_codes before
+// [CheckoutProvider.UpdateSession] is called, so buyers are not left
+// believing a code was applied.
+type DiscountApplier interface {
+	ApplyDiscounts(ctx context.Context, session *CheckoutSession, codes []string) ([]DiscountRejection, error)
+}
+
+// DiscountRejection explains why a discount code was not applied.
+type DiscountRejection struct {
+	// Code is the rejected code as sent by the client.
+	Code string
+	// Reason is shown to the buyer, e.g. "This code has expired."
+	Reason string
+}
+
+// validateDiscountCodes rejects blank and duplicate codes. Codes are compared
+// case-insensitively since buyers type them by hand.
+func validateDiscountCodes(codes []string) error {
+	seen := make([]string, 0, len(codes))
+	for i, code := range codes {
+		param := WithOffendingParam(fmt.Sprintf("$.discount_codes[%d]", i))
+		normalized := strings.ToLower(strings.TrimSpace(code))
+		if normalized == "" {
+			return NewInvalidRequestError(fmt.Sprintf("discount_codes[%d]: code must not be empty", i), param)
+		}
+		if slices.Contains(seen, normalized) {
+			return NewInvalidRequestError(fmt.Sprintf("discount_codes[%d]: duplicate code %q", i, code), param)
+		}
+		seen = append(seen, normalized)
+	}
+	return nil
+}
+
+// checkDiscountSupport rejects discount codes when the provider cannot apply
+// them.
+func (h *CheckoutHandler) checkDiscountSupport(codes []string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	if _, ok := h.service.(DiscountApplier); ok {
+		return nil
+	}
+	return h.cfg.newValidationError("discount codes are not supported", WithOffendingParam("$.discount_codes"))
+}
+
+// applyDiscounts runs the [DiscountApplier] of the provider, if any, and
+// appends a message for every rejected code.
+func (h *CheckoutHandler) applyDiscounts(ctx context.Context, session *CheckoutSession, codes []string) error {
+	applier, ok := h.service.(DiscountApplier)
+	if !ok || codes == nil || session == nil {
+		return nil
+	}
+	rejections, err := applier.ApplyDiscounts(ctx, session, slices.Clone(codes))
+	if err != nil {
+		return err
+	}
+	for _, rejection := range rejections {
+		msg := MessageError{
+			Type:        "error",
+			Code:        Invalid,
+			Content:     rejection.Reason,
+			ContentType: MessageErrorContentTypePlain,
+		}
+		if msg.Content == "" {
+			msg.Content = fmt.Sprintf("Discount code %q cannot be applied.", rejection.Code)
+		}
+		if i := slices.Index(codes, rejection.Code); i >= 0 {
+			param := fmt.Sprintf("$.discount_codes[%d]", i)
+			msg.Param = &param
+		}
+		var message Message
+		if err := message.FromMessageError(msg); err != nil {
+			return err
+		}
+		session.Messages = append(session.Messages, message)
+	}
+	return nil
+}
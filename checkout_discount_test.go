@@ -0,0 +1,212 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type discountStub struct {
+	stubService
+	apply func(ctx context.Context, session *CheckoutSession, codes []string) ([]DiscountRejection, error)
+}
+
+func (s *discountStub) ApplyDiscounts(ctx context.Context, session *CheckoutSession, codes []string) ([]DiscountRejection, error) {
+	return s.apply(ctx, session, codes)
+}
+
+func TestCheckoutSessionUpdateRequestValidateDiscountCodes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		codes     []string
+		wantParam string
+	}{
+		"none":             {},
+		"empty list":       {codes: []string{}},
+		"distinct":         {codes: []string{"SAVE10", "FREESHIP"}},
+		"blank":            {codes: []string{"SAVE10", "  "}, wantParam: "$.discount_codes[1]"},
+		"duplicate":        {codes: []string{"SAVE10", "FREESHIP", "SAVE10"}, wantParam: "$.discount_codes[2]"},
+		"duplicate casing": {codes: []string{"save10", " SAVE10"}, wantParam: "$.discount_codes[1]"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := CheckoutSessionUpdateRequest{DiscountCodes: tt.codes}.Validate()
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				return
+			}
+			acpErr, ok := err.(*Error)
+			if !ok || acpErr.Param == nil || *acpErr.Param != tt.wantParam {
+				t.Fatalf("expected error with param %s got %v", tt.wantParam, err)
+			}
+		})
+	}
+}
+
+func TestCheckoutSessionUpdateRequestDiscountCodesJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		req  CheckoutSessionUpdateRequest
+		want string
+	}{
+		"unchanged":  {req: CheckoutSessionUpdateRequest{}, want: `{}`},
+		"remove all": {req: CheckoutSessionUpdateRequest{DiscountCodes: []string{}}, want: `{"discount_codes":[]}`},
+		"apply":      {req: CheckoutSessionUpdateRequest{DiscountCodes: []string{"SAVE10"}}, want: `{"discount_codes":["SAVE10"]}`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("expected %s got %s", tt.want, data)
+			}
+			var decoded CheckoutSessionUpdateRequest
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if (decoded.DiscountCodes == nil) != (tt.req.DiscountCodes == nil) || !slices.Equal(decoded.DiscountCodes, tt.req.DiscountCodes) {
+				t.Fatalf("expected codes %#v got %#v", tt.req.DiscountCodes, decoded.DiscountCodes)
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerAppliesDiscounts(t *testing.T) {
+	t.Parallel()
+
+	var gotCodes []string
+	handler := NewCheckoutHandler(&discountStub{
+		stubService: stubService{
+			update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+				return &CheckoutSession{
+					ID:        id,
+					Status:    CheckoutSessionStatusReadyForPayment,
+					LineItems: []LineItem{{ID: "li_1", BaseAmount: 1000, Subtotal: 1000, Total: 1000}},
+					Totals:    []Total{{Type: TotalTypeTotal, Amount: 1000}},
+					Messages:  []Message{},
+				}, nil
+			},
+		},
+		apply: func(ctx context.Context, session *CheckoutSession, codes []string) ([]DiscountRejection, error) {
+			gotCodes = codes
+			session.LineItems[0].Discount = 100
+			session.LineItems[0].Total = 900
+			session.Totals = []Total{{Type: TotalTypeItemsDiscount, Amount: 100}, {Type: TotalTypeTotal, Amount: 900}}
+			return []DiscountRejection{{Code: "EXPIRED", Reason: "This code has expired."}}, nil
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(`{"discount_codes":["SAVE10","EXPIRED"]}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if !slices.Equal(gotCodes, []string{"SAVE10", "EXPIRED"}) {
+		t.Fatalf("expected codes in request order got %v", gotCodes)
+	}
+	var session CheckoutSession
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("decode session: %v", err)
+	}
+	if len(session.Totals) != 2 || session.Totals[0].Type != TotalTypeItemsDiscount || session.Totals[0].Amount != 100 {
+		t.Fatalf("expected items_discount total got %+v", session.Totals)
+	}
+	if len(session.Messages) != 1 {
+		t.Fatalf("expected one rejection message got %d", len(session.Messages))
+	}
+	msg, err := session.Messages[0].AsMessageError()
+	if err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	if msg.Code != Invalid || msg.Content != "This code has expired." || msg.Param == nil || *msg.Param != "$.discount_codes[1]" {
+		t.Fatalf("unexpected rejection message %+v", msg)
+	}
+}
+
+func TestCheckoutHandlerSkipsDiscountsWhenOmitted(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCheckoutHandler(&discountStub{
+		stubService: stubService{
+			update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+				return &CheckoutSession{ID: id}, nil
+			},
+		},
+		apply: func(ctx context.Context, session *CheckoutSession, codes []string) ([]DiscountRejection, error) {
+			t.Errorf("ApplyDiscounts called without discount_codes")
+			return nil, nil
+		},
+	})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(`{}`)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCheckoutHandlerRejectsDiscountsWithoutApplier(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body       string
+		wantStatus int
+	}{
+		"codes":       {body: `{"discount_codes":["SUMMER10"]}`, wantStatus: http.StatusBadRequest},
+		"remove all":  {body: `{"discount_codes":[]}`, wantStatus: http.StatusOK},
+		"not present": {body: `{}`, wantStatus: http.StatusOK},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var updated bool
+			handler := NewCheckoutHandler(&stubService{
+				update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+					updated = true
+					return &CheckoutSession{ID: id}, nil
+				},
+			})
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Code != ErrorCode(InvalidRequest) || payload.Param == nil || *payload.Param != "$.discount_codes" {
+				t.Fatalf("expected invalid_request on $.discount_codes got %+v", payload)
+			}
+			if updated {
+				t.Fatalf("expected the session not to be updated")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package acp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// WithSessionETags makes GET /checkout_sessions/{id} return an ETag computed
+// over the session JSON, or taken from [CheckoutSession.Version] when set,
+// and answer 304 Not Modified without a body when the If-None-Match header
+// of the request matches it. The provider is still asked for the session on
+// every request.
+func WithSessionETags() Option {
+	return func(cfg *config) {
+		cfg.sessionETags = true
+	}
+}
+
+// sessionETag returns the strong entity tag of session: its Version when
+// set, a digest of its JSON otherwise.
+func sessionETag(session *CheckoutSession) (string, bool) {
+	if session.Version != "" {
+		return `"` + session.Version + `"`, true
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, true
+}
+
+// notModified sets the [WithSessionETags] ETag header and reports whether a
+// 304 response was written because the request already holds session.
+func (h *CheckoutHandler) notModified(w http.ResponseWriter, r *http.Request, session *CheckoutSession) bool {
+	if !h.cfg.sessionETags || session == nil {
+		setVersionETag(w, session)
+		return false
+	}
+	etag, ok := sessionETag(session)
+	if !ok {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.Header().Set("API-Version", APIVersion)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches applies the weak comparison of If-None-Match (RFC 9110,
+// section 13.1.2) between header and etag.
+func etagMatches(header, etag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setVersionETag sends the [CheckoutSession.Version] of session, if any, as
+// the ETag of the response.
+func setVersionETag(w http.ResponseWriter, session *CheckoutSession) {
+	if session != nil && session.Version != "" {
+		w.Header().Set("ETag", `"`+session.Version+`"`)
+	}
+}
+
+// AssertIfMatch is meant to be called from [CheckoutProvider.UpdateSession]
+// and [CheckoutProvider.CompleteSession] while the session is locked; it
+// rejects the change with a 412 Precondition Failed [PreconditionFailed]
+// error when the request carries an If-Match header that does not match the
+// entity tag of session, i.e. its [CheckoutSession.Version]. The handler
+// performs the same check before calling the provider, but only a check made
+// under the provider's lock turns it into a compare-and-swap.
+func AssertIfMatch(ctx context.Context, session *CheckoutSession) error {
+	requestCtx := RequestContextFromContext(ctx)
+	if requestCtx == nil || requestCtx.IfMatch == "" || session == nil {
+		return nil
+	}
+	etag, ok := sessionETag(session)
+	if ok && ifMatches(requestCtx.IfMatch, etag) {
+		return nil
+	}
+	return NewHTTPError(http.StatusPreconditionFailed, InvalidRequest, PreconditionFailed, "checkout session does not match If-Match; fetch it again and retry")
+}
+
+// ifMatches applies the strong comparison of If-Match (RFC 9110, section
+// 13.1.1) between header and etag: weak entity tags never match.
+func ifMatches(header, etag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
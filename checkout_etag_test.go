@@ -0,0 +1,230 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithSessionETags(t *testing.T) {
+	t.Parallel()
+
+	service := &stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id, Status: CheckoutSessionStatusInProgress, Currency: "usd"}, nil
+		},
+	}
+	etag, ok := sessionETag(&CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusInProgress, Currency: "usd"})
+	if !ok {
+		t.Fatal("expected session etag")
+	}
+
+	tests := map[string]struct {
+		opts        []Option
+		ifNoneMatch string
+		wantCode    int
+		wantETag    string
+	}{
+		"disabled": {
+			ifNoneMatch: etag,
+			wantCode:    http.StatusOK,
+		},
+		"no precondition": {
+			opts:     []Option{WithSessionETags()},
+			wantCode: http.StatusOK,
+			wantETag: etag,
+		},
+		"matching etag": {
+			opts:        []Option{WithSessionETags()},
+			ifNoneMatch: etag,
+			wantCode:    http.StatusNotModified,
+			wantETag:    etag,
+		},
+		"weak etag in list": {
+			opts:        []Option{WithSessionETags()},
+			ifNoneMatch: `"stale", W/` + etag,
+			wantCode:    http.StatusNotModified,
+			wantETag:    etag,
+		},
+		"stale etag": {
+			opts:        []Option{WithSessionETags()},
+			ifNoneMatch: `"stale"`,
+			wantCode:    http.StatusOK,
+			wantETag:    etag,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			NewCheckoutHandler(service, tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d body=%s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("expected ETag %q got %q", tt.wantETag, got)
+			}
+			if tt.wantCode == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("expected empty body got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerIfMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		body     string
+		ifMatch  string
+		wantCode int
+	}{
+		"update without precondition": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			wantCode: http.StatusOK,
+		},
+		"update matching version": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			ifMatch:  `"v1"`,
+			wantCode: http.StatusOK,
+		},
+		"update wildcard": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			ifMatch:  `*`,
+			wantCode: http.StatusOK,
+		},
+		"update stale version": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			ifMatch:  `"v0"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		"update weak version": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			ifMatch:  `W/"v1"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		"complete stale version": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123/complete",
+			body:     `{"payment_data":{"token":"vt_123","provider":"stripe"}}`,
+			ifMatch:  `"v0", "v2"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		"complete matching version in list": {
+			method:   http.MethodPost,
+			path:     "/checkout_sessions/cs_123/complete",
+			body:     `{"payment_data":{"token":"vt_123","provider":"stripe"}}`,
+			ifMatch:  `"v0", "v1"`,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			current := &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusReadyForPayment, Currency: "usd", Version: "v1"}
+			var called bool
+			service := &stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return current, nil
+				},
+				update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+					called = true
+					if err := AssertIfMatch(ctx, current); err != nil {
+						return nil, err
+					}
+					return &CheckoutSession{ID: id, Status: CheckoutSessionStatusReadyForPayment, Currency: "usd", Version: "v2"}, nil
+				},
+				complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+					called = true
+					if err := AssertIfMatch(ctx, current); err != nil {
+						return nil, err
+					}
+					return &SessionWithOrder{CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted, Currency: "usd", Version: "v2"}}, nil
+				},
+			}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			NewCheckoutHandler(service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d body=%s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusPreconditionFailed {
+				if called {
+					t.Fatal("expected provider not to be called")
+				}
+				if got := getErrorCode(rec.Body.Bytes()); got != string(PreconditionFailed) {
+					t.Fatalf("expected code %s got %s", PreconditionFailed, got)
+				}
+				return
+			}
+			if got := rec.Header().Get("ETag"); got != `"v2"` {
+				t.Fatalf("expected ETag of the new version got %q", got)
+			}
+		})
+	}
+}
+
+func TestAssertIfMatch(t *testing.T) {
+	t.Parallel()
+
+	session := &CheckoutSession{ID: "cs_123", Version: "v1"}
+	tests := map[string]struct {
+		requestCtx *RequestContext
+		wantErr    bool
+	}{
+		"no request context": {},
+		"no precondition":    {requestCtx: &RequestContext{}},
+		"matching":           {requestCtx: &RequestContext{IfMatch: `"v1"`}},
+		"stale":              {requestCtx: &RequestContext{IfMatch: `"v0"`}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tt.requestCtx != nil {
+				ctx = contextWithRequestContext(ctx, tt.requestCtx)
+			}
+			err := AssertIfMatch(ctx, session)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			var httpErr *Error
+			if tt.wantErr && (!errors.As(err, &httpErr) || httpErr.status != http.StatusPreconditionFailed) {
+				t.Fatalf("expected 412 error got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// SessionSubscriber is optionally implemented by a [CheckoutProvider] to
+// stream checkout session updates, enabling the
+// GET /checkout_sessions/{id}/events Server-Sent Events route.
+type SessionSubscriber interface {
+	// SubscribeSession returns a channel yielding a snapshot of the session
+	// whenever it changes. The provider stops sending and closes the channel
+	// once ctx is done, which happens when the client disconnects.
+	SubscribeSession(ctx context.Context, id string) (<-chan CheckoutSession, error)
+}
+
+// sessionEventName is the SSE event name of checkout session snapshots.
+const sessionEventName = "checkout_session"
+
+// handleEvents is only registered when the service implements [SessionSubscriber].
+func (h *CheckoutHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	subscriber := h.service.(SessionSubscriber)
+	id := r.PathValue("id")
+	if id == "" {
+		writeJSONError(w, NewInvalidRequestError("checkout_session_id is required"))
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	snapshots, err := subscriber.SubscribeSession(ctx, id)
+	if err != nil {
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("API-Version", APIVersion)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case session, ok := <-snapshots:
+			if !ok {
+				return
+			}
+			if err := writeSessionEvent(w, session); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSessionEvent writes session as a single SSE frame; encoding/json never
+// emits raw newlines, so the payload fits on one data line.
+func writeSessionEvent(w http.ResponseWriter, session CheckoutSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString("event: " + sessionEventName + "\ndata: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
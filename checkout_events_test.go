@@ -0,0 +1,123 @@
+package acp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type subscriberStub struct {
+	stubService
+	subscribe func(context.Context, string) (<-chan CheckoutSession, error)
+}
+
+func (s *subscriberStub) SubscribeSession(ctx context.Context, id string) (<-chan CheckoutSession, error) {
+	return s.subscribe(ctx, id)
+}
+
+func TestCheckoutHandlerSessionEvents(t *testing.T) {
+	t.Parallel()
+
+	service := &subscriberStub{subscribe: func(ctx context.Context, id string) (<-chan CheckoutSession, error) {
+		ch := make(chan CheckoutSession, 2)
+		ch <- CheckoutSession{ID: id, Status: CheckoutSessionStatusInProgress}
+		ch <- CheckoutSession{ID: id, Status: CheckoutSessionStatusReadyForPayment}
+		close(ch)
+		return ch, nil
+	}}
+	rec := httptest.NewRecorder()
+
+	NewCheckoutHandler(service).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123/events", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("expected text/event-stream got %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "event: checkout_session\n") {
+		t.Fatalf("expected checkout_session events got %q", body)
+	}
+	var statuses []CheckoutSessionStatus
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var session CheckoutSession
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		statuses = append(statuses, session.Status)
+	}
+	want := []CheckoutSessionStatus{CheckoutSessionStatusInProgress, CheckoutSessionStatusReadyForPayment}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("expected statuses %v got %v", want, statuses)
+	}
+}
+
+func TestCheckoutHandlerSessionEventsStopsOnDisconnect(t *testing.T) {
+	t.Parallel()
+
+	subscribed := make(chan context.Context, 1)
+	service := &subscriberStub{subscribe: func(ctx context.Context, id string) (<-chan CheckoutSession, error) {
+		subscribed <- ctx
+		return make(chan CheckoutSession), nil
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123/events", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewCheckoutHandler(service).ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	subscriptionCtx := <-subscribed
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected handler to return after the client disconnected")
+	}
+	if subscriptionCtx.Err() == nil {
+		t.Fatalf("expected subscription context to be canceled")
+	}
+}
+
+func TestCheckoutHandlerSessionEventsSubscribeError(t *testing.T) {
+	t.Parallel()
+
+	service := &subscriberStub{subscribe: func(ctx context.Context, id string) (<-chan CheckoutSession, error) {
+		return nil, NewHTTPError(http.StatusNotFound, InvalidRequest, NotFound, "session not found")
+	}}
+	rec := httptest.NewRecorder()
+
+	NewCheckoutHandler(service).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_404/events", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", rec.Code)
+	}
+	if code := getErrorCode(rec.Body.Bytes()); code != string(NotFound) {
+		t.Fatalf("expected error code %s got %s", NotFound, code)
+	}
+}
+
+func TestCheckoutHandlerSessionEventsRequiresSubscriber(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+
+	NewCheckoutHandler(&stubService{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123/events", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", rec.Code)
+	}
+}
@@ -0,0 +1,181 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// Fulfillment option type discriminators, the type field of each
+// [FulfillmentOption] variant.
+const (
+	FulfillmentOptionTypeShipping = "shipping"
+	FulfillmentOptionTypeDigital  = "digital"
+)
+
+// fulfillmentOptionTypes lists the [FulfillmentOption] variants this SDK
+// models. New variants must be added here along with their As/From helpers.
+var fulfillmentOptionTypes = []string{
+	FulfillmentOptionTypeShipping,
+	FulfillmentOptionTypeDigital,
+}
+
+// SupportedFulfillmentOptionTypes returns the fulfillment option types that
+// can be sent in a [CheckoutSession], so clients can decide which variants
+// to render.
+func SupportedFulfillmentOptionTypes() []string {
+	return slices.Clone(fulfillmentOptionTypes)
+}
+
+// Type returns the type discriminator of the union, such as
+// [FulfillmentOptionTypeShipping].
+func (t FulfillmentOption) Type() (string, error) {
+	var body struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(t.union, &body)
+	return body.Type, err
+}
+
+// ID returns the id of the option, shared by every variant.
+func (t FulfillmentOption) ID() (string, error) {
+	var body struct {
+		ID string `json:"id"`
+	}
+	err := json.Unmarshal(t.union, &body)
+	return body.ID, err
+}
+
+// FulfillmentResolver is implemented by a [CheckoutProvider] whose
+// fulfillment options depend on the destination. When an update request
+// carries a fulfillment_address, the handler calls ResolveFulfillmentOptions
+// with the session returned by [CheckoutProvider.GetSession] and that
+// address, before calling [CheckoutProvider.UpdateSession]. Any error other
+// than a [FulfillmentUnavailableError] fails the request before the session
+// is updated.
+//
+// A resolver that cannot serve the address at all, e.g. because the merchant
+// does not ship to its country, returns a [FulfillmentUnavailableError]: the
+// options are then cleared, the reason is reported to the buyer as an
+// [Invalid] error message on $.fulfillment_address and a ready_for_payment
+// session moves back to not_ready_for_payment.
+//
+// UpdateSession applies the outcome with [ApplyFulfillmentOptions] before
+// persisting the session, so the options, the selected option and the status
+// are saved in the same write as the rest of the update.
+type FulfillmentResolver interface {
+	ResolveFulfillmentOptions(ctx context.Context, session *CheckoutSession, address Address) ([]FulfillmentOption, error)
+}
+
+// FulfillmentUnavailableError is returned, possibly wrapped, by
+// [FulfillmentResolver.ResolveFulfillmentOptions] when no fulfillment option
+// serves the address.
+type FulfillmentUnavailableError struct {
+	// Reason is shown to the buyer, e.g. "We do not ship to Canada."
+	Reason string
+}
+
+// Error makes *FulfillmentUnavailableError satisfy the stdlib error interface.
+func (e *FulfillmentUnavailableError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return e.Reason
+}
+
+// resolvedFulfillment is the outcome of a [FulfillmentResolver] for an
+// update, handed to the provider through the context.
+type resolvedFulfillment struct {
+	options     []FulfillmentOption
+	unavailable *FulfillmentUnavailableError
+}
+
+type resolvedFulfillmentKey struct{}
+
+// resolveFulfillment runs the [FulfillmentResolver] of the provider, if any,
+// for the fulfillment address of an update and returns ctx carrying the
+// outcome for [ApplyFulfillmentOptions].
+func (h *CheckoutHandler) resolveFulfillment(ctx context.Context, id string, address *Address) (context.Context, error) {
+	resolver, ok := h.service.(FulfillmentResolver)
+	if !ok || address == nil {
+		return ctx, nil
+	}
+	session, err := h.service.GetSession(ctx, id)
+	if err != nil {
+		return ctx, err
+	}
+	options, err := resolver.ResolveFulfillmentOptions(ctx, session, *address)
+	var unavailable *FulfillmentUnavailableError
+	switch {
+	case errors.As(err, &unavailable):
+		options = nil
+	case err != nil:
+		return ctx, err
+	}
+	return context.WithValue(ctx, resolvedFulfillmentKey{}, &resolvedFulfillment{options: options, unavailable: unavailable}), nil
+}
+
+// ApplyFulfillmentOptions is meant to be called from
+// [CheckoutProvider.UpdateSession] of a [FulfillmentResolver], after the
+// update is applied to session and before it is persisted. It replaces the
+// fulfillment options of session with those resolved for the request,
+// clearing a selected option that is no longer offered, or records that the
+// address cannot be served. Totals depending on the selected option must be
+// recomputed afterwards. It reports false, leaving session untouched, when
+// the request carried no fulfillment_address.
+func ApplyFulfillmentOptions(ctx context.Context, session *CheckoutSession) (bool, error) {
+	if ctx == nil || session == nil {
+		return false, nil
+	}
+	resolved, ok := ctx.Value(resolvedFulfillmentKey{}).(*resolvedFulfillment)
+	if !ok {
+		return false, nil
+	}
+	if resolved.unavailable != nil {
+		return true, fulfillmentUnavailable(session, resolved.unavailable.Reason)
+	}
+	mergeFulfillmentOptions(session, resolved.options)
+	return true, nil
+}
+
+// mergeFulfillmentOptions replaces the fulfillment options of session and
+// clears the selected option when it is no longer offered.
+func mergeFulfillmentOptions(session *CheckoutSession, options []FulfillmentOption) {
+	if options == nil {
+		options = []FulfillmentOption{}
+	}
+	session.FulfillmentOptions = options
+	if session.FulfillmentOptionId != nil && !slices.ContainsFunc(options, func(option FulfillmentOption) bool {
+		id, err := option.ID()
+		return err == nil && id == *session.FulfillmentOptionId
+	}) {
+		session.FulfillmentOptionId = nil
+	}
+}
+
+// fulfillmentUnavailable clears the fulfillment options of session and tells
+// the buyer why.
+func fulfillmentUnavailable(session *CheckoutSession, reason string) error {
+	if reason == "" {
+		reason = "No fulfillment option is available for this address."
+	}
+	param := "$.fulfillment_address"
+	var message Message
+	if err := message.FromMessageError(MessageError{
+		Type:        "error",
+		Code:        Invalid,
+		Content:     reason,
+		ContentType: MessageErrorContentTypePlain,
+		Param:       &param,
+	}); err != nil {
+		return err
+	}
+	session.FulfillmentOptions = []FulfillmentOption{}
+	session.FulfillmentOptionId = nil
+	session.Messages = append(session.Messages, message)
+	if session.Status == CheckoutSessionStatusReadyForPayment {
+		session.Status = CheckoutSessionStatusNotReadyForPayment
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSupportedFulfillmentOptionTypes(t *testing.T) {
+	t.Parallel()
+
+	got := SupportedFulfillmentOptionTypes()
+	want := []string{FulfillmentOptionTypeShipping, FulfillmentOptionTypeDigital}
+	if !slices.Equal(got, want) {
+		t.Fatalf("SupportedFulfillmentOptionTypes() = %v want %v", got, want)
+	}
+
+	got[0] = "pickup"
+	if SupportedFulfillmentOptionTypes()[0] != FulfillmentOptionTypeShipping {
+		t.Fatalf("expected callers not to mutate the registered types")
+	}
+}
+
+func TestFulfillmentOptionType(t *testing.T) {
+	t.Parallel()
+
+	var option FulfillmentOption
+	if err := option.FromFulfillmentOptionDigital(FulfillmentOptionDigital{ID: "digital", Type: FulfillmentOptionTypeDigital}); err != nil {
+		t.Fatalf("FromFulfillmentOptionDigital() error = %v", err)
+	}
+	got, err := option.Type()
+	if err != nil {
+		t.Fatalf("Type() error = %v", err)
+	}
+	if !slices.Contains(SupportedFulfillmentOptionTypes(), got) {
+		t.Fatalf("Type() = %q is not a supported type", got)
+	}
+}
+
+type fulfillmentStub struct {
+	stubService
+	resolve func(ctx context.Context, session *CheckoutSession, address Address) ([]FulfillmentOption, error)
+}
+
+func (s *fulfillmentStub) ResolveFulfillmentOptions(ctx context.Context, session *CheckoutSession, address Address) ([]FulfillmentOption, error) {
+	return s.resolve(ctx, session, address)
+}
+
+func shippingOption(t *testing.T, id string) FulfillmentOption {
+	t.Helper()
+	var option FulfillmentOption
+	if err := option.FromFulfillmentOptionShipping(FulfillmentOptionShipping{ID: id, Type: FulfillmentOptionTypeShipping, Title: id}); err != nil {
+		t.Fatalf("FromFulfillmentOptionShipping() error = %v", err)
+	}
+	return option
+}
+
+func TestCheckoutHandlerResolvesFulfillmentOptions(t *testing.T) {
+	t.Parallel()
+
+	const address = `"fulfillment_address":{"name":"Jane","line_one":"1 Main St","postal_code":"10115","city":"Berlin","state":"BE","country":"DE"}`
+	tests := map[string]struct {
+		body         string
+		resolveErr   error
+		options      []string
+		wantCalled   bool
+		wantOptions  []string
+		wantSelected string
+		wantStatus   CheckoutSessionStatus
+		wantMessage  string
+		wantHTTP     int
+	}{
+		"no address": {
+			body:         `{}`,
+			wantOptions:  []string{"standard"},
+			wantSelected: "standard",
+			wantStatus:   CheckoutSessionStatusReadyForPayment,
+			wantHTTP:     http.StatusOK,
+		},
+		"keeps selected option": {
+			body:         `{` + address + `}`,
+			options:      []string{"standard", "express"},
+			wantCalled:   true,
+			wantOptions:  []string{"standard", "express"},
+			wantSelected: "standard",
+			wantStatus:   CheckoutSessionStatusReadyForPayment,
+			wantHTTP:     http.StatusOK,
+		},
+		"clears option no longer offered": {
+			body:        `{` + address + `}`,
+			options:     []string{"international"},
+			wantCalled:  true,
+			wantOptions: []string{"international"},
+			wantStatus:  CheckoutSessionStatusReadyForPayment,
+			wantHTTP:    http.StatusOK,
+		},
+		"unavailable": {
+			body:        `{` + address + `}`,
+			resolveErr:  fmt.Errorf("resolve: %w", &FulfillmentUnavailableError{Reason: "We do not ship to Germany."}),
+			wantCalled:  true,
+			wantOptions: []string{},
+			wantStatus:  CheckoutSessionStatusNotReadyForPayment,
+			wantMessage: "We do not ship to Germany.",
+			wantHTTP:    http.StatusOK,
+		},
+		"resolver failure": {
+			body:       `{` + address + `}`,
+			resolveErr: errors.New("carrier API down"),
+			wantCalled: true,
+			wantHTTP:   http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var called bool
+			var persisted *CheckoutSession
+			selected := "standard"
+			current := func(id string) *CheckoutSession {
+				return &CheckoutSession{
+					ID:                  id,
+					Currency:            "eur",
+					Status:              CheckoutSessionStatusReadyForPayment,
+					FulfillmentOptionId: &selected,
+					FulfillmentOptions:  []FulfillmentOption{shippingOption(t, "standard")},
+				}
+			}
+			service := &fulfillmentStub{
+				stubService: stubService{
+					get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+						return current(id), nil
+					},
+					update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+						session := current(id)
+						if _, err := ApplyFulfillmentOptions(ctx, session); err != nil {
+							return nil, err
+						}
+						saved := *session
+						persisted = &saved
+						return session, nil
+					},
+				},
+				resolve: func(ctx context.Context, session *CheckoutSession, address Address) ([]FulfillmentOption, error) {
+					called = true
+					if session == nil || session.ID != "cs_123" {
+						t.Errorf("expected the current session got %+v", session)
+					}
+					if address.Country != "DE" {
+						t.Errorf("expected the request address got %+v", address)
+					}
+					var options []FulfillmentOption
+					for _, id := range tt.options {
+						options = append(options, shippingOption(t, id))
+					}
+					return options, tt.resolveErr
+				},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewCheckoutHandler(service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantHTTP {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantHTTP, rec.Code, rec.Body.String())
+			}
+			if called != tt.wantCalled {
+				t.Fatalf("expected resolver called %v got %v", tt.wantCalled, called)
+			}
+			if updated := persisted != nil; updated != (tt.wantHTTP == http.StatusOK) {
+				t.Fatalf("expected session updated %v got %v", !updated, updated)
+			}
+			if persisted != nil && (persisted.Status != tt.wantStatus || (persisted.FulfillmentOptionId == nil) != (tt.wantSelected == "")) {
+				t.Fatalf("expected persisted status %s and selected %q got %+v", tt.wantStatus, tt.wantSelected, persisted)
+			}
+			if tt.wantHTTP != http.StatusOK {
+				return
+			}
+			var session CheckoutSession
+			if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+				t.Fatalf("decode session: %v", err)
+			}
+			ids := []string{}
+			for _, option := range session.FulfillmentOptions {
+				id, err := option.ID()
+				if err != nil {
+					t.Fatalf("ID() error = %v", err)
+				}
+				ids = append(ids, id)
+			}
+			if !slices.Equal(ids, tt.wantOptions) {
+				t.Fatalf("expected options %v got %v", tt.wantOptions, ids)
+			}
+			var gotSelected string
+			if session.FulfillmentOptionId != nil {
+				gotSelected = *session.FulfillmentOptionId
+			}
+			if gotSelected != tt.wantSelected {
+				t.Fatalf("expected selected option %q got %q", tt.wantSelected, gotSelected)
+			}
+			if session.Status != tt.wantStatus {
+				t.Fatalf("expected status %s got %s", tt.wantStatus, session.Status)
+			}
+			if tt.wantMessage == "" {
+				if len(session.Messages) != 0 {
+					t.Fatalf("expected no messages got %d", len(session.Messages))
+				}
+				return
+			}
+			if len(session.Messages) != 1 {
+				t.Fatalf("expected one message got %d", len(session.Messages))
+			}
+			msg, err := session.Messages[0].AsMessageError()
+			if err != nil {
+				t.Fatalf("AsMessageError() error = %v", err)
+			}
+			if msg.Code != Invalid || msg.Content != tt.wantMessage || msg.Param == nil || *msg.Param != "$.fulfillment_address" {
+				t.Fatalf("unexpected message %+v", msg)
+			}
+		})
+	}
+}
+
+func TestApplyFulfillmentOptionsWithoutAddress(t *testing.T) {
+	t.Parallel()
+
+	selected := "standard"
+	session := &CheckoutSession{FulfillmentOptionId: &selected, FulfillmentOptions: []FulfillmentOption{shippingOption(t, "standard")}}
+	applied, err := ApplyFulfillmentOptions(context.Background(), session)
+	if err != nil || applied {
+		t.Fatalf("expected nothing applied got %v, %v", applied, err)
+	}
+	if len(session.FulfillmentOptions) != 1 || session.FulfillmentOptionId == nil {
+		t.Fatalf("expected the session untouched got %+v", session)
+	}
+}
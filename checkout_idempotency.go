@@ -0,0 +1,144 @@
+package acp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// CompletionRecord is a completed checkout response remembered under an
+// Idempotency-Key.
+type CompletionRecord struct {
+	// Fingerprint identifies the session and payment data the key was first used with.
+	Fingerprint string
+	// Response is the original completion result replayed to retries.
+	Response *SessionWithOrder
+}
+
+// ErrCompletionInProgress is returned by [CompletionStore.ReserveCompletion]
+// while another request holds the Idempotency-Key.
+var ErrCompletionInProgress = errors.New("acp: completion in progress")
+
+// CompletionStore persists [CompletionRecord] values for [WithCompletionIdempotency].
+// Implementations must be safe for concurrent use.
+type CompletionStore interface {
+	// ReserveCompletion atomically claims key before the provider is called.
+	// It returns the stored record when the completion already finished,
+	// [ErrCompletionInProgress] while another request holds the key, and nil
+	// once the caller owns the key. The owner ends the reservation with
+	// StoreCompletion or ReleaseCompletion; persistent stores should also
+	// expire reservations of crashed instances.
+	ReserveCompletion(ctx context.Context, key string) (*CompletionRecord, error)
+	// StoreCompletion records the response of a reserved key.
+	StoreCompletion(ctx context.Context, key string, record CompletionRecord) error
+	// ReleaseCompletion drops the reservation of a key whose completion
+	// failed, so that a retry can call the provider again.
+	ReleaseCompletion(ctx context.Context, key string) error
+}
+
+// NewMemoryCompletionStore returns a process-local [CompletionStore]. Records
+// are never evicted, so it suits tests and single-instance deployments.
+func NewMemoryCompletionStore() CompletionStore {
+	return &memoryCompletionStore{
+		records:  make(map[string]CompletionRecord),
+		reserved: make(map[string]struct{}),
+	}
+}
+
+type memoryCompletionStore struct {
+	mu       sync.Mutex
+	records  map[string]CompletionRecord
+	reserved map[string]struct{}
+}
+
+func (s *memoryCompletionStore) ReserveCompletion(_ context.Context, key string) (*CompletionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok {
+		return &record, nil
+	}
+	if _, ok := s.reserved[key]; ok {
+		return nil, ErrCompletionInProgress
+	}
+	s.reserved[key] = struct{}{}
+	return nil, nil
+}
+
+func (s *memoryCompletionStore) StoreCompletion(_ context.Context, key string, record CompletionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reserved, key)
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryCompletionStore) ReleaseCompletion(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reserved, key)
+	return nil
+}
+
+// WithCompletionIdempotency makes POST /checkout_sessions/{id}/complete
+// idempotent per Idempotency-Key: a retry with the same key and payment data
+// replays the original response without calling the provider again, with
+// 202 Accepted for orders still processing and 200 OK otherwise. Reusing the
+// key for another session or payment token fails with [IdempotencyConflict],
+// as does a retry that arrives while the first request is still in flight.
+// Requests without the header are not affected.
+func WithCompletionIdempotency(store CompletionStore) Option {
+	if store == nil {
+		return invalidOption(errors.New("acp: completion store is required"))
+	}
+	return func(cfg *config) {
+		cfg.completionStore = store
+	}
+}
+
+// completionFingerprint binds an Idempotency-Key to the session and payment
+// data it was first used with.
+func completionFingerprint(id string, req CheckoutSessionCompleteRequest) string {
+	sum := sha256.New()
+	for _, part := range []string{id, string(req.PaymentData.Provider), req.PaymentData.Token} {
+		_, _ = sum.Write([]byte(part))
+		_, _ = sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// reserveCompletion claims key for a completion, or writes the stored
+// response of a retried completion, and reports whether the request was fully
+// handled. A concurrent request with the same key gets [IdempotencyConflict].
+func (h *CheckoutHandler) reserveCompletion(w http.ResponseWriter, r *http.Request, key, fingerprint string) bool {
+	record, err := h.cfg.completionStore.ReserveCompletion(r.Context(), key)
+	if errors.Is(err, ErrCompletionInProgress) {
+		writeJSONError(w, NewHTTPError(http.StatusConflict, InvalidRequest, IdempotencyConflict, "a request with this Idempotency-Key is still in progress"))
+		return true
+	}
+	if err != nil {
+		writeServiceError(w, h.cfg, err)
+		return true
+	}
+	if record == nil {
+		return false
+	}
+	if record.Fingerprint != fingerprint {
+		writeJSONError(w, NewHTTPError(http.StatusConflict, InvalidRequest, IdempotencyConflict, "Idempotency-Key was already used with different parameters"))
+		return true
+	}
+	writeResource(w, h.cfg, completionStatus(record.Response), envelopeCheckoutSession, record.Response)
+	return true
+}
+
+// releaseCompletion drops the reservation of a failed completion. Failures
+// are only logged: the reservation then blocks retries until the store
+// expires it, which is safer than charging twice.
+func (h *CheckoutHandler) releaseCompletion(ctx context.Context, key string) {
+	if err := h.cfg.completionStore.ReleaseCompletion(ctx, key); err != nil {
+		h.cfg.log().ErrorContext(ctx, "acp: release completion reservation", slog.String("idempotency_key", key), slog.Any("error", err))
+	}
+}
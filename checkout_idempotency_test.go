@@ -0,0 +1,160 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckoutHandlerCompletionIdempotency(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	handler := NewCheckoutHandler(&stubService{
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			n := calls.Add(1)
+			return &SessionWithOrder{
+				CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted},
+				Order:           Order{ID: fmt.Sprintf("ord_%d", n), CheckoutSessionId: id},
+			}, nil
+		},
+	}, WithCompletionIdempotency(NewMemoryCompletionStore()))
+
+	complete := func(key, token string) *httptest.ResponseRecorder {
+		body := `{"payment_data":{"token":"` + token + `","provider":"sumup"}}`
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	orderID := func(rec *httptest.ResponseRecorder) string {
+		var resp SessionWithOrder
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp.Order.ID
+	}
+
+	first := complete("idem_1", "tok_a")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", first.Code, first.Body.String())
+	}
+
+	retry := complete("idem_1", "tok_a")
+	if retry.Code != http.StatusOK {
+		t.Fatalf("expected replayed 200 got %d", retry.Code)
+	}
+	if orderID(retry) != orderID(first) {
+		t.Fatalf("expected replayed order %s got %s", orderID(first), orderID(retry))
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected provider to be called once, got %d", got)
+	}
+
+	conflict := complete("idem_1", "tok_b")
+	if conflict.Code != http.StatusConflict {
+		t.Fatalf("expected 409 got %d", conflict.Code)
+	}
+	if got := getErrorCode(conflict.Body.Bytes()); got != string(IdempotencyConflict) {
+		t.Fatalf("expected code %s got %s", IdempotencyConflict, got)
+	}
+
+	if rec := complete("", "tok_a"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 without Idempotency-Key got %d", rec.Code)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected requests without a key to reach the provider, got %d calls", got)
+	}
+}
+
+func TestCheckoutHandlerCompletionIdempotencyInFlight(t *testing.T) {
+	t.Parallel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	handler := NewCheckoutHandler(&stubService{
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			if calls.Add(1) == 1 {
+				close(started)
+				<-release
+				return nil, NewServiceUnavailableError("payment gateway timeout")
+			}
+			return &SessionWithOrder{
+				CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted},
+				Order:           Order{ID: "ord_1", CheckoutSessionId: id},
+			}, nil
+		},
+	}, WithCompletionIdempotency(NewMemoryCompletionStore()))
+
+	complete := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"tok_a","provider":"sumup"}}`))
+		req.Header.Set("Idempotency-Key", "idem_1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- complete() }()
+	<-started
+
+	if rec := complete(); rec.Code != http.StatusConflict || getErrorCode(rec.Body.Bytes()) != string(IdempotencyConflict) {
+		t.Fatalf("expected 409 idempotency_conflict while in flight got %d body=%s", rec.Code, rec.Body.String())
+	}
+	close(release)
+	if rec := <-first; rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from the first request got %d", rec.Code)
+	}
+
+	if rec := complete(); rec.Code != http.StatusOK {
+		t.Fatalf("expected retry after a failed completion to reach the provider, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected provider to be called twice, got %d", got)
+	}
+}
+
+type failingCompletionStore struct {
+	CompletionStore
+}
+
+func (failingCompletionStore) StoreCompletion(context.Context, string, CompletionRecord) error {
+	return errors.New("store unavailable")
+}
+
+func TestCheckoutHandlerCompletionIdempotencyLogsStoreFailure(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	handler := NewCheckoutHandler(&stubService{
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			return &SessionWithOrder{CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted}}, nil
+		},
+	},
+		WithCompletionIdempotency(failingCompletionStore{NewMemoryCompletionStore()}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"tok_a","provider":"sumup"}}`))
+	req.Header.Set("Idempotency-Key", "idem_1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 despite the store failure got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), "store unavailable") {
+		t.Fatalf("expected the store failure to be logged, got %q", logs.String())
+	}
+}
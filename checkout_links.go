@@ -0,0 +1,34 @@
+package acp
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// ValidateLinks checks the links of a checkout session before a provider
+// returns it: every link must have a known type and an absolute https URL,
+// and each of required, typically [PrivacyPolicy] and [TermsOfUse], must be
+// present.
+func ValidateLinks(links []Link, required ...LinkType) error {
+	for i, link := range links {
+		switch link.Type {
+		case PrivacyPolicy, SellerShopPolicies, TermsOfUse:
+		default:
+			return fmt.Errorf("acp: links[%d]: unknown link type %q", i, link.Type)
+		}
+		u, err := url.Parse(link.Url)
+		if err != nil {
+			return fmt.Errorf("acp: links[%d]: invalid url: %w", i, err)
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("acp: links[%d]: url %q must be an absolute https URL", i, link.Url)
+		}
+	}
+	for _, typ := range required {
+		if !slices.ContainsFunc(links, func(link Link) bool { return link.Type == typ }) {
+			return fmt.Errorf("acp: %s link is required", typ)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package acp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLinks(t *testing.T) {
+	t.Parallel()
+
+	valid := []Link{
+		{Type: PrivacyPolicy, Url: "https://merchant.example/privacy"},
+		{Type: TermsOfUse, Url: "https://merchant.example/terms"},
+		{Type: SellerShopPolicies, Url: "https://merchant.example/policies"},
+	}
+
+	tests := map[string]struct {
+		links    []Link
+		required []LinkType
+		wantErr  string
+	}{
+		"valid": {
+			links:    valid,
+			required: []LinkType{PrivacyPolicy, TermsOfUse},
+		},
+		"no links": {},
+		"relative url": {
+			links:   []Link{{Type: PrivacyPolicy, Url: "/privacy"}},
+			wantErr: "links[0]: url \"/privacy\" must be an absolute https URL",
+		},
+		"http url": {
+			links:   []Link{valid[0], {Type: TermsOfUse, Url: "http://merchant.example/terms"}},
+			wantErr: "links[1]: url \"http://merchant.example/terms\" must be an absolute https URL",
+		},
+		"unknown type": {
+			links:   []Link{{Type: "refund_policy", Url: "https://merchant.example/refunds"}},
+			wantErr: "unknown link type",
+		},
+		"missing required": {
+			links:    valid[:1],
+			required: []LinkType{PrivacyPolicy, TermsOfUse},
+			wantErr:  "terms_of_use link is required",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateLinks(tt.links, tt.required...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
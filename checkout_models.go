@@ -91,7 +91,7 @@ type Address struct {
 	PostalCode string  `json:"postal_code"`
 	City       string  `json:"city"`
 	State      string  `json:"state"`
-	Country    string  `json:"country"`
+	Country    string  `json:"country" validate:"country"`
 }
 
 // Buyer defines model for Buyer.
@@ -116,6 +116,12 @@ type CheckoutSession struct {
 	PaymentProvider     *PaymentProvider      `json:"payment_provider,omitempty"`
 	Status              CheckoutSessionStatus `json:"status"`
 	Totals              []Total               `json:"totals"`
+
+	// Version is an optional concurrency token maintained by the provider,
+	// changed on every modification of the session. It is not part of the
+	// JSON body: the handler sends it as the ETag of the session and checks
+	// the If-Match header of updates and completions against it.
+	Version string `json:"-"`
 }
 
 // FulfillmentOption defines model for CheckoutSessionBase.fulfillment_options.Item.
@@ -137,6 +143,7 @@ type CheckoutSessionCompleteRequest struct {
 // CheckoutSessionCreateRequest defines model for CheckoutSessionCreateRequest.
 type CheckoutSessionCreateRequest struct {
 	Buyer              *Buyer   `json:"buyer,omitempty"`
+	Currency           *string  `json:"currency,omitempty"`
 	FulfillmentAddress *Address `json:"fulfillment_address,omitempty"`
 	Items              []Item   `json:"items"`
 }
@@ -147,6 +154,14 @@ type CheckoutSessionUpdateRequest struct {
 	FulfillmentAddress  *Address `json:"fulfillment_address,omitempty"`
 	FulfillmentOptionId *string  `json:"fulfillment_option_id,omitempty"`
 	Items               *[]Item  `json:"items,omitempty"`
+
+	// DiscountCodes lists the promo codes to apply, replacing any applied
+	// before; an empty list removes them all and nil leaves them unchanged.
+	// See [DiscountApplier].
+	DiscountCodes []string `json:"discount_codes,omitzero"`
+
+	// cleared lists the fields sent as null; see [CheckoutSessionUpdateRequest.Clears].
+	cleared []UpdateField
 }
 
 // SessionWithOrder defines model for SessionWithOrder.
@@ -195,6 +210,9 @@ type LineItem struct {
 	Subtotal   int    `json:"subtotal"`
 	Tax        int    `json:"tax"`
 	Total      int    `json:"total"`
+
+	// TaxRate is the fractional tax rate applied to the line item, e.g. 0.07 for 7%.
+	TaxRate *float64 `json:"tax_rate,omitempty"`
 }
 
 // Link defines model for Link.
@@ -203,6 +221,17 @@ type Link struct {
 	Url  string   `json:"url"`
 }
 
+// MessageError defines model for MessageError.
+type MessageError struct {
+	Code        MessageErrorCode        `json:"code"`
+	Content     string                  `json:"content"`
+	ContentType MessageErrorContentType `json:"content_type"`
+
+	// Param RFC 9535 JSONPath
+	Param *string `json:"param,omitempty"`
+	Type  string  `json:"type"`
+}
+
 // MessageInfo defines model for MessageInfo.
 type MessageInfo struct {
 	Content     string                 `json:"content"`
@@ -218,6 +247,12 @@ type Order struct {
 	ID                string `json:"id"`
 	CheckoutSessionId string `json:"checkout_session_id"`
 	PermalinkUrl      string `json:"permalink_url"`
+
+	// Status is the processing state of the order. It selects the status
+	// code of the completion response: 202 Accepted while the payment is
+	// still being processed ([OrderStatusCreated] and
+	// [OrderStatusManualReview]), 200 OK otherwise, including when empty.
+	Status OrderStatus `json:"status,omitempty"`
 }
 
 // PaymentData defines model for PaymentData.
@@ -230,6 +265,12 @@ type PaymentData struct {
 // PaymentDataProvider defines model for PaymentData.Provider.
 type PaymentDataProvider string
 
+// Defines values for PaymentDataProvider.
+const (
+	PaymentDataProviderStripe PaymentDataProvider = "stripe"
+	PaymentDataProviderSumUp  PaymentDataProvider = "sumup"
+)
+
 // PaymentProvider defines model for PaymentProvider.
 type PaymentProvider struct {
 	Provider                PaymentProviderProvider   `json:"provider"`
@@ -239,6 +280,12 @@ type PaymentProvider struct {
 // PaymentProviderProvider defines model for PaymentProvider.Provider.
 type PaymentProviderProvider string
 
+// Defines values for PaymentProviderProvider.
+const (
+	PaymentProviderProviderStripe PaymentProviderProvider = "stripe"
+	PaymentProviderProviderSumUp  PaymentProviderProvider = "sumup"
+)
+
 // Total defines model for Total.
 type Total struct {
 	Amount      int       `json:"amount"`
@@ -336,6 +383,32 @@ func (t *Message) MergeMessageInfo(v MessageInfo) error {
 	return err
 }
 
+// AsMessageError returns the union data inside the CheckoutSessionBase_Messages_Item as a MessageError
+func (t Message) AsMessageError() (MessageError, error) {
+	var body MessageError
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromMessageError overwrites any union data inside the CheckoutSessionBase_Messages_Item as the provided MessageError
+func (t *Message) FromMessageError(v MessageError) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeMessageError performs a merge with any union data inside the CheckoutSessionBase_Messages_Item, using the provided MessageError
+func (t *Message) MergeMessageError(v MessageError) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JSONMerge(t.union, b)
+	t.union = merged
+	return err
+}
+
 // MarshalJSON serializes the underlying union for CheckoutSessionBase_Messages_Item.
 func (t Message) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
@@ -0,0 +1,63 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AssertMutable is meant to be called at the start of
+// [CheckoutProvider.UpdateSession] and [CheckoutProvider.CompleteSession]; it
+// rejects changes to completed or canceled sessions with a 409 Conflict
+// [SessionClosed] error. Providers that replay completions for idempotency
+// should do so before calling it.
+func AssertMutable(session *CheckoutSession) error {
+	if session == nil {
+		return nil
+	}
+	switch session.Status {
+	case CheckoutSessionStatusCompleted, CheckoutSessionStatusCanceled:
+		return NewHTTPError(http.StatusConflict, InvalidRequest, SessionClosed, fmt.Sprintf("checkout session is %s and can no longer be changed", session.Status))
+	}
+	return nil
+}
+
+// WithImmutableSessions makes the checkout handler load the session with
+// [CheckoutProvider.GetSession] before every update and completion and
+// reject it with [AssertMutable], so providers need not check it themselves.
+// Completions are also checked with [RequireNoPendingThreeDS]. Completions
+// replayed from a [CompletionStore] are not affected.
+func WithImmutableSessions() Option {
+	return func(cfg *config) {
+		cfg.immutableSessions = true
+	}
+}
+
+// assertMutable enforces [WithImmutableSessions] and the If-Match header,
+// see [AssertIfMatch], for session id, loading the session at most once.
+// When the session is loaded, checks run on it as well.
+func (h *CheckoutHandler) assertMutable(ctx context.Context, id string, checks ...func(*CheckoutSession) error) error {
+	requestCtx := RequestContextFromContext(ctx)
+	ifMatch := requestCtx != nil && requestCtx.IfMatch != ""
+	if !h.cfg.immutableSessions && !ifMatch {
+		return nil
+	}
+	session, err := h.service.GetSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := AssertIfMatch(ctx, session); err != nil {
+		return err
+	}
+	if h.cfg.immutableSessions {
+		if err := AssertMutable(session); err != nil {
+			return err
+		}
+	}
+	for _, check := range checks {
+		if err := check(session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
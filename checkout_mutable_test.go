@@ -0,0 +1,117 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAssertMutable(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		session *CheckoutSession
+		wantErr bool
+	}{
+		"nil session":       {},
+		"in progress":       {session: &CheckoutSession{Status: CheckoutSessionStatusInProgress}},
+		"ready for payment": {session: &CheckoutSession{Status: CheckoutSessionStatusReadyForPayment}},
+		"completed":         {session: &CheckoutSession{Status: CheckoutSessionStatusCompleted}, wantErr: true},
+		"canceled":          {session: &CheckoutSession{Status: CheckoutSessionStatusCanceled}, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := AssertMutable(tt.session)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				return
+			}
+			acpErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("expected *Error got %T", err)
+			}
+			if acpErr.status != http.StatusConflict || acpErr.Code != SessionClosed {
+				t.Fatalf("expected 409 %s got %d %s", SessionClosed, acpErr.status, acpErr.Code)
+			}
+		})
+	}
+}
+
+func TestWithImmutableSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		status   CheckoutSessionStatus
+		path     string
+		body     string
+		wantCode int
+	}{
+		"update in progress": {
+			status:   CheckoutSessionStatusInProgress,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			wantCode: http.StatusOK,
+		},
+		"update completed": {
+			status:   CheckoutSessionStatusCompleted,
+			path:     "/checkout_sessions/cs_123",
+			body:     `{}`,
+			wantCode: http.StatusConflict,
+		},
+		"complete canceled": {
+			status:   CheckoutSessionStatusCanceled,
+			path:     "/checkout_sessions/cs_123/complete",
+			body:     `{"payment_data":{"token":"tok","provider":"sumup"}}`,
+			wantCode: http.StatusConflict,
+		},
+		"complete ready": {
+			status:   CheckoutSessionStatusReadyForPayment,
+			path:     "/checkout_sessions/cs_123/complete",
+			body:     `{"payment_data":{"token":"tok","provider":"sumup"}}`,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var called bool
+			handler := NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: id, Status: tt.status}, nil
+				},
+				update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+					called = true
+					return &CheckoutSession{ID: id, Status: tt.status}, nil
+				},
+				complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+					called = true
+					return &SessionWithOrder{CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted}}, nil
+				},
+			}, WithImmutableSessions())
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d body=%s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusConflict {
+				if got := getErrorCode(rec.Body.Bytes()); got != string(SessionClosed) {
+					t.Fatalf("expected code %s got %s", SessionClosed, got)
+				}
+				if called {
+					t.Fatalf("expected provider not to be called for a closed session")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// NegotiatePaymentMethods intersects the methods the merchant's payment
+// provider supports with the ones the buyer can pay with, keeping the
+// merchant's order. An empty requested list means no buyer preference and
+// yields every merchant method. When nothing overlaps it returns an
+// [UnsupportedPaymentMethod] error.
+//
+// It is meant to be called from [CheckoutProvider.CreateSession] to fill in
+// [CheckoutSession.PaymentProvider]. The handler cannot call it because
+// [CheckoutSessionCreateRequest] carries no buyer payment methods; providers
+// pass the ones the agent reported, or none.
+func NegotiatePaymentMethods(merchant PaymentProvider, requested []SupportedPaymentMethods) ([]SupportedPaymentMethods, error) {
+	if len(requested) == 0 {
+		if len(merchant.SupportedPaymentMethods) == 0 {
+			return nil, NewHTTPError(http.StatusBadRequest, InvalidRequest, UnsupportedPaymentMethod, "merchant does not support any payment method")
+		}
+		return slices.Clone(merchant.SupportedPaymentMethods), nil
+	}
+	var negotiated []SupportedPaymentMethods
+	for _, method := range merchant.SupportedPaymentMethods {
+		if slices.Contains(requested, method) && !slices.Contains(negotiated, method) {
+			negotiated = append(negotiated, method)
+		}
+	}
+	if len(negotiated) == 0 {
+		return nil, NewHTTPError(http.StatusBadRequest, InvalidRequest, UnsupportedPaymentMethod, fmt.Sprintf("none of the requested payment methods %v are supported by %s", requested, merchant.Provider))
+	}
+	return negotiated, nil
+}
+
+// RequirePaymentProvider is meant to be called from
+// [CheckoutProvider.CompleteSession]; it rejects payment data issued by a
+// different provider than the one configured on the session, so a token from
+// the wrong PSP is never charged. Sessions without a payment provider accept
+// any payment data.
+func RequirePaymentProvider(session *CheckoutSession, data PaymentData) error {
+	if session == nil || session.PaymentProvider == nil {
+		return nil
+	}
+	want := string(session.PaymentProvider.Provider)
+	if string(data.Provider) == want {
+		return nil
+	}
+	return NewHTTPError(http.StatusBadRequest, InvalidRequest, PaymentProviderMismatch, fmt.Sprintf("payment_data.provider %q does not match the session payment provider %q", data.Provider, want), WithOffendingParam("$.payment_data.provider"))
+}
+
+// AssertWithinAllowance is meant to be called from
+// [CheckoutProvider.CompleteSession] once the delegated token is resolved;
+// it rejects an order whose total, in minor units, exceeds the max_amount of
+// the token's [Allowance] with an [AllowanceExceeded] error. The message
+// includes both amounts but nothing about the token.
+func AssertWithinAllowance(total int, maxAmount int) error {
+	if total <= maxAmount {
+		return nil
+	}
+	return NewHTTPError(http.StatusBadRequest, InvalidRequest, AllowanceExceeded, fmt.Sprintf("order total %d exceeds the payment allowance of %d", total, maxAmount), WithOffendingParam("$.payment_data.token"))
+}
+
+// NewCompleteRequest assembles the body of POST
+// /checkout_sessions/{id}/complete for session, paying with token issued by
+// provider. billing is optional. The request is validated, including the
+// provider check of [RequirePaymentProvider], before it is returned.
+func NewCompleteRequest(session *CheckoutSession, token string, provider PaymentDataProvider, billing *Address) (CheckoutSessionCompleteRequest, error) {
+	if session == nil {
+		return CheckoutSessionCompleteRequest{}, errors.New("acp: session is required")
+	}
+	req := CheckoutSessionCompleteRequest{
+		Buyer: session.Buyer,
+		PaymentData: PaymentData{
+			BillingAddress: billing,
+			Provider:       provider,
+			Token:          token,
+		},
+	}
+	if err := req.Validate(); err != nil {
+		return CheckoutSessionCompleteRequest{}, err
+	}
+	if err := validateAddressCountry("payment_data.billing_address", billing); err != nil {
+		return CheckoutSessionCompleteRequest{}, err
+	}
+	if err := RequirePaymentProvider(session, req.PaymentData); err != nil {
+		return CheckoutSessionCompleteRequest{}, err
+	}
+	return req, nil
+}
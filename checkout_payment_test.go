@@ -0,0 +1,225 @@
+package acp
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatePaymentMethods(t *testing.T) {
+	t.Parallel()
+
+	const wallet SupportedPaymentMethods = "wallet"
+	merchant := PaymentProvider{
+		Provider:                "sumup",
+		SupportedPaymentMethods: []SupportedPaymentMethods{Card, wallet},
+	}
+
+	tests := map[string]struct {
+		merchant  PaymentProvider
+		requested []SupportedPaymentMethods
+		want      []SupportedPaymentMethods
+		wantErr   bool
+	}{
+		"intersection keeps merchant order": {
+			merchant:  merchant,
+			requested: []SupportedPaymentMethods{wallet, Card, Card},
+			want:      []SupportedPaymentMethods{Card, wallet},
+		},
+		"partial overlap": {
+			merchant:  merchant,
+			requested: []SupportedPaymentMethods{"bank_transfer", Card},
+			want:      []SupportedPaymentMethods{Card},
+		},
+		"no buyer preference": {
+			merchant: merchant,
+			want:     []SupportedPaymentMethods{Card, wallet},
+		},
+		"no overlap": {
+			merchant:  merchant,
+			requested: []SupportedPaymentMethods{"bank_transfer"},
+			wantErr:   true,
+		},
+		"merchant without methods": {
+			merchant: PaymentProvider{Provider: "sumup"},
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NegotiatePaymentMethods(tt.merchant, tt.requested)
+			if tt.wantErr {
+				var httpErr *Error
+				if !errors.As(err, &httpErr) || httpErr.Code != UnsupportedPaymentMethod {
+					t.Fatalf("expected %s error got %v", UnsupportedPaymentMethod, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NegotiatePaymentMethods() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRequirePaymentProvider(t *testing.T) {
+	t.Parallel()
+
+	sumup := &CheckoutSession{PaymentProvider: &PaymentProvider{Provider: PaymentProviderProviderSumUp}}
+	tests := map[string]struct {
+		session  *CheckoutSession
+		provider PaymentDataProvider
+		wantErr  bool
+	}{
+		"matching provider": {
+			session:  sumup,
+			provider: PaymentDataProviderSumUp,
+		},
+		"mismatched provider": {
+			session:  sumup,
+			provider: PaymentDataProviderStripe,
+			wantErr:  true,
+		},
+		"session without provider": {
+			session:  &CheckoutSession{},
+			provider: PaymentDataProviderStripe,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := RequirePaymentProvider(tt.session, PaymentData{Provider: tt.provider, Token: "tok_123"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("RequirePaymentProvider() error = %v", err)
+				}
+				return
+			}
+			var httpErr *Error
+			if !errors.As(err, &httpErr) || httpErr.Code != PaymentProviderMismatch {
+				t.Fatalf("expected %s error got %v", PaymentProviderMismatch, err)
+			}
+			if httpErr.Param == nil || *httpErr.Param != "$.payment_data.provider" {
+				t.Fatalf("expected payment_data.provider param got %v", httpErr.Param)
+			}
+		})
+	}
+}
+
+func TestAssertWithinAllowance(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		total     int
+		maxAmount int
+		wantErr   bool
+	}{
+		"below allowance":    {total: 900, maxAmount: 1000},
+		"equal to allowance": {total: 1000, maxAmount: 1000},
+		"above allowance":    {total: 1001, maxAmount: 1000, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := AssertWithinAllowance(tt.total, tt.maxAmount)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("AssertWithinAllowance() error = %v", err)
+				}
+				return
+			}
+			var httpErr *Error
+			if !errors.As(err, &httpErr) || httpErr.Code != AllowanceExceeded {
+				t.Fatalf("expected %s error got %v", AllowanceExceeded, err)
+			}
+			if !strings.Contains(httpErr.Message, "1001") || !strings.Contains(httpErr.Message, "1000") {
+				t.Fatalf("expected amounts in message got %q", httpErr.Message)
+			}
+		})
+	}
+}
+
+func TestNewCompleteRequest(t *testing.T) {
+	t.Parallel()
+
+	session := &CheckoutSession{
+		ID:              "cs_123",
+		Buyer:           &Buyer{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe"},
+		PaymentProvider: &PaymentProvider{Provider: PaymentProviderProviderSumUp},
+	}
+	billing := &Address{Name: "Jane Doe", LineOne: "1 Main St", City: "Berlin", PostalCode: "10115", Country: "DE"}
+	tests := map[string]struct {
+		session  *CheckoutSession
+		token    string
+		provider PaymentDataProvider
+		billing  *Address
+		wantErr  bool
+	}{
+		"valid request": {
+			session:  session,
+			token:    "tok_123",
+			provider: PaymentDataProviderSumUp,
+			billing:  billing,
+		},
+		"empty token": {
+			session:  session,
+			provider: PaymentDataProviderSumUp,
+			wantErr:  true,
+		},
+		"wrong provider": {
+			session:  session,
+			token:    "tok_123",
+			provider: PaymentDataProviderStripe,
+			wantErr:  true,
+		},
+		"invalid billing country": {
+			session:  session,
+			token:    "tok_123",
+			provider: PaymentDataProviderSumUp,
+			billing:  &Address{Country: "ZZ"},
+			wantErr:  true,
+		},
+		"missing session": {
+			token:    "tok_123",
+			provider: PaymentDataProviderSumUp,
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := NewCompleteRequest(tt.session, tt.token, tt.provider, tt.billing)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error got %+v", req)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCompleteRequest() error = %v", err)
+			}
+			if err := req.Validate(); err != nil {
+				t.Fatalf("expected a valid request got %v", err)
+			}
+			if req.PaymentData.Token != tt.token || req.PaymentData.Provider != tt.provider || req.PaymentData.BillingAddress != tt.billing {
+				t.Fatalf("unexpected payment data %+v", req.PaymentData)
+			}
+			if req.Buyer != tt.session.Buyer {
+				t.Fatalf("expected buyer from session")
+			}
+		})
+	}
+}
@@ -197,9 +197,269 @@ func TestCheckoutHandlerErrors(t *testing.T) {
 		if rec.Code != http.StatusMethodNotAllowed {
 			t.Fatalf("expected 405 got %d", rec.Code)
 		}
+		if got := getErrorCode(rec.Body.Bytes()); got != string(MethodNotAllowed) {
+			t.Fatalf("expected code %s got %s body=%s", MethodNotAllowed, got, rec.Body.String())
+		}
 	})
 }
 
+func TestCheckoutHandlerAcceptedCurrencies(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		currency   string
+		wantStatus int
+	}{
+		"accepted currency": {
+			currency:   "USD",
+			wantStatus: http.StatusCreated,
+		},
+		"unaccepted currency": {
+			currency:   "JPY",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var created bool
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					created = true
+					return &CheckoutSession{ID: "cs_123", Currency: *req.Currency}, nil
+				},
+			}, WithAcceptedCurrencies("usd", "eur"))
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(`{"currency":"`+tt.currency+`","items":[{"id":"sku_1","quantity":1}]}`))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if got := getErrorCode(rec.Body.Bytes()); got != string(InvalidRequest) {
+					t.Fatalf("expected code %s got %s", InvalidRequest, got)
+				}
+				if created {
+					t.Fatalf("expected the provider not to be called for an unaccepted currency")
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerCreateRequestCurrency(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body         string
+		wantStatus   int
+		wantCurrency string
+	}{
+		"explicit currency": {
+			body:         `{"currency":"EUR","items":[{"id":"sku_1","quantity":1}]}`,
+			wantStatus:   http.StatusCreated,
+			wantCurrency: "EUR",
+		},
+		"omitted currency uses default": {
+			body:         `{"items":[{"id":"sku_1","quantity":1}]}`,
+			wantStatus:   http.StatusCreated,
+			wantCurrency: "usd",
+		},
+		"unaccepted currency": {
+			body:       `{"currency":"JPY","items":[{"id":"sku_1","quantity":1}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			called := false
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					called = true
+					if req.Currency == nil || *req.Currency != tt.wantCurrency {
+						t.Fatalf("expected currency %q got %v", tt.wantCurrency, req.Currency)
+					}
+					return &CheckoutSession{ID: "cs_123", Currency: *req.Currency}, nil
+				},
+			}, WithAcceptedCurrencies("usd", "eur"), WithDefaultCurrency("USD"))
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if called {
+					t.Fatalf("expected provider not to be called for unaccepted currency")
+				}
+				var payload Error
+				if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+					t.Fatalf("decode error: %v", err)
+				}
+				if payload.Param == nil || *payload.Param != "$.currency" {
+					t.Fatalf("expected param $.currency got %v", payload.Param)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerCompletePaymentDeclined(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		code     MessageErrorCode
+		wantCode MessageErrorCode
+	}{
+		"payment declined": {
+			code:     PaymentDeclined,
+			wantCode: PaymentDeclined,
+		},
+		"requires 3ds": {
+			code:     Requires3ds,
+			wantCode: Requires3ds,
+		},
+		"unknown code falls back to declined": {
+			code:     OutOfStock,
+			wantCode: PaymentDeclined,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+					session := &CheckoutSession{
+						ID:       id,
+						Status:   CheckoutSessionStatusInProgress,
+						Currency: "usd",
+						Messages: []Message{},
+					}
+					return nil, NewPaymentDeclinedError(session, tt.code, "card was declined")
+				},
+			})
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"tok","provider":"sumup"}}`))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 got %d body=%s", rec.Code, rec.Body.String())
+			}
+			var session CheckoutSession
+			if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+				t.Fatalf("decode session: %v", err)
+			}
+			if session.Status != CheckoutSessionStatusReadyForPayment {
+				t.Fatalf("expected status %s got %s", CheckoutSessionStatusReadyForPayment, session.Status)
+			}
+			if len(session.Messages) != 1 {
+				t.Fatalf("expected 1 message got %d", len(session.Messages))
+			}
+			msg, err := session.Messages[0].AsMessageError()
+			if err != nil {
+				t.Fatalf("decode message: %v", err)
+			}
+			if msg.Type != "error" || msg.Code != tt.wantCode || msg.Content != "card was declined" {
+				t.Fatalf("unexpected message %+v", msg)
+			}
+		})
+	}
+}
+
+func TestCheckoutHandlerRejectsNonPositiveQuantities(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body        string
+		wantMessage string
+	}{
+		"zero quantity": {
+			body:        `{"items":[{"id":"sku_1","quantity":0}]}`,
+			wantMessage: "items[0]: quantity must not be zero; omit the item to remove it",
+		},
+		"negative quantity": {
+			body:        `{"items":[{"id":"sku_1","quantity":-1}]}`,
+			wantMessage: "items[0]: quantity must not be negative",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{})
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400 got %d", rec.Code)
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Message != tt.wantMessage {
+				t.Fatalf("expected message %q got %q", tt.wantMessage, payload.Message)
+			}
+			if payload.Param == nil || *payload.Param != "$.items[0].quantity" {
+				t.Fatalf("expected param $.items[0].quantity got %v", payload.Param)
+			}
+		})
+	}
+}
+
+func TestLineItemTaxRateSerialization(t *testing.T) {
+	t.Parallel()
+
+	rate := 0.07
+	tests := map[string]struct {
+		item LineItem
+		want string
+	}{
+		"with tax rate": {
+			item: LineItem{ID: "li_1", TaxRate: &rate},
+			want: `"tax_rate":0.07`,
+		},
+		"without tax rate": {
+			item: LineItem{ID: "li_1"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload, err := json.Marshal(tt.item)
+			if err != nil {
+				t.Fatalf("marshal line item: %v", err)
+			}
+			if tt.want == "" {
+				if strings.Contains(string(payload), "tax_rate") {
+					t.Fatalf("expected tax_rate to be omitted, got %s", payload)
+				}
+				return
+			}
+			if !strings.Contains(string(payload), tt.want) {
+				t.Fatalf("expected %s in %s", tt.want, payload)
+			}
+		})
+	}
+}
+
 type stubService struct {
 	create   func(context.Context, CheckoutSessionCreateRequest) (*CheckoutSession, error)
 	update   func(context.Context, string, CheckoutSessionUpdateRequest) (*CheckoutSession, error)
@@ -242,3 +502,55 @@ func (s *stubService) CancelSession(ctx context.Context, id string) (*CheckoutSe
 	}
 	return nil, NewHTTPError(http.StatusNotImplemented, InvalidRequest, ErrorCode("not_implemented"), "cancel not implemented")
 }
+
+func TestCheckoutHandlerCompleteStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		orderStatus OrderStatus
+		wantCode    int
+	}{
+		"unset":         {wantCode: http.StatusOK},
+		"confirmed":     {orderStatus: OrderStatusConfirmed, wantCode: http.StatusOK},
+		"fulfilled":     {orderStatus: OrderStatusFulfilled, wantCode: http.StatusOK},
+		"created":       {orderStatus: OrderStatusCreated, wantCode: http.StatusAccepted},
+		"manual review": {orderStatus: OrderStatusManualReview, wantCode: http.StatusAccepted},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+					return &SessionWithOrder{
+						CheckoutSession: CheckoutSession{ID: id, Status: CheckoutSessionStatusCompleted},
+						Order:           Order{ID: "ord_123", CheckoutSessionId: id, Status: tt.orderStatus},
+					}, nil
+				},
+			}, WithCompletionIdempotency(NewMemoryCompletionStore()))
+			complete := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete", strings.NewReader(`{"payment_data":{"token":"vt_123","provider":"stripe"}}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Idempotency-Key", "idem_1")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			for _, attempt := range []string{"first", "replay"} {
+				rec := complete()
+				if rec.Code != tt.wantCode {
+					t.Fatalf("%s: expected %d got %d body=%s", attempt, tt.wantCode, rec.Code, rec.Body.String())
+				}
+				var got SessionWithOrder
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatalf("%s: decode: %v", attempt, err)
+				}
+				if got.Order.Status != tt.orderStatus {
+					t.Fatalf("%s: expected order status %q got %q", attempt, tt.orderStatus, got.Order.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// UpdateField names a [CheckoutSessionUpdateRequest] field that clients can
+// clear by sending it as null.
+type UpdateField string
+
+const (
+	UpdateFieldBuyer               UpdateField = "buyer"
+	UpdateFieldFulfillmentAddress  UpdateField = "fulfillment_address"
+	UpdateFieldFulfillmentOptionID UpdateField = "fulfillment_option_id"
+)
+
+// clearableFields lists the fields tracked by [CheckoutSessionUpdateRequest.Clears].
+var clearableFields = []UpdateField{UpdateFieldBuyer, UpdateFieldFulfillmentAddress, UpdateFieldFulfillmentOptionID}
+
+// Clears reports whether the client sent field as null, asking to clear the
+// stored value. Update requests follow partial-update semantics for buyer,
+// fulfillment_address and fulfillment_option_id:
+//
+//   - omitted: leave the stored value unchanged (the field is nil)
+//   - null: clear the stored value (the field is nil and Clears is true)
+//   - a value: replace the stored value
+func (r CheckoutSessionUpdateRequest) Clears(field UpdateField) bool {
+	return slices.Contains(r.cleared, field)
+}
+
+// Clear marks field as explicitly cleared, as if the client had sent null,
+// and resets its value.
+func (r *CheckoutSessionUpdateRequest) Clear(field UpdateField) {
+	switch field {
+	case UpdateFieldBuyer:
+		r.Buyer = nil
+	case UpdateFieldFulfillmentAddress:
+		r.FulfillmentAddress = nil
+	case UpdateFieldFulfillmentOptionID:
+		r.FulfillmentOptionId = nil
+	default:
+		return
+	}
+	if !r.Clears(field) {
+		r.cleared = append(r.cleared, field)
+	}
+}
+
+// UnmarshalJSON decodes the request, rejecting unknown fields, and records
+// which clearable fields were sent as null.
+func (r *CheckoutSessionUpdateRequest) UnmarshalJSON(data []byte) error {
+	type plain CheckoutSessionUpdateRequest
+	var decoded plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&decoded); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	decoded.cleared = nil
+	for _, field := range clearableFields {
+		if raw, ok := fields[string(field)]; ok && bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			decoded.cleared = append(decoded.cleared, field)
+		}
+	}
+	*r = CheckoutSessionUpdateRequest(decoded)
+	return nil
+}
+
+// MarshalJSON encodes the request, writing cleared fields as null.
+func (r CheckoutSessionUpdateRequest) MarshalJSON() ([]byte, error) {
+	type plain CheckoutSessionUpdateRequest
+	data, err := json.Marshal(plain(r))
+	if err != nil || len(r.cleared) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, field := range r.cleared {
+		fields[string(field)] = json.RawMessage("null")
+	}
+	return json.Marshal(fields)
+}
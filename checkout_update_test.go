@@ -0,0 +1,173 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckoutSessionUpdateRequestClears(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body        string
+		wantBuyer   bool
+		wantCleared []UpdateField
+	}{
+		"omitted fields": {
+			body: `{}`,
+		},
+		"buyer value": {
+			body:      `{"buyer":{"first_name":"Ada","last_name":"Lovelace","email":"ada@example.com"}}`,
+			wantBuyer: true,
+		},
+		"null buyer": {
+			body:        `{"buyer":null}`,
+			wantCleared: []UpdateField{UpdateFieldBuyer},
+		},
+		"all null": {
+			body:        `{"buyer":null,"fulfillment_address":null,"fulfillment_option_id":null}`,
+			wantCleared: []UpdateField{UpdateFieldBuyer, UpdateFieldFulfillmentAddress, UpdateFieldFulfillmentOptionID},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var req CheckoutSessionUpdateRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if (req.Buyer != nil) != tt.wantBuyer {
+				t.Fatalf("expected buyer set %t got %+v", tt.wantBuyer, req.Buyer)
+			}
+			for _, field := range clearableFields {
+				want := false
+				for _, cleared := range tt.wantCleared {
+					want = want || cleared == field
+				}
+				if got := req.Clears(field); got != want {
+					t.Fatalf("expected Clears(%s) = %t got %t", field, want, got)
+				}
+			}
+			encoded, err := json.Marshal(req)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var roundTrip CheckoutSessionUpdateRequest
+			if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+				t.Fatalf("unmarshal round trip: %v", err)
+			}
+			for _, field := range tt.wantCleared {
+				if !roundTrip.Clears(field) {
+					t.Fatalf("expected %s to stay cleared after round trip, encoded %s", field, encoded)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutSessionUpdateRequestRejectsUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	var req CheckoutSessionUpdateRequest
+	if err := json.Unmarshal([]byte(`{"buyer":null,"coupon":"X"}`), &req); err == nil {
+		t.Fatalf("expected unknown field error")
+	}
+}
+
+func TestCheckoutSessionUpdateRequestClear(t *testing.T) {
+	t.Parallel()
+
+	id := "ship_1"
+	req := CheckoutSessionUpdateRequest{FulfillmentOptionId: &id}
+	req.Clear(UpdateFieldFulfillmentOptionID)
+	req.Clear(UpdateFieldFulfillmentOptionID)
+
+	if req.FulfillmentOptionId != nil || !req.Clears(UpdateFieldFulfillmentOptionID) {
+		t.Fatalf("expected fulfillment option to be cleared got %+v", req)
+	}
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(encoded) != `{"fulfillment_option_id":null}` {
+		t.Fatalf("expected null fulfillment_option_id got %s", encoded)
+	}
+}
+
+func TestCheckoutHandlerUpdatePassesClearedFields(t *testing.T) {
+	t.Parallel()
+
+	var got CheckoutSessionUpdateRequest
+	handler := NewCheckoutHandler(&stubService{
+		update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+			got = req
+			return &CheckoutSession{ID: id}, nil
+		},
+	})
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(`{"buyer":null}`)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !got.Clears(UpdateFieldBuyer) {
+		t.Fatalf("expected provider to see the cleared buyer")
+	}
+}
+
+func TestCheckoutHandlerDecodeTypeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path      string
+		body      string
+		wantParam string
+		wantMsg   string
+	}{
+		"create": {
+			path:      "/checkout_sessions",
+			body:      `{"items":[{"id":"sku_1","quantity":"2"}]}`,
+			wantParam: "$.items[0].quantity",
+			wantMsg:   "items[0].quantity must be an integer",
+		},
+		"update": {
+			path:      "/checkout_sessions/cs_123",
+			body:      `{"items":[{"id":"sku_1","quantity":1},{"id":"sku_2","quantity":"2"}]}`,
+			wantParam: "$.items[1].quantity",
+			wantMsg:   "items[1].quantity must be an integer",
+		},
+		"update object": {
+			path:      "/checkout_sessions/cs_123",
+			body:      `{"buyer":"jane"}`,
+			wantParam: "$.buyer",
+			wantMsg:   "buyer must be an object",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			NewCheckoutHandler(&stubService{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400 got %d: %s", rec.Code, rec.Body.String())
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Param == nil || *payload.Param != tt.wantParam || payload.Message != tt.wantMsg {
+				t.Fatalf("expected %q on %s got %+v", tt.wantMsg, tt.wantParam, payload)
+			}
+		})
+	}
+}
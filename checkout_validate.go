@@ -5,45 +5,69 @@ import (
 	"fmt"
 )
 
-// Validate ensures CheckoutSessionCreateRequest satisfies required schema constraints.
+// Validate ensures CheckoutSessionCreateRequest satisfies required schema
+// constraints, with the default [WithCartLimits].
 func (r CheckoutSessionCreateRequest) Validate() error {
+	return r.validate(defaultCartLimits)
+}
+
+func (r CheckoutSessionCreateRequest) validate(limits cartLimits) error {
 	if len(r.Items) == 0 {
 		return errors.New("items must contain at least one entry")
 	}
+	if err := limits.validateCartSize(r.Items); err != nil {
+		return err
+	}
+	if r.Currency != nil && !isISO4217Currency(*r.Currency) {
+		return NewInvalidRequestError(fmt.Sprintf("currency %q is not an ISO-4217 currency code", *r.Currency), WithOffendingParam("$.currency"))
+	}
 	for i, item := range r.Items {
 		if item.ID == "" {
 			return fmt.Errorf("items[%d]: id is required", i)
 		}
-		if item.Quantity <= 0 {
-			return fmt.Errorf("items[%d]: quantity must be positive", i)
+		if err := validateItemQuantity(i, item.Quantity); err != nil {
+			return err
 		}
 	}
-	if r.Buyer != nil {
-		if r.Buyer.FirstName == "" || r.Buyer.LastName == "" || string(r.Buyer.Email) == "" {
-			return errors.New("buyer requires first_name, last_name, and email")
-		}
+	if err := limits.validateCartQuantity(r.Items); err != nil {
+		return err
 	}
-	return nil
+	if err := validateBuyer(r.Buyer); err != nil {
+		return err
+	}
+	return validateAddressCountry("fulfillment_address", r.FulfillmentAddress)
 }
 
-// Validate ensures CheckoutSessionUpdateRequest maintains schema constraints.
+// Validate ensures CheckoutSessionUpdateRequest maintains schema constraints,
+// with the default [WithCartLimits].
 func (r CheckoutSessionUpdateRequest) Validate() error {
+	return r.validate(defaultCartLimits)
+}
+
+func (r CheckoutSessionUpdateRequest) validate(limits cartLimits) error {
 	if r.Items != nil {
+		if err := limits.validateCartSize(*r.Items); err != nil {
+			return err
+		}
 		for i, item := range *r.Items {
 			if item.ID == "" {
 				return fmt.Errorf("items[%d]: id is required", i)
 			}
-			if item.Quantity <= 0 {
-				return fmt.Errorf("items[%d]: quantity must be positive", i)
+			if err := validateItemQuantity(i, item.Quantity); err != nil {
+				return err
 			}
 		}
-	}
-	if r.Buyer != nil {
-		if r.Buyer.FirstName == "" || r.Buyer.LastName == "" || string(r.Buyer.Email) == "" {
-			return errors.New("buyer requires first_name, last_name, and email")
+		if err := limits.validateCartQuantity(*r.Items); err != nil {
+			return err
 		}
 	}
-	return nil
+	if err := validateBuyer(r.Buyer); err != nil {
+		return err
+	}
+	if err := validateDiscountCodes(r.DiscountCodes); err != nil {
+		return err
+	}
+	return validateAddressCountry("fulfillment_address", r.FulfillmentAddress)
 }
 
 // Validate ensures CheckoutSessionCompleteRequest satisfies payment requirements.
@@ -56,3 +80,44 @@ func (r CheckoutSessionCompleteRequest) Validate() error {
 	}
 	return nil
 }
+
+// validateBuyer requires the buyer's name and email, and checks that the
+// email is a valid address and the phone number, when present, is in E.164
+// format (e.g. +15551234567).
+func validateBuyer(buyer *Buyer) error {
+	if buyer == nil {
+		return nil
+	}
+	if buyer.FirstName == "" || buyer.LastName == "" || buyer.Email == "" {
+		return errors.New("buyer requires first_name, last_name, and email")
+	}
+	if validate.Var(buyer.Email, "email") != nil {
+		return NewInvalidRequestError(fmt.Sprintf("buyer.email %q is not a valid email address", buyer.Email), WithOffendingParam("$.buyer.email"))
+	}
+	if buyer.PhoneNumber != nil && validate.Var(*buyer.PhoneNumber, "e164") != nil {
+		return NewInvalidRequestError(fmt.Sprintf("buyer.phone_number %q must be in E.164 format, e.g. +15551234567", *buyer.PhoneNumber), WithOffendingParam("$.buyer.phone_number"))
+	}
+	return nil
+}
+
+// validateAddressCountry rejects addresses whose country is not an ISO 3166-1
+// alpha-2 code; field is the JSON name of the address.
+func validateAddressCountry(field string, address *Address) error {
+	if address == nil || isISO3166Country(address.Country) {
+		return nil
+	}
+	return NewInvalidRequestError(fmt.Sprintf("%s.country %s", field, countryMessage), WithOffendingParam("$."+field+".country"))
+}
+
+// validateItemQuantity separates zero quantities, which usually mean the client
+// meant to remove the item, from negative ones.
+func validateItemQuantity(i, quantity int) error {
+	param := WithOffendingParam(fmt.Sprintf("$.items[%d].quantity", i))
+	switch {
+	case quantity == 0:
+		return NewInvalidRequestError(fmt.Sprintf("items[%d]: quantity must not be zero; omit the item to remove it", i), param)
+	case quantity < 0:
+		return NewInvalidRequestError(fmt.Sprintf("items[%d]: quantity must not be negative", i), param)
+	}
+	return nil
+}
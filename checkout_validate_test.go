@@ -0,0 +1,72 @@
+package acp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuyerValidation(t *testing.T) {
+	t.Parallel()
+
+	phone := func(v string) *string { return &v }
+	tests := map[string]struct {
+		buyer     Buyer
+		wantErr   bool
+		wantParam string
+	}{
+		"valid": {
+			buyer: Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", PhoneNumber: phone("+15551234567")},
+		},
+		"valid without phone": {
+			buyer: Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane.doe+acp@example.co.uk"},
+		},
+		"missing email": {
+			buyer:   Buyer{FirstName: "Jane", LastName: "Doe"},
+			wantErr: true,
+		},
+		"invalid email": {
+			buyer:     Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane@"},
+			wantErr:   true,
+			wantParam: "$.buyer.email",
+		},
+		"email without at": {
+			buyer:     Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane.example.com"},
+			wantErr:   true,
+			wantParam: "$.buyer.email",
+		},
+		"phone without plus": {
+			buyer:     Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", PhoneNumber: phone("5551234567")},
+			wantErr:   true,
+			wantParam: "$.buyer.phone_number",
+		},
+		"formatted phone": {
+			buyer:     Buyer{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", PhoneNumber: phone("+1 (555) 123-4567")},
+			wantErr:   true,
+			wantParam: "$.buyer.phone_number",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			buyer := tt.buyer
+			errs := map[string]error{
+				"create": CheckoutSessionCreateRequest{Items: []Item{{ID: "sku_1", Quantity: 1}}, Buyer: &buyer}.Validate(),
+				"update": CheckoutSessionUpdateRequest{Buyer: &buyer}.Validate(),
+			}
+			for kind, err := range errs {
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s: expected error=%v got %v", kind, tt.wantErr, err)
+				}
+				if tt.wantParam == "" {
+					continue
+				}
+				var acpErr *Error
+				if !errors.As(err, &acpErr) || acpErr.Param == nil || *acpErr.Param != tt.wantParam {
+					t.Fatalf("%s: expected param %s got %v", kind, tt.wantParam, err)
+				}
+			}
+		})
+	}
+}
@@ -12,6 +12,9 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/sumup/acp/signature"
 )
 
 // WebhookEventType enumerates the supported checkout webhook events.
@@ -20,13 +23,15 @@ type WebhookEventType string
 const (
 	WebhookEventTypeOrderCreated WebhookEventType = "order_created"
 	WebhookEventTypeOrderUpdated WebhookEventType = "order_updated"
+	WebhookEventTypeRefundIssued WebhookEventType = "refund_issued"
 )
 
 // EventDataType labels the payload for a webhook event.
 type EventDataType string
 
 const (
-	EventDataTypeOrder EventDataType = "order"
+	EventDataTypeOrder  EventDataType = "order"
+	EventDataTypeRefund EventDataType = "refund"
 )
 
 // OrderStatus defines model for webhook data status.
@@ -82,12 +87,108 @@ type OrderUpdated struct {
 
 func (OrderUpdated) eventType() WebhookEventType { return WebhookEventTypeOrderUpdated }
 
+// RefundIssued emits a single refund as it is issued, distinct from the
+// status changes reported by [OrderUpdated]. Build it with [NewRefundIssued].
+type RefundIssued struct {
+	Type              EventDataType `json:"type"`
+	CheckoutSessionID string        `json:"checkout_session_id"`
+	Refund            Refund        `json:"refund"`
+}
+
+func (RefundIssued) eventType() WebhookEventType { return WebhookEventTypeRefundIssued }
+
+// NewRefundIssued returns a [RefundIssued] event for checkoutSessionID,
+// rejecting unknown refund types and non-positive amounts.
+func NewRefundIssued(checkoutSessionID string, refundType RefundType, amount int) (RefundIssued, error) {
+	if checkoutSessionID == "" {
+		return RefundIssued{}, errors.New("checkout: refund checkout session id is required")
+	}
+	switch refundType {
+	case RefundTypeStoreCredit, RefundTypeOriginalPayment:
+	default:
+		return RefundIssued{}, fmt.Errorf("checkout: unsupported refund type %q", refundType)
+	}
+	if amount <= 0 {
+		return RefundIssued{}, fmt.Errorf("checkout: refund amount must be positive, got %d", amount)
+	}
+	return RefundIssued{
+		Type:              EventDataTypeRefund,
+		CheckoutSessionID: checkoutSessionID,
+		Refund:            Refund{Type: refundType, Amount: amount},
+	}, nil
+}
+
+// UnknownEvent is returned by [ParseWebhookEvent] for event types this
+// package does not model yet, so receivers can log and skip them instead of
+// failing. It re-encodes to the original data when sent with [CheckoutHandler.SendWebhook].
+type UnknownEvent struct {
+	// Type is the event type as received.
+	Type WebhookEventType
+	// Data is the raw data object of the event.
+	Data json.RawMessage
+}
+
+func (e UnknownEvent) eventType() WebhookEventType { return e.Type }
+
+// MarshalJSON encodes the raw event data.
+func (e UnknownEvent) MarshalJSON() ([]byte, error) {
+	if len(e.Data) == 0 {
+		return []byte("null"), nil
+	}
+	return e.Data, nil
+}
+
 type webhookEvent struct {
 	Type WebhookEventType `json:"type"`
 	Data any              `json:"data"`
 }
 
+// ParseWebhookEvent decodes a webhook body produced by [CheckoutHandler.SendWebhook]
+// into [OrderCreate], [OrderUpdated] or [RefundIssued]. Unrecognized event types yield an
+// [UnknownEvent] rather than an error, for forward compatibility.
+func ParseWebhookEvent(body []byte) (EventData, error) {
+	var envelope struct {
+		Type WebhookEventType `json:"type"`
+		Data json.RawMessage  `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("checkout: decode webhook event: %w", err)
+	}
+	if envelope.Type == "" {
+		return nil, errors.New("checkout: webhook event type is required")
+	}
+	var data EventData
+	switch envelope.Type {
+	case WebhookEventTypeOrderCreated:
+		var event OrderCreate
+		if err := json.Unmarshal(envelope.Data, &event); err != nil {
+			return nil, fmt.Errorf("checkout: decode %s data: %w", envelope.Type, err)
+		}
+		data = event
+	case WebhookEventTypeOrderUpdated:
+		var event OrderUpdated
+		if err := json.Unmarshal(envelope.Data, &event); err != nil {
+			return nil, fmt.Errorf("checkout: decode %s data: %w", envelope.Type, err)
+		}
+		data = event
+	case WebhookEventTypeRefundIssued:
+		var event RefundIssued
+		if err := json.Unmarshal(envelope.Data, &event); err != nil {
+			return nil, fmt.Errorf("checkout: decode %s data: %w", envelope.Type, err)
+		}
+		data = event
+	default:
+		data = UnknownEvent{Type: envelope.Type, Data: envelope.Data}
+	}
+	return data, nil
+}
+
 // SendWebhook posts webhook events to the OpenAI endpoint configured via [WithWebhookOptions].
+// Each delivery carries a Timestamp header taken from the handler clock (see
+// [WithClock]) and a signature over that timestamp and the body, so receivers
+// can reject replayed deliveries. With [WithWebhookOutbox], the
+// event is persisted first and SendWebhook only fails when it cannot be
+// enqueued; failed deliveries are retried by [CheckoutHandler.DeliverPending].
 func (h *CheckoutHandler) SendWebhook(ctx context.Context, data EventData) error {
 	if h.cfg.webhook == nil {
 		return errors.New("checkout: webhook options must be configured")
@@ -99,13 +200,24 @@ func (h *CheckoutHandler) SendWebhook(ctx context.Context, data EventData) error
 	if err != nil {
 		return fmt.Errorf("checkout: marshal webhook payload: %w", err)
 	}
+	if h.cfg.webhookOutbox != nil {
+		return h.enqueueWebhook(ctx, data.eventType(), body)
+	}
+	return h.deliverWebhook(ctx, body)
+}
+
+// deliverWebhook signs body under a fresh timestamp and posts it to the
+// webhook endpoint.
+func (h *CheckoutHandler) deliverWebhook(ctx context.Context, body []byte) error {
+	ts := h.cfg.clock().UTC()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.webhook.endpoint, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("checkout: build webhook request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("API-Version", APIVersion)
-	req.Header.Set(h.cfg.webhook.header, signWebhookPayload(h.cfg.webhook.secret, body))
+	req.Header.Set("Timestamp", ts.Format(time.RFC3339Nano))
+	req.Header.Set(h.cfg.webhook.header, signWebhookPayload(h.cfg.webhook.secret, signature.BuildSigningPayload(ts, body)))
 
 	resp, err := h.cfg.webhook.client.Do(req)
 	if err != nil {
@@ -113,12 +225,59 @@ func (h *CheckoutHandler) SendWebhook(ctx context.Context, data EventData) error
 	}
 	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("checkout: webhook endpoint %s returned %s: %s", h.cfg.webhook.endpoint, resp.Status, strings.TrimSpace(string(snippet)))
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+		return &WebhookDeliveryError{URL: h.cfg.webhook.endpoint, StatusCode: resp.StatusCode, Body: snippet}
 	}
 	return nil
 }
 
+// maxWebhookErrorBody caps the response body kept in a [WebhookDeliveryError].
+const maxWebhookErrorBody = 4096
+
+// WebhookDeliveryError is returned by [CheckoutHandler.SendWebhook] when the
+// endpoint answers with a non-2xx status, letting callers retry on 5xx but
+// alert on 4xx, for instance.
+type WebhookDeliveryError struct {
+	// URL is the webhook endpoint.
+	URL string
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Body holds up to the first 4096 bytes of the response body, which
+	// need not be JSON.
+	Body []byte
+}
+
+// Error makes *WebhookDeliveryError satisfy the stdlib error interface.
+func (e *WebhookDeliveryError) Error() string {
+	if e == nil {
+		return ""
+	}
+	msg := fmt.Sprintf("checkout: webhook endpoint %s returned %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if body := strings.TrimSpace(strings.ToValidUTF8(string(e.Body), "\uFFFD")); body != "" {
+		msg += ": " + body
+	}
+	return msg
+}
+
+// VerifyWebhookSignature checks the signature header value of a webhook
+// delivery made by [CheckoutHandler.SendWebhook] against the HMAC-SHA256 of
+// its Timestamp header and body under secret, comparing in constant time. It
+// returns the verified timestamp so receivers can reject deliveries outside
+// their replay window. Receivers and tests use it with the raw request body.
+func VerifyWebhookSignature(secret, body []byte, timestamp, sig string) (time.Time, error) {
+	if len(secret) == 0 {
+		return time.Time{}, errors.New("checkout: webhook secret key is required")
+	}
+	ts, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("checkout: invalid webhook timestamp: %w", err)
+	}
+	if !signature.ConstantTimeEqualString(sig, signWebhookPayload(secret, signature.BuildSigningPayload(ts, body))) {
+		return time.Time{}, errors.New("checkout: invalid webhook signature")
+	}
+	return ts, nil
+}
+
 func signWebhookPayload(secret, payload []byte) string {
 	mac := hmac.New(sha256.New, secret)
 	_, _ = mac.Write(payload)
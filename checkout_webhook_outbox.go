@@ -0,0 +1,134 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// WebhookDelivery is a webhook event persisted in a [WebhookOutbox].
+type WebhookDelivery struct {
+	// ID identifies the delivery in the outbox.
+	ID string
+	// Type is the type of the event.
+	Type WebhookEventType
+	// Body is the JSON body posted to the endpoint. It is stored without a
+	// timestamp or signature and is re-signed on each attempt, under that
+	// attempt's Timestamp header.
+	Body []byte
+}
+
+// WebhookOutbox persists webhook events until they are delivered, for
+// [WithWebhookOutbox]. PendingBatch returns up to limit undelivered events,
+// oldest first, and keeps returning an event until MarkDelivered is called
+// with its ID. Implementations must be safe for concurrent use.
+type WebhookOutbox interface {
+	Enqueue(ctx context.Context, delivery WebhookDelivery) error
+	MarkDelivered(ctx context.Context, id string) error
+	PendingBatch(ctx context.Context, limit int) ([]WebhookDelivery, error)
+}
+
+// NewMemoryWebhookOutbox returns a process-local [WebhookOutbox]. Pending
+// events are lost on restart, so it suits tests and development only.
+func NewMemoryWebhookOutbox() WebhookOutbox {
+	return &memoryWebhookOutbox{}
+}
+
+type memoryWebhookOutbox struct {
+	mu      sync.Mutex
+	pending []WebhookDelivery
+}
+
+func (o *memoryWebhookOutbox) Enqueue(_ context.Context, delivery WebhookDelivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delivery.Body = slices.Clone(delivery.Body)
+	o.pending = append(o.pending, delivery)
+	return nil
+}
+
+func (o *memoryWebhookOutbox) MarkDelivered(_ context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending = slices.DeleteFunc(o.pending, func(d WebhookDelivery) bool { return d.ID == id })
+	return nil
+}
+
+func (o *memoryWebhookOutbox) PendingBatch(_ context.Context, limit int) ([]WebhookDelivery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return slices.Clone(o.pending[:min(limit, len(o.pending))]), nil
+}
+
+// WithWebhookOutbox makes [CheckoutHandler.SendWebhook] persist every event
+// in outbox before attempting delivery, so events whose delivery fails, or
+// is interrupted by a restart, are retried by
+// [CheckoutHandler.DeliverPending]. Requires [WithWebhookOptions].
+func WithWebhookOutbox(outbox WebhookOutbox) Option {
+	if outbox == nil {
+		return invalidOption(errors.New("checkout: webhook outbox is required"))
+	}
+	return func(cfg *config) {
+		cfg.webhookOutbox = outbox
+	}
+}
+
+// webhookOutboxBatchSize is the number of events DeliverPending requests at once.
+const webhookOutboxBatchSize = 50
+
+// enqueueWebhook persists body in the outbox and attempts its delivery once.
+// A failed delivery is left pending rather than reported.
+func (h *CheckoutHandler) enqueueWebhook(ctx context.Context, eventType WebhookEventType, body []byte) error {
+	delivery := WebhookDelivery{ID: uuid.NewString(), Type: eventType, Body: body}
+	if err := h.cfg.webhookOutbox.Enqueue(ctx, delivery); err != nil {
+		return fmt.Errorf("checkout: enqueue webhook: %w", err)
+	}
+	if h.deliverWebhook(ctx, body) != nil {
+		return nil
+	}
+	if err := h.cfg.webhookOutbox.MarkDelivered(ctx, delivery.ID); err != nil {
+		return fmt.Errorf("checkout: mark webhook delivered: %w", err)
+	}
+	return nil
+}
+
+// DeliverPending drains the [WithWebhookOutbox] outbox, delivering pending
+// events oldest first with the signing of [CheckoutHandler.SendWebhook]. It
+// stops after a batch in which a delivery failed, since the failed events
+// stay pending, and returns the delivery errors joined. Call it on startup
+// and periodically, for instance from a ticker, to get at-least-once
+// delivery: receivers should expect duplicates.
+func (h *CheckoutHandler) DeliverPending(ctx context.Context) error {
+	if h.cfg.webhook == nil || h.cfg.webhookOutbox == nil {
+		return errors.New("checkout: webhook options and outbox must be configured")
+	}
+	for {
+		batch, err := h.cfg.webhookOutbox.PendingBatch(ctx, webhookOutboxBatchSize)
+		if err != nil {
+			return fmt.Errorf("checkout: load pending webhooks: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		var errs []error
+		for _, delivery := range batch {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			if err := h.deliverWebhook(ctx, delivery.Body); err != nil {
+				errs = append(errs, fmt.Errorf("webhook %s: %w", delivery.ID, err))
+				continue
+			}
+			if err := h.cfg.webhookOutbox.MarkDelivered(ctx, delivery.ID); err != nil {
+				return errors.Join(append(errs, fmt.Errorf("checkout: mark webhook delivered: %w", err))...)
+			}
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	t.Parallel()
+
+	var (
+		failing  atomic.Bool
+		mu       sync.Mutex
+		received []string
+	)
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+
+	outbox := NewMemoryWebhookOutbox()
+	handler := NewCheckoutHandler(&stubService{}, WithWebhookOptions(WebhookOptions{
+		Endpoint:               srv.URL,
+		AllowInsecureLocalhost: true,
+		HeaderName:             "Merchant_Name-Signature",
+		SecretKey:              []byte("super-secret"),
+		Client:                 srv.Client(),
+	}), WithWebhookOutbox(outbox))
+	ctx := context.Background()
+	pending := func() int {
+		batch, err := outbox.PendingBatch(ctx, 100)
+		if err != nil {
+			t.Fatalf("PendingBatch() error = %v", err)
+		}
+		return len(batch)
+	}
+
+	first := OrderCreate{Type: EventDataTypeOrder, CheckoutSessionID: "cs_1", Status: OrderStatusCreated}
+	if err := handler.SendWebhook(ctx, first); err != nil {
+		t.Fatalf("expected failed delivery to be enqueued got %v", err)
+	}
+	if got := pending(); got != 1 {
+		t.Fatalf("expected 1 pending event got %d", got)
+	}
+
+	var deliveryErr *WebhookDeliveryError
+	if err := handler.DeliverPending(ctx); !errors.As(err, &deliveryErr) || deliveryErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected delivery error got %v", err)
+	}
+	if got := pending(); got != 1 {
+		t.Fatalf("expected event to stay pending got %d", got)
+	}
+
+	failing.Store(false)
+	second := OrderCreate{Type: EventDataTypeOrder, CheckoutSessionID: "cs_2", Status: OrderStatusCreated}
+	if err := handler.SendWebhook(ctx, second); err != nil {
+		t.Fatalf("SendWebhook() error = %v", err)
+	}
+	if got := pending(); got != 1 {
+		t.Fatalf("expected delivered event to leave the outbox got %d pending", got)
+	}
+	if err := handler.DeliverPending(ctx); err != nil {
+		t.Fatalf("DeliverPending() error = %v", err)
+	}
+	if got := pending(); got != 0 {
+		t.Fatalf("expected empty outbox got %d pending", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected 2 deliveries got %d", len(received))
+	}
+}
+
+func TestDeliverPendingDrainsSeveralBatches(t *testing.T) {
+	t.Parallel()
+
+	var delivered atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	outbox := NewMemoryWebhookOutbox()
+	total := webhookOutboxBatchSize + 10
+	for i := range total {
+		id := fmt.Sprintf("evt_%d", i)
+		if err := outbox.Enqueue(context.Background(), WebhookDelivery{ID: id, Type: WebhookEventTypeOrderCreated, Body: []byte(`{}`)}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	handler := NewCheckoutHandler(&stubService{}, WithWebhookOptions(WebhookOptions{
+		Endpoint:               srv.URL,
+		AllowInsecureLocalhost: true,
+		HeaderName:             "Merchant_Name-Signature",
+		SecretKey:              []byte("super-secret"),
+		Client:                 srv.Client(),
+	}), WithWebhookOutbox(outbox))
+
+	if err := handler.DeliverPending(context.Background()); err != nil {
+		t.Fatalf("DeliverPending() error = %v", err)
+	}
+	if got := delivered.Load(); got != int64(total) {
+		t.Fatalf("expected %d deliveries got %d", total, got)
+	}
+}
+
+func TestDeliverPendingRequiresOutbox(t *testing.T) {
+	t.Parallel()
+
+	if err := NewCheckoutHandler(&stubService{}).DeliverPending(context.Background()); err == nil {
+		t.Fatal("expected error without webhook outbox")
+	}
+}
@@ -3,10 +3,16 @@ package acp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/sumup/acp/signature"
 )
 
 func TestCheckoutHandlerSendWebhook(t *testing.T) {
@@ -24,12 +30,14 @@ func TestCheckoutHandlerSendWebhook(t *testing.T) {
 	}))
 	t.Cleanup(srv.Close)
 
+	now := time.Date(2026, 3, 4, 5, 6, 7, 8, time.FixedZone("CET", 3600))
 	handler := NewCheckoutHandler(&stubService{}, WithWebhookOptions(WebhookOptions{
-		Endpoint:   srv.URL,
-		HeaderName: "Merchant_Name-Signature",
-		SecretKey:  []byte("super-secret"),
-		Client:     srv.Client(),
-	}))
+		Endpoint:               srv.URL,
+		AllowInsecureLocalhost: true,
+		HeaderName:             "Merchant_Name-Signature",
+		SecretKey:              []byte("super-secret"),
+		Client:                 srv.Client(),
+	}), WithClock(func() time.Time { return now }))
 
 	event := OrderCreate{
 		Type:              "order",
@@ -44,10 +52,17 @@ func TestCheckoutHandlerSendWebhook(t *testing.T) {
 	if got := received.header.Get("API-Version"); got != APIVersion {
 		t.Fatalf("missing API-Version header, got %q", got)
 	}
+	timestamp := received.header.Get("Timestamp")
+	if want := "2026-03-04T04:06:07.000000008Z"; timestamp != want {
+		t.Fatalf("expected Timestamp %q got %q", want, timestamp)
+	}
 	sig := received.header.Get("Merchant_Name-Signature")
-	expectedSig := signWebhookPayload([]byte("super-secret"), received.body)
-	if sig != expectedSig {
-		t.Fatalf("unexpected signature header %q", sig)
+	ts, err := VerifyWebhookSignature([]byte("super-secret"), received.body, timestamp, sig)
+	if err != nil {
+		t.Fatalf("unexpected signature header %q: %v", sig, err)
+	}
+	if !ts.Equal(now) {
+		t.Fatalf("expected verified timestamp %s got %s", now, ts)
 	}
 
 	var decoded struct {
@@ -67,3 +82,257 @@ func TestCheckoutHandlerSendWebhook(t *testing.T) {
 		t.Fatalf("unexpected checkout_session_id %s", decoded.Data.CheckoutSessionID)
 	}
 }
+
+func TestWithWebhookOptionsEndpointScheme(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		endpoint       string
+		allowLocalhost bool
+		wantErr        bool
+	}{
+		"https endpoint": {
+			endpoint: "https://openai.example/webhooks",
+		},
+		"http endpoint": {
+			endpoint: "http://openai.example/webhooks",
+			wantErr:  true,
+		},
+		"http localhost without escape hatch": {
+			endpoint: "http://localhost:8080/webhooks",
+			wantErr:  true,
+		},
+		"http localhost with escape hatch": {
+			endpoint:       "http://localhost:8080/webhooks",
+			allowLocalhost: true,
+		},
+		"http loopback ip with escape hatch": {
+			endpoint:       "http://[::1]:8080/webhooks",
+			allowLocalhost: true,
+		},
+		"http remote host with escape hatch": {
+			endpoint:       "http://openai.example/webhooks",
+			allowLocalhost: true,
+			wantErr:        true,
+		},
+		"relative endpoint": {
+			endpoint: "/webhooks",
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewCheckoutHandlerWithError(&stubService{}, WithWebhookOptions(WebhookOptions{
+				Endpoint:               tt.endpoint,
+				AllowInsecureLocalhost: tt.allowLocalhost,
+				HeaderName:             "Merchant_Name-Signature",
+				SecretKey:              []byte("super-secret"),
+			}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseWebhookEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body    string
+		want    EventData
+		wantErr bool
+	}{
+		"known event": {
+			body: `{"type":"order_created","data":{"type":"order","checkout_session_id":"cs_123","permalink_url":"https://merchant.example/orders/cs_123","status":"created","refunds":[]}}`,
+			want: OrderCreate{
+				Type:              EventDataTypeOrder,
+				CheckoutSessionID: "cs_123",
+				PermalinkURL:      "https://merchant.example/orders/cs_123",
+				Status:            OrderStatusCreated,
+				Refunds:           []Refund{},
+			},
+		},
+		"refund issued": {
+			body: `{"type":"refund_issued","data":{"type":"refund","checkout_session_id":"cs_123","refund":{"type":"original_payment","amount":500}}}`,
+			want: RefundIssued{
+				Type:              EventDataTypeRefund,
+				CheckoutSessionID: "cs_123",
+				Refund:            Refund{Type: RefundTypeOriginalPayment, Amount: 500},
+			},
+		},
+		"unknown event": {
+			body: `{"type":"order_disputed","data":{"checkout_session_id":"cs_123"}}`,
+			want: UnknownEvent{Type: "order_disputed", Data: json.RawMessage(`{"checkout_session_id":"cs_123"}`)},
+		},
+		"missing type": {
+			body:    `{"data":{}}`,
+			wantErr: true,
+		},
+		"invalid JSON": {
+			body:    `{"type":`,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseWebhookEvent([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWebhookEvent() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseWebhookEvent() = %#v want %#v", got, tt.want)
+			}
+			reencoded, err := json.Marshal(webhookEvent{Type: got.eventType(), Data: got})
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if !signature.CanonicalEqual(reencoded, []byte(tt.body)) {
+				t.Fatalf("expected round trip %s got %s", tt.body, reencoded)
+			}
+		})
+	}
+}
+
+func TestNewRefundIssued(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sessionID  string
+		refundType RefundType
+		amount     int
+		wantErr    string
+	}{
+		"valid":           {sessionID: "cs_123", refundType: RefundTypeStoreCredit, amount: 100},
+		"missing id":      {refundType: RefundTypeStoreCredit, amount: 100, wantErr: "session id is required"},
+		"unknown type":    {sessionID: "cs_123", refundType: "voucher", amount: 100, wantErr: `unsupported refund type "voucher"`},
+		"zero amount":     {sessionID: "cs_123", refundType: RefundTypeOriginalPayment, wantErr: "must be positive"},
+		"negative amount": {sessionID: "cs_123", refundType: RefundTypeOriginalPayment, amount: -1, wantErr: "must be positive"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			event, err := NewRefundIssued(tt.sessionID, tt.refundType, tt.amount)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRefundIssued() error = %v", err)
+			}
+			if event.Type != EventDataTypeRefund || event.Refund.Amount != tt.amount || event.eventType() != WebhookEventTypeRefundIssued {
+				t.Fatalf("unexpected event %#v", event)
+			}
+		})
+	}
+}
+
+func TestSendWebhookDeliveryError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		status  int
+		body    string
+		wantMsg string
+	}{
+		"client error": {
+			status:  http.StatusBadRequest,
+			body:    "missing signature\n",
+			wantMsg: "returned 400 Bad Request: missing signature",
+		},
+		"server error without body": {
+			status:  http.StatusBadGateway,
+			wantMsg: "returned 502 Bad Gateway",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(srv.Close)
+			handler := NewCheckoutHandler(&stubService{}, WithWebhookOptions(WebhookOptions{
+				Endpoint:               srv.URL,
+				AllowInsecureLocalhost: true,
+				HeaderName:             "Merchant_Name-Signature",
+				SecretKey:              []byte("super-secret"),
+				Client:                 srv.Client(),
+			}))
+
+			err := handler.SendWebhook(context.Background(), OrderUpdated{Type: EventDataTypeOrder, CheckoutSessionID: "cs_123"})
+
+			var deliveryErr *WebhookDeliveryError
+			if !errors.As(err, &deliveryErr) {
+				t.Fatalf("expected *WebhookDeliveryError got %T %v", err, err)
+			}
+			if deliveryErr.StatusCode != tt.status || string(deliveryErr.Body) != tt.body || deliveryErr.URL != srv.URL {
+				t.Fatalf("unexpected delivery error %+v", deliveryErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Fatalf("expected message containing %q got %q", tt.wantMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	t.Parallel()
+
+	secret, body := []byte("super-secret"), []byte(`{"type":"order_created"}`)
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	timestamp := now.Format(time.RFC3339Nano)
+	valid := signWebhookPayload(secret, signature.BuildSigningPayload(now, body))
+	tests := map[string]struct {
+		secret    []byte
+		body      []byte
+		timestamp string
+		sig       string
+		wantErr   bool
+	}{
+		"valid":             {secret: secret, body: body, timestamp: timestamp, sig: valid},
+		"tampered body":     {secret: secret, body: []byte(`{"type":"order_updated"}`), timestamp: timestamp, sig: valid, wantErr: true},
+		"tampered time":     {secret: secret, body: body, timestamp: now.Add(time.Minute).Format(time.RFC3339Nano), sig: valid, wantErr: true},
+		"body-only sig":     {secret: secret, body: body, timestamp: timestamp, sig: signWebhookPayload(secret, body), wantErr: true},
+		"missing timestamp": {secret: secret, body: body, sig: valid, wantErr: true},
+		"wrong secret":      {secret: []byte("other"), body: body, timestamp: timestamp, sig: valid, wantErr: true},
+		"truncated":         {secret: secret, body: body, timestamp: timestamp, sig: valid[:len(valid)-4], wantErr: true},
+		"missing":           {secret: secret, body: body, timestamp: timestamp, wantErr: true},
+		"no secret":         {body: body, timestamp: timestamp, sig: valid, wantErr: true},
+		"padded encoding":   {secret: secret, body: body, timestamp: timestamp, sig: valid + "=", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ts, err := VerifyWebhookSignature(tt.secret, tt.body, tt.timestamp, tt.sig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err == nil && !ts.Equal(now) {
+				t.Fatalf("expected timestamp %s got %s", now, ts)
+			}
+		})
+	}
+}
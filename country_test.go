@@ -0,0 +1,81 @@
+package acp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddressCountryValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		validate  func(country string) error
+		country   string
+		wantParam string
+	}{
+		"billing address valid": {
+			validate: func(country string) error {
+				req := sampleDelegatePaymentRequest()
+				req.BillingAddress = &Address{Name: "Jane", LineOne: "1 Main St", City: "Austin", PostalCode: "78701", Country: country}
+				return req.Validate()
+			},
+			country: "US",
+		},
+		"billing address unknown": {
+			validate: func(country string) error {
+				req := sampleDelegatePaymentRequest()
+				req.BillingAddress = &Address{Country: country}
+				return req.Validate()
+			},
+			country:   "ZZ",
+			wantParam: "$.billing_address.country",
+		},
+		"fulfillment address valid": {
+			validate: func(country string) error {
+				return CheckoutSessionCreateRequest{
+					Items:              []Item{{ID: "sku_1", Quantity: 1}},
+					FulfillmentAddress: &Address{Country: country},
+				}.Validate()
+			},
+			country: "US",
+		},
+		"fulfillment address unknown": {
+			validate: func(country string) error {
+				return CheckoutSessionCreateRequest{
+					Items:              []Item{{ID: "sku_1", Quantity: 1}},
+					FulfillmentAddress: &Address{Country: country},
+				}.Validate()
+			},
+			country:   "ZZ",
+			wantParam: "$.fulfillment_address.country",
+		},
+		"fulfillment address lowercase": {
+			validate: func(country string) error {
+				return CheckoutSessionUpdateRequest{FulfillmentAddress: &Address{Country: country}}.Validate()
+			},
+			country:   "us",
+			wantParam: "$.fulfillment_address.country",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.validate(tt.country)
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("expected %s to pass got %v", tt.country, err)
+				}
+				return
+			}
+			var acpErr *Error
+			if !errors.As(err, &acpErr) {
+				t.Fatalf("expected *Error got %v", err)
+			}
+			if acpErr.Param == nil || *acpErr.Param != tt.wantParam {
+				t.Fatalf("expected param %s got %v", tt.wantParam, acpErr.Param)
+			}
+		})
+	}
+}
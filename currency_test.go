@@ -0,0 +1,61 @@
+package acp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowanceCurrencyValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		currency    string
+		wantMessage string
+	}{
+		"known code": {
+			currency: "usd",
+		},
+		"unknown code": {
+			currency:    "zzz",
+			wantMessage: `allowance.currency "zzz" is not an ISO-4217 currency code`,
+		},
+		"uppercase code": {
+			currency:    "USD",
+			wantMessage: "allowance.currency must be a lowercase 3-letter ISO-4217 code",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := sampleDelegatePaymentRequest()
+			req.Allowance.Currency = tt.currency
+			err := req.Validate()
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantMessage {
+				t.Fatalf("expected error %q got %v", tt.wantMessage, err)
+			}
+		})
+	}
+}
+
+func TestCheckoutCreateCurrencyValidation(t *testing.T) {
+	t.Parallel()
+
+	for currency, wantErr := range map[string]bool{"usd": false, "EUR": false, "zzz": true} {
+		req := CheckoutSessionCreateRequest{Currency: &currency, Items: []Item{{ID: "sku_1", Quantity: 1}}}
+		err := req.Validate()
+		if (err != nil) != wantErr {
+			t.Fatalf("Validate() with currency %q error = %v want error %t", currency, err, wantErr)
+		}
+		if wantErr && !strings.Contains(err.Error(), "ISO-4217") {
+			t.Fatalf("expected ISO-4217 message got %v", err)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package acp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 )
 
 // DelegatedPaymentProvider owns the delegated payment tokenization lifecycle.
@@ -13,78 +16,199 @@ type DelegatedPaymentProvider interface {
 	DelegatePayment(ctx context.Context, req PaymentRequest) (*VaultToken, error)
 }
 
+// DelegatedPaymentDryRunner is optionally implemented by a [DelegatedPaymentProvider]
+// to run provider-side checks for dry-run requests without minting a vault token.
+type DelegatedPaymentDryRunner interface {
+	DryRunPayment(ctx context.Context, req PaymentRequest) error
+}
+
+// DryRunResult summarizes a validation-only delegate payment request, sent
+// with a `Dry-Run: true` header or a `dry_run=1` query parameter.
+type DryRunResult struct {
+	// Always true; distinguishes the summary from a [VaultToken].
+	DryRun bool `json:"dry_run"`
+	// Whether the payload passed validation.
+	Valid bool `json:"valid"`
+	// Whether the provider implements [DelegatedPaymentDryRunner] and accepted the payload.
+	ProviderChecked bool `json:"provider_checked"`
+	// Validation warnings for rules relaxed by [ValidationModeLenient].
+	Messages []MessageInfo `json:"messages,omitempty"`
+}
+
 // DelegatedPaymentHandler exposes the ACP delegate payment API over net/http.
 type DelegatedPaymentHandler struct {
 	service DelegatedPaymentProvider
 	mux     *http.ServeMux
+	routes  []Route
 	cfg     config
 }
 
 // NewDelegatedPaymentHandler wires the delegate payment routes to the provided [DelegatedPaymentProvider].
+// It panics on invalid options; see [NewDelegatedPaymentHandlerWithError].
 func NewDelegatedPaymentHandler(service DelegatedPaymentProvider, opts ...Option) *DelegatedPaymentHandler {
-	if service == nil {
-		panic("delegatedpayment: service is required")
-	}
-	cfg := config{
-		maxClockSkew: 5 * time.Minute,
-		clock:        time.Now,
+	h, err := NewDelegatedPaymentHandlerWithError(service, opts...)
+	if err != nil {
+		panic(err)
 	}
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-		opt(&cfg)
+	return h
+}
+
+// NewDelegatedPaymentHandlerWithError is like [NewDelegatedPaymentHandler] but
+// reports invalid or contradicting options as an error instead of panicking.
+func NewDelegatedPaymentHandlerWithError(service DelegatedPaymentProvider, opts ...Option) (*DelegatedPaymentHandler, error) {
+	if service == nil {
+		return nil, errors.New("delegatedpayment: service is required")
 	}
-	if cfg.requireSignedRequests && cfg.signatureVerifier == nil {
-		panic("delegatedpayment: signature verifier required when signed requests are enforced")
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 	h := &DelegatedPaymentHandler{
 		service: service,
 		mux:     http.NewServeMux(),
 		cfg:     cfg,
 	}
-	var middleware []Middleware
-	if mw := newSignatureMiddleware(signatureMiddlewareConfig{
-		Verifier:      cfg.signatureVerifier,
-		RequireSigned: cfg.requireSignedRequests,
-		MaxClockSkew:  cfg.maxClockSkew,
-		Clock:         cfg.clock,
-	}); mw != nil {
-		middleware = append(middleware, Middleware(mw))
-	}
+	var authentication Middleware
 	if cfg.authenticator != nil {
-		middleware = append(middleware, h.authenticationMiddleware)
+		authentication = h.authenticationMiddleware
 	}
-	middleware = append(middleware, cfg.middleware...)
-	h.registerRoutes(middleware...)
-	return h
+	h.registerRoutes(cfg.handlerMiddleware(authentication)...)
+	return h, nil
 }
 
 // ServeHTTP satisfies http.Handler.
 func (h *DelegatedPaymentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestCtx := requestContextFromRequest(r)
+	echoRequestMetadata(w, requestCtx)
 	ctx := contextWithRequestContext(r.Context(), requestCtx)
-	h.mux.ServeHTTP(w, r.WithContext(ctx))
+	ctx = contextWithResponseHeader(ctx, w.Header())
+	if h.cfg.requestIDGenerator != nil {
+		ctx = contextWithRequestIDGenerator(ctx, h.cfg.requestIDGenerator)
+	}
+	r = r.WithContext(ctx)
+	w = withErrorRendering(w, r, h.cfg)
+	if !limitRequestBody(w, r) {
+		return
+	}
+	serveMux(h.mux, h.cfg, w, r)
 }
 
 func (h *DelegatedPaymentHandler) registerRoutes(middleware ...Middleware) {
-	h.mux.HandleFunc("POST /agentic_commerce/delegate_payment", applyMiddleware(h.handleDelegatePayment, middleware...))
+	handleRoute(h.mux, &h.routes, http.MethodPost, "/agentic_commerce/delegate_payment", applyMiddleware(h.handleDelegatePayment, middleware...))
+	if _, ok := h.service.(DelegatedPaymentBatcher); ok {
+		handleRoute(h.mux, &h.routes, http.MethodPost, "/agentic_commerce/delegate_payment/batch", applyMiddleware(h.handleBatchDelegatePayment, middleware...))
+	}
+	if h.cfg.openAPI {
+		handleOpenAPI(h.mux, &h.routes, "Agentic Commerce Protocol Delegated Payment API", h.cfg.responseEnvelope, middleware...)
+	}
 }
 
 func (h *DelegatedPaymentHandler) handleDelegatePayment(w http.ResponseWriter, r *http.Request) {
-	var req PaymentRequest
-	if err := decodeJSON(r.Body, &req); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	req, decodeErr := decodeRequest[PaymentRequest](r, h.cfg)
+	if decodeErr != nil {
+		writeJSONError(w, decodeErr)
 		return
 	}
-	if err := req.Validate(); err != nil {
-		writeJSONError(w, NewInvalidRequestError(err.Error()))
+	warnings, validationErr := h.checkRequest(req)
+	if validationErr != nil {
+		writeJSONError(w, validationErr)
+		return
+	}
+	if isDryRun(r) {
+		result := DryRunResult{DryRun: true, Valid: true, Messages: warnings}
+		if runner, ok := h.service.(DelegatedPaymentDryRunner); ok {
+			if err := runner.DryRunPayment(r.Context(), req); err != nil {
+				writeServiceError(w, h.cfg, err)
+				return
+			}
+			result.ProviderChecked = true
+		}
+		writeResource(w, h.cfg, http.StatusOK, envelopeDryRun, result)
 		return
 	}
 	resp, err := h.service.DelegatePayment(r.Context(), req)
 	if err != nil {
-		writeServiceError(w, err)
+		writeServiceError(w, h.cfg, err)
+		return
+	}
+	if err := h.checkToken(resp); err != nil {
+		writeJSONError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, resp)
+	writeResource(w, h.cfg, http.StatusCreated, envelopeVaultToken, withWarnings(resp, warnings))
+}
+
+// checkRequest validates req according to the configured [ValidationMode],
+// accepted currencies, the allowance expiry and the card expiry. It returns the warnings of
+// relaxed rules for valid requests.
+func (h *DelegatedPaymentHandler) checkRequest(req PaymentRequest) ([]MessageInfo, *Error) {
+	var (
+		warnings []MessageInfo
+		err      error
+	)
+	if h.cfg.validationMode == ValidationModeLenient {
+		warnings, err = req.validateLenient()
+	} else {
+		err = req.Validate()
+	}
+	if err != nil {
+		return nil, h.cfg.validationError(err)
+	}
+	if !h.cfg.currencyAccepted(req.Allowance.Currency) {
+		return nil, h.cfg.newValidationError(fmt.Sprintf("allowance.currency %q is not accepted", req.Allowance.Currency), WithOffendingParam("$.allowance.currency"))
+	}
+	if h.allowanceExpired(req.Allowance) {
+		return nil, h.cfg.newValidationError("allowance.expires_at must be in the future", WithOffendingParam("$.allowance.expires_at"))
+	}
+	if card, err := req.PaymentMethod.AsCard(); err == nil {
+		if param, expired := cardExpired(card, h.cfg.clock()); expired {
+			payload := h.cfg.newValidationError(strings.TrimPrefix(param, "$.")+" must not be in the past", WithOffendingParam(param))
+			payload.Code = InvalidCard
+			return nil, payload
+		}
+	}
+	if err := h.cfg.checkMetadata(req); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// checkToken guards against providers returning vault token metadata beyond
+// the limits of [WithMetadataLimits].
+func (h *DelegatedPaymentHandler) checkToken(token *VaultToken) *Error {
+	if token == nil {
+		return nil
+	}
+	if message := h.cfg.metadataViolation(token.Metadata); message != "" {
+		return NewProcessingError("vault token metadata " + message)
+	}
+	return nil
+}
+
+// withWarnings returns a copy of token carrying the validation warnings.
+func withWarnings(token *VaultToken, warnings []MessageInfo) *VaultToken {
+	if len(warnings) == 0 || token == nil {
+		return token
+	}
+	withMessages := *token
+	withMessages.Messages = append(append([]MessageInfo(nil), token.Messages...), warnings...)
+	return &withMessages
+}
+
+// allowanceExpired reports whether the allowance expired according to the
+// handler clock (see [WithClock]). Expiries up to [WithMaxClockSkew] in the
+// past are tolerated, as for signature timestamps, so an allowance is not
+// rejected early when our clock runs ahead of the agent's.
+func (h *DelegatedPaymentHandler) allowanceExpired(allowance Allowance) bool {
+	return !allowance.ExpiresAt.After(h.cfg.clock().Add(-h.cfg.maxClockSkew))
+}
+
+// isDryRun reports whether the caller asked for validation only.
+func isDryRun(r *http.Request) bool {
+	if value := strings.TrimSpace(r.Header.Get("Dry-Run")); value != "" {
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	enabled, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return enabled
 }
@@ -0,0 +1,99 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// DelegatedPaymentBatcher is optionally implemented by a [DelegatedPaymentProvider]
+// to tokenize several allowances in one round trip, such as split-tender
+// orders. It enables the POST /agentic_commerce/delegate_payment/batch route.
+type DelegatedPaymentBatcher interface {
+	// BatchDelegatePayment receives the requests that passed validation and
+	// returns one token per request, in the same order. A nil token reports
+	// that entry as failed without failing the others; an error fails the
+	// whole batch.
+	BatchDelegatePayment(ctx context.Context, reqs []PaymentRequest) ([]*VaultToken, error)
+}
+
+// BatchPaymentRequest is the body of a batch delegate payment request.
+type BatchPaymentRequest struct {
+	Requests []PaymentRequest `json:"requests"`
+}
+
+// BatchPaymentResponse lists the outcome of every entry of a
+// [BatchPaymentRequest], in request order. The batch responds with 200 OK
+// even when some or all entries failed; callers inspect each result.
+type BatchPaymentResponse struct {
+	Results []BatchPaymentResult `json:"results"`
+}
+
+// BatchPaymentResult is the outcome of a single batch entry: exactly one of
+// VaultToken and Error is set. Error params are relative to the entry, so a
+// failure on requests[1].allowance.currency reports $.allowance.currency
+// with Index 1.
+type BatchPaymentResult struct {
+	// Position of the entry in [BatchPaymentRequest.Requests].
+	Index int `json:"index"`
+	// Token minted for the entry.
+	VaultToken *VaultToken `json:"vault_token,omitempty"`
+	// Why the entry was rejected.
+	Error *Error `json:"error,omitempty"`
+}
+
+// handleBatchDelegatePayment is only registered when the service implements [DelegatedPaymentBatcher].
+func (h *DelegatedPaymentHandler) handleBatchDelegatePayment(w http.ResponseWriter, r *http.Request) {
+	batch, decodeErr := decodeRequest[BatchPaymentRequest](r, h.cfg)
+	if decodeErr != nil {
+		writeJSONError(w, decodeErr)
+		return
+	}
+	if len(batch.Requests) == 0 {
+		writeJSONError(w, h.cfg.newValidationError("requests must have at least 1 entries", WithOffendingParam("$.requests")))
+		return
+	}
+
+	results := make([]BatchPaymentResult, len(batch.Requests))
+	warnings := make([][]MessageInfo, len(batch.Requests))
+	var (
+		valid   []PaymentRequest
+		indexes []int
+	)
+	for i, req := range batch.Requests {
+		results[i].Index = i
+		entryWarnings, err := h.checkRequest(req)
+		if err != nil {
+			results[i].Error = renderError(w, err)
+			continue
+		}
+		warnings[i] = entryWarnings
+		valid = append(valid, req)
+		indexes = append(indexes, i)
+	}
+
+	if len(valid) > 0 {
+		tokens, err := h.service.(DelegatedPaymentBatcher).BatchDelegatePayment(r.Context(), valid)
+		if err != nil {
+			writeServiceError(w, h.cfg, err)
+			return
+		}
+		if len(tokens) != len(valid) {
+			writeJSONError(w, NewProcessingError(fmt.Sprintf("provider returned %d tokens for %d requests", len(tokens), len(valid))))
+			return
+		}
+		for j, token := range tokens {
+			i := indexes[j]
+			if token == nil {
+				results[i].Error = renderError(w, NewProcessingError("payment could not be delegated"))
+				continue
+			}
+			if err := h.checkToken(token); err != nil {
+				results[i].Error = renderError(w, err)
+				continue
+			}
+			results[i].VaultToken = withWarnings(token, warnings[i])
+		}
+	}
+	writeResource(w, h.cfg, http.StatusOK, envelopeBatchPayment, BatchPaymentResponse{Results: results})
+}
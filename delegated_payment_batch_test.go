@@ -0,0 +1,175 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type batchStubService struct {
+	delegatedStubService
+	batch func(context.Context, []PaymentRequest) ([]*VaultToken, error)
+}
+
+func (s *batchStubService) BatchDelegatePayment(ctx context.Context, reqs []PaymentRequest) ([]*VaultToken, error) {
+	return s.batch(ctx, reqs)
+}
+
+func TestDelegatedPaymentHandlerBatch(t *testing.T) {
+	t.Parallel()
+
+	valid := sampleDelegatePaymentRequest()
+	invalid := sampleDelegatePaymentRequest()
+	invalid.Allowance.Currency = "usdx"
+	declined := sampleDelegatePaymentRequest()
+	declined.Allowance.MerchantID = "declined"
+
+	tokenizeAll := func(ctx context.Context, reqs []PaymentRequest) ([]*VaultToken, error) {
+		tokens := make([]*VaultToken, len(reqs))
+		for i, req := range reqs {
+			if req.Allowance.MerchantID == "declined" {
+				continue
+			}
+			tokens[i] = &VaultToken{ID: "vt_" + req.Allowance.CheckoutSessionID, Created: time.Now()}
+		}
+		return tokens, nil
+	}
+
+	tests := map[string]struct {
+		requests    []PaymentRequest
+		batch       func(context.Context, []PaymentRequest) ([]*VaultToken, error)
+		wantStatus  int
+		wantTokens  []bool
+		wantParams  map[int]string
+		wantCalls   int
+		wantErrCode ErrorCode
+	}{
+		"all valid": {
+			requests:   []PaymentRequest{valid, valid},
+			batch:      tokenizeAll,
+			wantStatus: http.StatusOK,
+			wantTokens: []bool{true, true},
+			wantCalls:  2,
+		},
+		"partial validation failure": {
+			requests:   []PaymentRequest{valid, invalid, valid},
+			batch:      tokenizeAll,
+			wantStatus: http.StatusOK,
+			wantTokens: []bool{true, false, true},
+			wantParams: map[int]string{1: "$.allowance.currency"},
+			wantCalls:  2,
+		},
+		"provider declines an entry": {
+			requests:   []PaymentRequest{declined, valid},
+			batch:      tokenizeAll,
+			wantStatus: http.StatusOK,
+			wantTokens: []bool{false, true},
+			wantCalls:  2,
+		},
+		"all invalid skips provider": {
+			requests:   []PaymentRequest{invalid},
+			batch:      tokenizeAll,
+			wantStatus: http.StatusOK,
+			wantTokens: []bool{false},
+			wantParams: map[int]string{0: "$.allowance.currency"},
+		},
+		"empty batch": {
+			wantStatus:  http.StatusBadRequest,
+			wantErrCode: ErrorCode(InvalidRequest),
+		},
+		"provider error fails batch": {
+			requests: []PaymentRequest{valid},
+			batch: func(context.Context, []PaymentRequest) ([]*VaultToken, error) {
+				return nil, errors.New("boom")
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantErrCode: ErrorCode(ProcessingError),
+			wantCalls:   1,
+		},
+		"token count mismatch": {
+			requests: []PaymentRequest{valid, valid},
+			batch: func(context.Context, []PaymentRequest) ([]*VaultToken, error) {
+				return []*VaultToken{{ID: "vt_1"}}, nil
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantErrCode: ErrorCode(ProcessingError),
+			wantCalls:   2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			service := &batchStubService{batch: func(ctx context.Context, reqs []PaymentRequest) ([]*VaultToken, error) {
+				calls = len(reqs)
+				return tt.batch(ctx, reqs)
+			}}
+			body, err := json.Marshal(BatchPaymentRequest{Requests: tt.requests})
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment/batch", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("expected provider to receive %d requests got %d", tt.wantCalls, calls)
+			}
+			if tt.wantErrCode != "" {
+				if code := getErrorCode(rec.Body.Bytes()); code != string(tt.wantErrCode) {
+					t.Fatalf("expected error code %s got %s", tt.wantErrCode, code)
+				}
+				return
+			}
+			var resp BatchPaymentResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var gotTokens []bool
+			for i, result := range resp.Results {
+				if result.Index != i {
+					t.Fatalf("expected index %d got %d", i, result.Index)
+				}
+				if (result.VaultToken == nil) == (result.Error == nil) {
+					t.Fatalf("expected exactly one of vault_token and error got %+v", result)
+				}
+				gotTokens = append(gotTokens, result.VaultToken != nil)
+				if want, ok := tt.wantParams[i]; ok {
+					if result.Error.Param == nil || *result.Error.Param != want {
+						t.Fatalf("expected entry %d param %q got %v", i, want, result.Error.Param)
+					}
+				}
+			}
+			if !reflect.DeepEqual(gotTokens, tt.wantTokens) {
+				t.Fatalf("expected tokens %v got %v", tt.wantTokens, gotTokens)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerBatchRequiresBatcher(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment/batch", bytes.NewReader([]byte(`{"requests":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	NewDelegatedPaymentHandler(successService()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", rec.Code)
+	}
+}
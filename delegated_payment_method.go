@@ -0,0 +1,105 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// PaymentMethod is the union of payment method credentials accepted in a
+// delegated payment request, discriminated by its type field. Only cards
+// exist today; use [PaymentMethod.AsCard] and [PaymentMethod.FromCard] to
+// access them.
+//
+// Unlike the checkout unions, which hold raw JSON, PaymentMethod keeps the
+// decoded variant so credentials such as the card number stay wrapped in
+// [secret.Secret] and out of logs.
+type PaymentMethod struct {
+	typ  string
+	card *PaymentMethodCard
+}
+
+// unsupportedPaymentMethod stands in for payment methods of unknown types
+// during validation so they are reported on payment_method.type.
+type unsupportedPaymentMethod struct {
+	Type string `json:"type" validate:"oneof=card"`
+}
+
+// paymentMethodVariants lists the structs a [PaymentMethod] can hold, for [ValidationRules].
+var paymentMethodVariants = []reflect.Type{reflect.TypeFor[PaymentMethodCard]()}
+
+// Type returns the discriminator of the payment method, such as card, or ""
+// for the zero value.
+func (t PaymentMethod) Type() string {
+	return t.typ
+}
+
+// AsCard returns the card held by the payment method.
+func (t PaymentMethod) AsCard() (PaymentMethodCard, error) {
+	if t.card == nil {
+		return PaymentMethodCard{}, fmt.Errorf("payment method type is %q, not %q", t.typ, PaymentMethodCardTypeCard)
+	}
+	return *t.card, nil
+}
+
+// FromCard overwrites the payment method with v, defaulting its type to card.
+func (t *PaymentMethod) FromCard(v PaymentMethodCard) error {
+	if v.Type == "" {
+		v.Type = PaymentMethodCardTypeCard
+	}
+	t.typ = string(v.Type)
+	t.card = &v
+	return nil
+}
+
+// MarshalJSON serializes the payment method variant.
+func (t PaymentMethod) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.card != nil:
+		return json.Marshal(t.card)
+	case t.typ != "":
+		return json.Marshal(unsupportedPaymentMethod{Type: t.typ})
+	default:
+		return []byte("null"), nil
+	}
+}
+
+// UnmarshalJSON decodes the variant named by the type field, rejecting
+// unknown fields. Unknown types are kept so validation can report them.
+func (t *PaymentMethod) UnmarshalJSON(b []byte) error {
+	*t = PaymentMethod{}
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		return nil
+	}
+	var discriminator struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &discriminator); err != nil {
+		return err
+	}
+	t.typ = discriminator.Type
+	if discriminator.Type != string(PaymentMethodCardTypeCard) {
+		return nil
+	}
+	var card PaymentMethodCard
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&card); err != nil {
+		return err
+	}
+	t.card = &card
+	return nil
+}
+
+// validationValue returns the variant validated in place of the payment method.
+func (t PaymentMethod) validationValue() any {
+	switch {
+	case t.card != nil:
+		return *t.card
+	case t.typ != "":
+		return unsupportedPaymentMethod{Type: t.typ}
+	default:
+		return nil
+	}
+}
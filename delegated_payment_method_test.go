@@ -0,0 +1,221 @@
+package acp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPaymentMethodCardWireCompatible(t *testing.T) {
+	t.Parallel()
+
+	const body = `{"type":"card","card_number_type":"fpan","number":"4242424242424242","display_card_funding_type":"credit","metadata":{}}`
+
+	var method PaymentMethod
+	if err := json.Unmarshal([]byte(body), &method); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if method.Type() != "card" {
+		t.Fatalf("expected card type got %q", method.Type())
+	}
+	card, err := method.AsCard()
+	if err != nil {
+		t.Fatalf("AsCard() error = %v", err)
+	}
+	if card.Number.Value() != "4242424242424242" || card.DisplayCardFundingType != CardFundingTypeCredit {
+		t.Fatalf("unexpected card %+v", card)
+	}
+	encoded, err := json.Marshal(method)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	direct, err := json.Marshal(card)
+	if err != nil {
+		t.Fatalf("marshal card: %v", err)
+	}
+	if string(encoded) != string(direct) {
+		t.Fatalf("expected %s got %s", direct, encoded)
+	}
+}
+
+func TestPaymentMethodFromCardDefaultsType(t *testing.T) {
+	t.Parallel()
+
+	var method PaymentMethod
+	if err := method.FromCard(PaymentMethodCard{CardNumberType: CardCardNumberTypeFPAN}); err != nil {
+		t.Fatalf("FromCard() error = %v", err)
+	}
+	card, err := method.AsCard()
+	if err != nil || card.Type != PaymentMethodCardTypeCard || method.Type() != "card" {
+		t.Fatalf("expected card type got %q / %q (%v)", card.Type, method.Type(), err)
+	}
+}
+
+func TestPaymentRequestValidatePaymentMethod(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		paymentMethod string
+		wantParam     string
+		wantDecodeErr bool
+	}{
+		"missing": {
+			paymentMethod: `null`,
+			wantParam:     "$.payment_method",
+		},
+		"unsupported type": {
+			paymentMethod: `{"type":"bank_debit","iban":"DE00"}`,
+			wantParam:     "$.payment_method.type",
+		},
+		"invalid card": {
+			paymentMethod: `{"type":"card","card_number_type":"fpan","number":"4242","display_card_funding_type":"credit","display_last4":"42","metadata":{}}`,
+			wantParam:     "$.payment_method.display_last4",
+		},
+		"month out of range": {
+			paymentMethod: `{"type":"card","card_number_type":"fpan","number":"4242424242424242","display_card_funding_type":"credit","display_last4":"4242","metadata":{},"exp_month":"13","exp_year":"2030"}`,
+			wantParam:     "$.payment_method.exp_month",
+		},
+		"zero month": {
+			paymentMethod: `{"type":"card","card_number_type":"fpan","number":"4242424242424242","display_card_funding_type":"credit","display_last4":"4242","metadata":{},"exp_month":"00","exp_year":"2030"}`,
+			wantParam:     "$.payment_method.exp_month",
+		},
+		"month without year": {
+			paymentMethod: `{"type":"card","card_number_type":"fpan","number":"4242424242424242","display_card_funding_type":"credit","display_last4":"4242","metadata":{},"exp_month":"01"}`,
+			wantParam:     "$.payment_method.exp_year",
+		},
+		"year without month": {
+			paymentMethod: `{"type":"card","card_number_type":"fpan","number":"4242424242424242","display_card_funding_type":"credit","display_last4":"4242","metadata":{},"exp_year":"2030"}`,
+			wantParam:     "$.payment_method.exp_month",
+		},
+		"unknown card field": {
+			paymentMethod: `{"type":"card","brand":"visa"}`,
+			wantDecodeErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			encoded, err := json.Marshal(sampleDelegatePaymentRequest())
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(encoded, &fields); err != nil {
+				t.Fatalf("unmarshal fields: %v", err)
+			}
+			fields["payment_method"] = json.RawMessage(tt.paymentMethod)
+			body, err := json.Marshal(fields)
+			if err != nil {
+				t.Fatalf("marshal fields: %v", err)
+			}
+
+			var req PaymentRequest
+			err = json.Unmarshal(body, &req)
+			if tt.wantDecodeErr {
+				if err == nil {
+					t.Fatalf("expected decode error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshal request: %v", err)
+			}
+			verr := req.Validate()
+			var payload *Error
+			if !errors.As(verr, &payload) || payload.Param == nil || *payload.Param != tt.wantParam {
+				t.Fatalf("expected error on %s got %v", tt.wantParam, verr)
+			}
+		})
+	}
+}
+
+func TestPaymentRequestValidateCardCryptogram(t *testing.T) {
+	t.Parallel()
+
+	cryptogram, eci := "AgAAAAAAAIR8CQrXcIhbQAAAAAA=", "05"
+	tests := map[string]struct {
+		numberType  CardNumberType
+		cryptogram  *string
+		eci         *string
+		wantParam   string
+		wantMessage string
+	}{
+		"network token with cryptogram and eci": {
+			numberType: CardCardNumberTypeNetworkToken,
+			cryptogram: &cryptogram,
+			eci:        &eci,
+		},
+		"network token without eci": {
+			numberType: CardCardNumberTypeNetworkToken,
+			cryptogram: &cryptogram,
+		},
+		"network token without cryptogram": {
+			numberType:  CardCardNumberTypeNetworkToken,
+			eci:         &eci,
+			wantParam:   "$.payment_method.cryptogram",
+			wantMessage: "payment_method.cryptogram is required when card_number_type is network_token",
+		},
+		"network token with empty cryptogram": {
+			numberType: CardCardNumberTypeNetworkToken,
+			cryptogram: new(string),
+			wantParam:  "$.payment_method.cryptogram",
+		},
+		"fpan without cryptogram": {
+			numberType: CardCardNumberTypeFPAN,
+		},
+		"fpan with cryptogram": {
+			numberType:  CardCardNumberTypeFPAN,
+			cryptogram:  &cryptogram,
+			wantParam:   "$.payment_method.cryptogram",
+			wantMessage: "payment_method.cryptogram must be omitted when card_number_type is fpan",
+		},
+		"fpan with eci": {
+			numberType: CardCardNumberTypeFPAN,
+			eci:        &eci,
+			wantParam:  "$.payment_method.eci_value",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := sampleDelegatePaymentRequest()
+			updateCard(&req, func(card *PaymentMethodCard) {
+				card.CardNumberType = tt.numberType
+				card.Cryptogram = tt.cryptogram
+				card.ECIValue = tt.eci
+			})
+
+			err := req.Validate()
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				return
+			}
+			var payload *Error
+			if !errors.As(err, &payload) || payload.Param == nil || *payload.Param != tt.wantParam {
+				t.Fatalf("expected error on %s got %v", tt.wantParam, err)
+			}
+			if tt.wantMessage != "" && payload.Message != tt.wantMessage {
+				t.Fatalf("expected message %q got %q", tt.wantMessage, payload.Message)
+			}
+		})
+	}
+}
+
+func TestPaymentMethodAsCardRejectsOtherTypes(t *testing.T) {
+	t.Parallel()
+
+	var method PaymentMethod
+	if err := json.Unmarshal([]byte(`{"type":"bank_debit"}`), &method); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := method.AsCard(); err == nil || !strings.Contains(err.Error(), "bank_debit") {
+		t.Fatalf("expected AsCard error naming bank_debit got %v", err)
+	}
+}
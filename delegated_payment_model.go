@@ -9,8 +9,8 @@ import (
 // PaymentRequest mirrors the ACP DelegatePaymentRequest payload described in the spec:
 // https://developers.openai.com/commerce/specs/payment.
 type PaymentRequest struct {
-	// Type of credential. The only accepted value is "CARD".
-	PaymentMethod PaymentMethodCard `json:"payment_method" validate:"required"`
+	// Payment method credential. The only accepted type is card.
+	PaymentMethod PaymentMethod `json:"payment_method" validate:"required"`
 	// Use cases that the stored credential can be applied to.
 	Allowance Allowance `json:"allowance" validate:"required"`
 	// Address associated with the payment method.
@@ -22,6 +22,8 @@ type PaymentRequest struct {
 }
 
 // VaultToken is emitted by PSPs after tokenizing the delegated payment payload.
+// Handlers encode responses with encoding/json, which writes map keys such as
+// those of Metadata in sorted order, so equal tokens encode to identical bytes.
 type VaultToken struct {
 	// Unique vault token identifier vt_….
 	ID string `json:"id" validate:"required"`
@@ -29,6 +31,8 @@ type VaultToken struct {
 	Created time.Time `json:"created" validate:"required"`
 	// Arbitrary key/value pairs for correlation (e.g., source, merchant_id, idempotency_key).
 	Metadata map[string]string `json:"metadata" validate:"omitempty"`
+	// Validation warnings for rules relaxed by [ValidationModeLenient].
+	Messages []MessageInfo `json:"messages,omitempty"`
 }
 
 // PaymentMethodCard captures the delegated card credential.
@@ -41,9 +45,10 @@ type PaymentMethodCard struct {
 	CardNumberType CardNumberType `json:"card_number_type" validate:"required,oneof=fpan network_token"`
 	// Card number.
 	Number secret.Secret[string] `json:"number" validate:"required"`
-	// Expiry month.
-	ExpMonth *string `json:"exp_month,omitempty" validate:"omitempty,len=2,numeric"`
-	// Expiry year.
+	// Expiry month, 01 to 12. Set together with ExpYear, or omitted along
+	// with it for network tokens without expiry.
+	ExpMonth *string `json:"exp_month,omitempty" validate:"omitempty,len=2,numeric,month"`
+	// Expiry year, four digits.
 	ExpYear *string `json:"exp_year,omitempty" validate:"omitempty,len=4,numeric"`
 	// Cardholder name.
 	Name *string `json:"name,omitempty"`
@@ -61,9 +66,11 @@ type PaymentMethodCard struct {
 	DisplayWalletType *string `json:"display_wallet_type,omitempty"`
 	// Institution Identification Number (aka BIN). The first 6 digits on a card identifying the issuer.
 	IIN *string `json:"iin,omitempty" validate:"omitempty,max=6"`
-	// Cryptogram provided with network tokens.
+	// Cryptogram provided with network tokens. Required for network tokens
+	// and rejected for FPANs.
 	Cryptogram *string `json:"cryptogram,omitempty"`
-	// Electronic Commerce Indicator / Security Level Indicator provided with network tokens.
+	// Electronic Commerce Indicator / Security Level Indicator provided with
+	// network tokens. Optional for network tokens and rejected for FPANs.
 	ECIValue *string `json:"eci_value,omitempty"`
 	// Checks already performed on the card.
 	ChecksPerformed []CardChecksPerformed `json:"checks_performed,omitempty" validate:"omitempty,dive,required"`
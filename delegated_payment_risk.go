@@ -0,0 +1,42 @@
+package acp
+
+// RiskDecision aggregates the [RiskSignal] entries of a [PaymentRequest] so
+// providers decline or escalate consistently.
+type RiskDecision struct {
+	// Blocked is true when any signal has action "blocked"; treat it as a hard decline.
+	Blocked bool
+	// ManualReview is true when any signal has action "manual_review".
+	ManualReview bool
+	// HighestScore is the maximum score across all signals.
+	HighestScore int
+}
+
+// Action returns the most severe action across the evaluated signals:
+// blocked, then manual_review, then authorized.
+func (d RiskDecision) Action() RiskSignalAction {
+	switch {
+	case d.Blocked:
+		return RiskSignalActionBlocked
+	case d.ManualReview:
+		return RiskSignalActionManualReview
+	default:
+		return RiskSignalActionAuthorized
+	}
+}
+
+// EvaluateRiskSignals folds the supplied signals into a single [RiskDecision].
+func EvaluateRiskSignals(signals []RiskSignal) RiskDecision {
+	var decision RiskDecision
+	for i, signal := range signals {
+		switch signal.Action {
+		case RiskSignalActionBlocked:
+			decision.Blocked = true
+		case RiskSignalActionManualReview:
+			decision.ManualReview = true
+		}
+		if i == 0 || signal.Score > decision.HighestScore {
+			decision.HighestScore = signal.Score
+		}
+	}
+	return decision
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -132,6 +135,59 @@ func TestDelegatedPaymentHandlerErrors(t *testing.T) {
 		}
 	})
 
+	t.Run("service unavailable response sets retry-after header", func(t *testing.T) {
+		t.Parallel()
+
+		handler := NewDelegatedPaymentHandler(&delegatedStubService{
+			delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+				outage := NewServiceUnavailableError("vault temporarily unavailable", WithRetryAfter(1500*time.Millisecond))
+				return nil, fmt.Errorf("tokenize: %w", outage)
+			},
+		})
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, newDelegatePaymentHTTPRequest(t))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503 got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "2" {
+			t.Fatalf("expected Retry-After header 2 got %q", got)
+		}
+		if got := getErrorCode(rec.Body.Bytes()); got != string(ServiceUnavailable) {
+			t.Fatalf("expected code %s got %s", ServiceUnavailable, got)
+		}
+	})
+
+	t.Run("form-encoded body", func(t *testing.T) {
+		t.Parallel()
+
+		handler := NewDelegatedPaymentHandler(successService())
+		req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", strings.NewReader("payment_method=card"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("expected 415 got %d", rec.Code)
+		}
+		if got := getErrorCode(rec.Body.Bytes()); got != string(UnsupportedMediaType) {
+			t.Fatalf("expected code %s got %s", UnsupportedMediaType, got)
+		}
+	})
+
+	t.Run("JSON body with charset", func(t *testing.T) {
+		t.Parallel()
+
+		handler := NewDelegatedPaymentHandler(successService())
+		req := newDelegatePaymentHTTPRequest(t)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201 got %d body=%s", rec.Code, rec.Body.String())
+		}
+	})
+
 	t.Run("method not allowed", func(t *testing.T) {
 		t.Parallel()
 
@@ -145,6 +201,247 @@ func TestDelegatedPaymentHandlerErrors(t *testing.T) {
 	})
 }
 
+func TestDelegatedPaymentHandlerUnprocessableEntityErrors(t *testing.T) {
+	t.Parallel()
+
+	invalid := sampleDelegatePaymentRequest()
+	invalid.Metadata = nil
+	invalidBody, _ := json.Marshal(invalid)
+
+	tests := map[string]struct {
+		body       []byte
+		opts       []Option
+		wantStatus int
+	}{
+		"validation failure defaults to 400": {
+			body:       invalidBody,
+			wantStatus: http.StatusBadRequest,
+		},
+		"validation failure opts into 422": {
+			body:       invalidBody,
+			opts:       []Option{WithUnprocessableEntityErrors()},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		"malformed JSON stays 400": {
+			body:       []byte("{"),
+			opts:       []Option{WithUnprocessableEntityErrors()},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewDelegatedPaymentHandler(&delegatedStubService{}, tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if got := getErrorCode(rec.Body.Bytes()); got != string(InvalidRequest) {
+				t.Fatalf("expected code %s got %s", InvalidRequest, got)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerAcceptedCurrencies(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		currency   string
+		wantStatus int
+	}{
+		"accepted currency": {
+			currency:   "eur",
+			wantStatus: http.StatusCreated,
+		},
+		"unaccepted currency": {
+			currency:   "gbp",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewDelegatedPaymentHandler(successService(), WithAcceptedCurrencies("USD", "EUR"))
+			payload := sampleDelegatePaymentRequest()
+			payload.Allowance.Currency = tt.currency
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if got := getErrorCode(rec.Body.Bytes()); got != string(InvalidRequest) {
+					t.Fatalf("expected code %s got %s", InvalidRequest, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerDryRun(t *testing.T) {
+	t.Parallel()
+
+	failDelegate := func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+		t.Errorf("DelegatePayment must not be called for dry-run requests")
+		return nil, nil
+	}
+
+	tests := map[string]struct {
+		service      DelegatedPaymentProvider
+		target       string
+		header       string
+		mutate       func(*PaymentRequest)
+		wantStatus   int
+		wantProvider bool
+	}{
+		"header without provider support": {
+			service:    &delegatedStubService{delegate: failDelegate},
+			target:     "/agentic_commerce/delegate_payment",
+			header:     "true",
+			wantStatus: http.StatusOK,
+		},
+		"query with provider support": {
+			service: &dryRunStubService{
+				delegatedStubService: delegatedStubService{delegate: failDelegate},
+				dryRun:               func(ctx context.Context, req PaymentRequest) error { return nil },
+			},
+			target:       "/agentic_commerce/delegate_payment?dry_run=1",
+			wantStatus:   http.StatusOK,
+			wantProvider: true,
+		},
+		"provider rejects payload": {
+			service: &dryRunStubService{
+				delegatedStubService: delegatedStubService{delegate: failDelegate},
+				dryRun: func(ctx context.Context, req PaymentRequest) error {
+					return NewHTTPError(http.StatusUnprocessableEntity, InvalidRequest, InvalidCard, "card rejected")
+				},
+			},
+			target:     "/agentic_commerce/delegate_payment",
+			header:     "true",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		"validation still runs": {
+			service:    &delegatedStubService{delegate: failDelegate},
+			target:     "/agentic_commerce/delegate_payment",
+			header:     "true",
+			mutate:     func(req *PaymentRequest) { req.RiskSignals = nil },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			if tt.mutate != nil {
+				tt.mutate(&payload)
+			}
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set("Dry-Run", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(tt.service).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var result DryRunResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if !result.DryRun || !result.Valid {
+				t.Fatalf("unexpected dry-run summary %+v", result)
+			}
+			if result.ProviderChecked != tt.wantProvider {
+				t.Fatalf("expected provider_checked %t got %t", tt.wantProvider, result.ProviderChecked)
+			}
+		})
+	}
+}
+
+func TestEvaluateRiskSignals(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		signals    []RiskSignal
+		wantAction RiskSignalAction
+		wantReview bool
+		wantScore  int
+	}{
+		"no signals": {
+			wantAction: RiskSignalActionAuthorized,
+		},
+		"authorized only": {
+			signals: []RiskSignal{
+				{Type: RiskSignalTypeCardTesting, Action: RiskSignalActionAuthorized, Score: 5},
+			},
+			wantAction: RiskSignalActionAuthorized,
+			wantScore:  5,
+		},
+		"manual review": {
+			signals: []RiskSignal{
+				{Type: RiskSignalTypeCardTesting, Action: RiskSignalActionAuthorized, Score: 40},
+				{Type: RiskSignalTypeCardTesting, Action: RiskSignalActionManualReview, Score: 20},
+			},
+			wantAction: RiskSignalActionManualReview,
+			wantReview: true,
+			wantScore:  40,
+		},
+		"blocked wins": {
+			signals: []RiskSignal{
+				{Type: RiskSignalTypeCardTesting, Action: RiskSignalActionManualReview, Score: 70},
+				{Type: RiskSignalTypeCardTesting, Action: RiskSignalActionBlocked, Score: 90},
+			},
+			wantAction: RiskSignalActionBlocked,
+			wantReview: true,
+			wantScore:  90,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := EvaluateRiskSignals(tt.signals)
+			if got.Action() != tt.wantAction {
+				t.Fatalf("expected action %s got %s", tt.wantAction, got.Action())
+			}
+			if got.Blocked != (tt.wantAction == RiskSignalActionBlocked) {
+				t.Fatalf("unexpected blocked %t", got.Blocked)
+			}
+			if got.ManualReview != tt.wantReview {
+				t.Fatalf("expected manual review %t got %t", tt.wantReview, got.ManualReview)
+			}
+			if got.HighestScore != tt.wantScore {
+				t.Fatalf("expected highest score %d got %d", tt.wantScore, got.HighestScore)
+			}
+		})
+	}
+}
+
 type delegatedStubService struct {
 	delegate func(context.Context, PaymentRequest) (*VaultToken, error)
 }
@@ -156,24 +453,37 @@ func (s *delegatedStubService) DelegatePayment(ctx context.Context, req PaymentR
 	return nil, NewHTTPError(http.StatusNotImplemented, InvalidRequest, ErrorCode("not_implemented"), "delegate payment not implemented")
 }
 
+type dryRunStubService struct {
+	delegatedStubService
+	dryRun func(context.Context, PaymentRequest) error
+}
+
+func (s *dryRunStubService) DryRunPayment(ctx context.Context, req PaymentRequest) error {
+	return s.dryRun(ctx, req)
+}
+
 func sampleDelegatePaymentRequest() PaymentRequest {
-	expMonth := "11"
-	expYear := "2026"
+	expiry := time.Now().AddDate(1, 0, 0)
+	expMonth := expiry.Format("01")
+	expYear := expiry.Format("2006")
 	displayLast4 := "4242"
 	checks := []CardChecksPerformed{CardChecksPerformedAVS}
 
+	var method PaymentMethod
+	_ = method.FromCard(PaymentMethodCard{
+		Type:                   PaymentMethodCardTypeCard,
+		CardNumberType:         CardCardNumberTypeFPAN,
+		Number:                 secret.New("4242424242424242"),
+		ExpMonth:               &expMonth,
+		ExpYear:                &expYear,
+		DisplayLast4:           &displayLast4,
+		DisplayCardFundingType: CardFundingTypeCredit,
+		Metadata:               map[string]string{"issuer": "acme"},
+		ChecksPerformed:        checks,
+	})
+
 	return PaymentRequest{
-		PaymentMethod: PaymentMethodCard{
-			Type:                   PaymentMethodCardTypeCard,
-			CardNumberType:         CardCardNumberTypeFPAN,
-			Number:                 secret.New("4242424242424242"),
-			ExpMonth:               &expMonth,
-			ExpYear:                &expYear,
-			DisplayLast4:           &displayLast4,
-			DisplayCardFundingType: CardFundingTypeCredit,
-			Metadata:               map[string]string{"issuer": "acme"},
-			ChecksPerformed:        checks,
-		},
+		PaymentMethod: method,
 		Allowance: Allowance{
 			Reason:            AllowanceReasonOneTime,
 			MaxAmount:         2000,
@@ -194,3 +504,393 @@ func sampleDelegatePaymentRequest() PaymentRequest {
 		},
 	}
 }
+
+func TestWithValidationErrorDetail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts       []Option
+		wantDetail []ValidationErrorDetail
+	}{
+		"off by default": {},
+		"disabled": {
+			opts: []Option{WithValidationErrorDetail(false)},
+		},
+		"enabled": {
+			opts: []Option{WithValidationErrorDetail(true)},
+			wantDetail: []ValidationErrorDetail{
+				{Field: "payment_method.display_last4", Tag: "len", Param: "4"},
+				{Field: "allowance.merchant_id", Tag: "required"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			last4 := "42"
+			updateCard(&payload, func(card *PaymentMethodCard) { card.DisplayLast4 = &last4 })
+			payload.Allowance.MerchantID = ""
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(successService(), tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400 got %d", rec.Code)
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Param == nil || *got.Param != "$.payment_method.display_last4" {
+				t.Fatalf("expected top-level param for the first failure got %v", got.Param)
+			}
+			if !reflect.DeepEqual(got.Errors, tt.wantDetail) {
+				t.Fatalf("expected errors %+v got %+v", tt.wantDetail, got.Errors)
+			}
+		})
+	}
+}
+
+func TestWithValidationMessages(t *testing.T) {
+	t.Parallel()
+
+	last4Param, merchantParam := "$.payment_method.display_last4", "$.allowance.merchant_id"
+	tests := map[string]struct {
+		opts         []Option
+		wantMessages []MessageError
+	}{
+		"off by default": {},
+		"disabled": {
+			opts: []Option{WithValidationMessages(false)},
+		},
+		"enabled": {
+			opts: []Option{WithValidationMessages(true)},
+			wantMessages: []MessageError{
+				{Type: "error", Code: Invalid, Content: "payment_method.display_last4 must be exactly 4 characters", ContentType: MessageErrorContentTypePlain, Param: &last4Param},
+				{Type: "error", Code: Missing, Content: "allowance.merchant_id is required", ContentType: MessageErrorContentTypePlain, Param: &merchantParam},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			last4 := "42"
+			updateCard(&payload, func(card *PaymentMethodCard) { card.DisplayLast4 = &last4 })
+			payload.Allowance.MerchantID = ""
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(successService(), tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400 got %d", rec.Code)
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Code != ErrorCode(InvalidRequest) || got.Param == nil || *got.Param != last4Param {
+				t.Fatalf("expected top-level error for the first failure got %+v", got)
+			}
+			if !reflect.DeepEqual(got.Messages, tt.wantMessages) {
+				t.Fatalf("expected messages %+v got %+v", tt.wantMessages, got.Messages)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerRejectsExpiredAllowance(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		expiresAt  time.Time
+		wantStatus int
+	}{
+		"future":             {expiresAt: now.Add(time.Hour), wantStatus: http.StatusCreated},
+		"within clock skew":  {expiresAt: now.Add(-time.Minute), wantStatus: http.StatusCreated},
+		"beyond clock skew":  {expiresAt: now.Add(-6 * time.Minute), wantStatus: http.StatusBadRequest},
+		"long expired":       {expiresAt: now.Add(-24 * time.Hour), wantStatus: http.StatusBadRequest},
+		"exactly skew limit": {expiresAt: now.Add(-5 * time.Minute), wantStatus: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			payload.Allowance.ExpiresAt = tt.expiresAt
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(successService(), WithClock(func() time.Time { return now })).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusCreated {
+				return
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Type != InvalidRequest || got.Param == nil || *got.Param != "$.allowance.expires_at" {
+				t.Fatalf("expected invalid_request for $.allowance.expires_at got %+v", got)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err        error
+		opts       []Option
+		wantStatus int
+	}{
+		"validation error with invalid card": {
+			err:        &ValidationError{Field: "payment_method.number", Code: InvalidCard, Message: "card declined by issuer"},
+			wantStatus: http.StatusPaymentRequired,
+		},
+		"error literal with invalid signature": {
+			err:        &Error{Type: InvalidRequest, Code: InvalidSignature, Message: "bad signature"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"error literal without mapping": {
+			err:        &Error{Type: ServiceUnavailable, Code: "maintenance", Message: "down"},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		"builder default": {
+			err:        NewInvalidRequestError("bad card", func(e *Error) { e.Code = InvalidCard }),
+			wantStatus: http.StatusPaymentRequired,
+		},
+		"explicit status wins": {
+			err:        NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidCard, "bad card"),
+			wantStatus: http.StatusBadRequest,
+		},
+		"configured status": {
+			err:        &ValidationError{Code: InvalidCard, Message: "card declined by issuer"},
+			opts:       []Option{WithErrorStatus(InvalidCard, http.StatusUnprocessableEntity)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		"configured status keeps other defaults": {
+			err:        &Error{Type: InvalidRequest, Code: InvalidSignature, Message: "bad signature"},
+			opts:       []Option{WithErrorStatus(InvalidCard, http.StatusUnprocessableEntity)},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			body, err := json.Marshal(sampleDelegatePaymentRequest())
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			service := &delegatedStubService{delegate: func(context.Context, PaymentRequest) (*VaultToken, error) {
+				return nil, tt.err
+			}}
+			NewDelegatedPaymentHandler(service, tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerRejectsExpiredCard(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		// expiry is MM/YYYY, or empty for network tokens without expiry.
+		expiry    string
+		wantParam string
+	}{
+		"current month":  {expiry: "10/2025"},
+		"next year":      {expiry: "01/2026"},
+		"without expiry": {},
+		"previous month": {expiry: "09/2025", wantParam: "$.payment_method.exp_month"},
+		"previous year":  {expiry: "12/2024", wantParam: "$.payment_method.exp_year"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			payload.Allowance.ExpiresAt = now.Add(time.Hour)
+			card, err := payload.PaymentMethod.AsCard()
+			if err != nil {
+				t.Fatalf("card: %v", err)
+			}
+			card.ExpMonth, card.ExpYear = nil, nil
+			if month, year, ok := strings.Cut(tt.expiry, "/"); ok {
+				card.ExpMonth, card.ExpYear = &month, &year
+			}
+			_ = payload.PaymentMethod.FromCard(card)
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(successService(), WithClock(func() time.Time { return now })).ServeHTTP(rec, req)
+
+			if tt.wantParam == "" {
+				if rec.Code != http.StatusCreated {
+					t.Fatalf("expected status 201 got %d: %s", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if rec.Code != http.StatusPaymentRequired || got.Code != InvalidCard || got.Param == nil || *got.Param != tt.wantParam {
+				t.Fatalf("expected 402 invalid_card for %s got %d %+v", tt.wantParam, rec.Code, got)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentResponseIsByteStable(t *testing.T) {
+	t.Parallel()
+
+	metadata := map[string]string{"source": "agent", "merchant_id": "acme", "idempotency_key": "idem_1", "campaign": "q4"}
+	handler := NewDelegatedPaymentHandler(&delegatedStubService{delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+		return &VaultToken{ID: "vt_123", Created: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), Metadata: metadata}, nil
+	}})
+
+	var first string
+	for i := range 20 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newDelegatePaymentHTTPRequest(t))
+		if i == 0 {
+			first = rec.Body.String()
+			continue
+		}
+		if rec.Body.String() != first {
+			t.Fatalf("expected identical bodies got %s and %s", first, rec.Body.String())
+		}
+	}
+	want := `"metadata":{"campaign":"q4","idempotency_key":"idem_1","merchant_id":"acme","source":"agent"}`
+	if !strings.Contains(first, want) {
+		t.Fatalf("expected sorted metadata %s in %s", want, first)
+	}
+}
+
+// updateCard applies fn to the card of req.
+func updateCard(req *PaymentRequest, fn func(*PaymentMethodCard)) {
+	card, _ := req.PaymentMethod.AsCard()
+	fn(&card)
+	_ = req.PaymentMethod.FromCard(card)
+}
+
+func TestConditionalRulesMessages(t *testing.T) {
+	t.Parallel()
+
+	cryptogram, month, year := "AgAAAAAAAIR8CQrXcIhbQAAAAAA=", "01", "2099"
+	tests := map[string]struct {
+		card        func(*PaymentMethodCard)
+		wantMessage string
+	}{
+		"required_with": {
+			card: func(card *PaymentMethodCard) {
+				card.ExpMonth, card.ExpYear = nil, &year
+			},
+			wantMessage: "payment_method.exp_month is required with exp_year",
+		},
+		"required_if": {
+			card: func(card *PaymentMethodCard) {
+				card.CardNumberType = CardCardNumberTypeNetworkToken
+			},
+			wantMessage: "payment_method.cryptogram is required when card_number_type is network_token",
+		},
+		"required_with year": {
+			card: func(card *PaymentMethodCard) {
+				card.ExpMonth, card.ExpYear = &month, nil
+			},
+			wantMessage: "payment_method.exp_year is required with exp_month",
+		},
+		"required_if empty": {
+			card: func(card *PaymentMethodCard) {
+				card.CardNumberType = CardCardNumberTypeNetworkToken
+				card.Cryptogram = new(string)
+			},
+			wantMessage: "payment_method.cryptogram is required when card_number_type is network_token",
+		},
+		"excluded_if": {
+			card: func(card *PaymentMethodCard) {
+				card.Cryptogram = &cryptogram
+			},
+			wantMessage: "payment_method.cryptogram must be omitted when card_number_type is fpan",
+		},
+		"excluded_if eci": {
+			card: func(card *PaymentMethodCard) {
+				card.ECIValue = new(string)
+			},
+			wantMessage: "payment_method.eci_value must be omitted when card_number_type is fpan",
+		},
+		"satisfied": {
+			card: func(card *PaymentMethodCard) {
+				card.CardNumberType = CardCardNumberTypeNetworkToken
+				card.Cryptogram = &cryptogram
+				card.ExpMonth, card.ExpYear = &month, &year
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := sampleDelegatePaymentRequest()
+			updateCard(&req, tt.card)
+
+			err := req.Validate()
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				return
+			}
+			var payload *Error
+			if !errors.As(err, &payload) || payload.Message != tt.wantMessage {
+				t.Fatalf("expected message %q got %v", tt.wantMessage, err)
+			}
+		})
+	}
+}
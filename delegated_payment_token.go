@@ -0,0 +1,88 @@
+package acp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"maps"
+	"time"
+)
+
+// TokenIDGenerator mints the ids of the [VaultToken] values returned by
+// [DelegatedPaymentProvider.DelegatePayment], so providers share one id shape
+// and can swap the scheme (for example to ULIDs) in a single place.
+type TokenIDGenerator interface {
+	NewTokenID() (string, error)
+}
+
+// VaultTokenIDPrefix starts every id minted by [RandomTokenIDGenerator].
+const VaultTokenIDPrefix = "vt_"
+
+// tokenIDLength is the number of base62 characters after the prefix, about
+// 131 bits of randomness.
+const tokenIDLength = 22
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RandomTokenIDGenerator is the default [TokenIDGenerator]. It returns
+// [VaultTokenIDPrefix] followed by 22 uniformly random base62 characters.
+type RandomTokenIDGenerator struct {
+	// Rand is the source of randomness; defaults to crypto/rand.Reader.
+	Rand io.Reader
+}
+
+// NewTokenID returns a new random vault token id.
+func (g RandomTokenIDGenerator) NewTokenID() (string, error) {
+	source := g.Rand
+	if source == nil {
+		source = rand.Reader
+	}
+	id := make([]byte, len(VaultTokenIDPrefix), len(VaultTokenIDPrefix)+tokenIDLength)
+	copy(id, VaultTokenIDPrefix)
+	buf := make([]byte, tokenIDLength*2)
+	for len(id) < cap(id) {
+		if _, err := io.ReadFull(source, buf); err != nil {
+			return "", fmt.Errorf("acp: generate token id: %w", err)
+		}
+		for _, b := range buf {
+			// Rejecting bytes above the largest multiple of 62 keeps the
+			// characters uniformly distributed.
+			if b >= 62*4 || len(id) == cap(id) {
+				continue
+			}
+			id = append(id, base62Alphabet[b%62])
+		}
+	}
+	return string(id), nil
+}
+
+// Reserved [VaultToken] metadata keys set by [NewVaultToken].
+const (
+	VaultTokenMetadataMerchantID        = "merchant_id"
+	VaultTokenMetadataCheckoutSessionID = "checkout_session_id"
+	VaultTokenMetadataIdempotencyKey    = "idempotency_key"
+)
+
+// NewVaultToken builds the [VaultToken] returned by
+// [DelegatedPaymentProvider.DelegatePayment] so every provider reports the
+// same correlation metadata. Metadata holds the request metadata, then extra,
+// then the reserved keys, which take precedence: merchant_id and
+// checkout_session_id from the allowance of req, and idempotency_key from the
+// Idempotency-Key header in the [RequestContext] of ctx, when present.
+// Created is stored in UTC.
+func NewVaultToken(ctx context.Context, id string, created time.Time, req PaymentRequest, extra map[string]string) *VaultToken {
+	metadata := make(map[string]string, len(req.Metadata)+len(extra)+3)
+	maps.Copy(metadata, req.Metadata)
+	maps.Copy(metadata, extra)
+	metadata[VaultTokenMetadataMerchantID] = req.Allowance.MerchantID
+	metadata[VaultTokenMetadataCheckoutSessionID] = req.Allowance.CheckoutSessionID
+	if requestCtx := RequestContextFromContext(ctx); requestCtx != nil && requestCtx.IdempotencyKey != "" {
+		metadata[VaultTokenMetadataIdempotencyKey] = requestCtx.IdempotencyKey
+	}
+	return &VaultToken{
+		ID:       id,
+		Created:  created.UTC(),
+		Metadata: metadata,
+	}
+}
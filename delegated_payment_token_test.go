@@ -0,0 +1,133 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRandomTokenIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	var generator TokenIDGenerator = RandomTokenIDGenerator{}
+	seen := make(map[string]struct{})
+	for range 100 {
+		id, err := generator.NewTokenID()
+		if err != nil {
+			t.Fatalf("NewTokenID() error = %v", err)
+		}
+		suffix, ok := strings.CutPrefix(id, VaultTokenIDPrefix)
+		if !ok || len(suffix) != tokenIDLength {
+			t.Fatalf("unexpected id shape %q", id)
+		}
+		if strings.Trim(suffix, base62Alphabet) != "" {
+			t.Fatalf("expected base62 characters got %q", id)
+		}
+		if _, dup := seen[id]; dup {
+			t.Fatalf("duplicate id %q", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestRandomTokenIDGeneratorSource(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		source  []byte
+		want    string
+		wantErr bool
+	}{
+		"skips biased bytes": {
+			// 248 and above are rejected; 61 and 124 map to the last and
+			// first characters of the alphabet.
+			source: append([]byte{255, 248, 61, 124}, bytes.Repeat([]byte{0}, 42)...),
+			want:   "vt_z" + strings.Repeat("0", 21),
+		},
+		"short source": {
+			source:  []byte{1, 2, 3},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			id, err := RandomTokenIDGenerator{Rand: bytes.NewReader(tt.source)}.NewTokenID()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error got id %q", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTokenID() error = %v", err)
+			}
+			if id != tt.want {
+				t.Fatalf("expected %q got %q", tt.want, id)
+			}
+		})
+	}
+}
+
+func TestNewVaultToken(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, 10, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	tests := map[string]struct {
+		requestCtx   *RequestContext
+		metadata     map[string]string
+		extra        map[string]string
+		wantMetadata map[string]string
+	}{
+		"reserved keys only": {
+			wantMetadata: map[string]string{"merchant_id": "acme", "checkout_session_id": "cs_123"},
+		},
+		"idempotency key from context": {
+			requestCtx:   &RequestContext{IdempotencyKey: "idem_1"},
+			wantMetadata: map[string]string{"merchant_id": "acme", "checkout_session_id": "cs_123", "idempotency_key": "idem_1"},
+		},
+		"request metadata and extra": {
+			metadata:     map[string]string{"campaign": "q4", "source": "agent"},
+			extra:        map[string]string{"source": "psp", "region": "eu"},
+			wantMetadata: map[string]string{"merchant_id": "acme", "checkout_session_id": "cs_123", "campaign": "q4", "source": "psp", "region": "eu"},
+		},
+		"reserved keys win": {
+			requestCtx:   &RequestContext{IdempotencyKey: "idem_1"},
+			metadata:     map[string]string{"merchant_id": "spoofed"},
+			extra:        map[string]string{"idempotency_key": "other"},
+			wantMetadata: map[string]string{"merchant_id": "acme", "checkout_session_id": "cs_123", "idempotency_key": "idem_1"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tt.requestCtx != nil {
+				ctx = contextWithRequestContext(ctx, tt.requestCtx)
+			}
+			req := PaymentRequest{
+				Allowance: Allowance{MerchantID: "acme", CheckoutSessionID: "cs_123"},
+				Metadata:  tt.metadata,
+			}
+
+			token := NewVaultToken(ctx, "vt_123", created, req, tt.extra)
+
+			if token.ID != "vt_123" {
+				t.Fatalf("expected id vt_123 got %q", token.ID)
+			}
+			if !token.Created.Equal(created) || token.Created.Location() != time.UTC {
+				t.Fatalf("expected created %v in UTC got %v", created, token.Created)
+			}
+			if !maps.Equal(token.Metadata, tt.wantMetadata) {
+				t.Fatalf("expected metadata %v got %v", tt.wantMetadata, token.Metadata)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -34,16 +36,36 @@ func newValidator() *validator.Validate {
 		return name
 	})
 
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(PaymentMethod).validationValue()
+	}, PaymentMethod{})
+
 	if err := v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
 		value, ok := fl.Field().Interface().(string)
 		if !ok {
 			return false
 		}
-		return currencyPattern.MatchString(value)
+		return currencyPattern.MatchString(value) && isISO4217Currency(value)
+	}); err != nil {
+		panic(err)
+	}
+
+	if err := v.RegisterValidation("country", func(fl validator.FieldLevel) bool {
+		value, ok := fl.Field().Interface().(string)
+		return ok && isISO3166Country(value)
+	}); err != nil {
+		panic(err)
+	}
+
+	if err := v.RegisterValidation("month", func(fl validator.FieldLevel) bool {
+		month, err := strconv.Atoi(fl.Field().String())
+		return err == nil && month >= 1 && month <= 12
 	}); err != nil {
 		panic(err)
 	}
 
+	v.RegisterStructValidation(validatePaymentMethodCard, PaymentMethodCard{})
+
 	if err := v.RegisterValidation("map_present", func(fl validator.FieldLevel) bool {
 		if fl.Field().Kind() != reflect.Map {
 			return false
@@ -56,6 +78,68 @@ func newValidator() *validator.Validate {
 	return v
 }
 
+// conditionalRules lists the cross-field rules of the request models, named
+// by JSON field, for [ValidationRules]. Those of [PaymentMethodCard] are
+// enforced by validatePaymentMethodCard.
+var conditionalRules = map[reflect.Type][]FieldRule{
+	reflect.TypeFor[PaymentMethodCard](): {
+		{Path: "exp_year", Tag: "required_with", Param: "exp_month"},
+		{Path: "exp_month", Tag: "required_with", Param: "exp_year"},
+		{Path: "cryptogram", Tag: "required_if", Param: "card_number_type network_token"},
+		{Path: "cryptogram", Tag: "excluded_if", Param: "card_number_type fpan"},
+		{Path: "eci_value", Tag: "excluded_if", Param: "card_number_type fpan"},
+	},
+}
+
+// validatePaymentMethodCard enforces the conditionalRules of a card: expiry
+// month and year go together, network tokens carry a cryptogram and FPANs
+// carry neither a cryptogram nor an ECI value.
+func validatePaymentMethodCard(sl validator.StructLevel) {
+	card := sl.Current().Interface().(PaymentMethodCard)
+	if card.ExpMonth != nil && card.ExpYear == nil {
+		sl.ReportError(card.ExpYear, "exp_year", "ExpYear", "required_with", "exp_month")
+	}
+	if card.ExpYear != nil && card.ExpMonth == nil {
+		sl.ReportError(card.ExpMonth, "exp_month", "ExpMonth", "required_with", "exp_year")
+	}
+	switch card.CardNumberType {
+	case CardCardNumberTypeNetworkToken:
+		if card.Cryptogram == nil || *card.Cryptogram == "" {
+			sl.ReportError(card.Cryptogram, "cryptogram", "Cryptogram", "required_if", "card_number_type network_token")
+		}
+	case CardCardNumberTypeFPAN:
+		if card.Cryptogram != nil {
+			sl.ReportError(card.Cryptogram, "cryptogram", "Cryptogram", "excluded_if", "card_number_type fpan")
+		}
+		if card.ECIValue != nil {
+			sl.ReportError(card.ECIValue, "eci_value", "ECIValue", "excluded_if", "card_number_type fpan")
+		}
+	}
+}
+
+// cardExpired reports the expiry field of card that lies in the past, as a
+// param path, when the card is no longer valid at now. Cards stay valid
+// through the last day of their expiry month (UTC); cards without expiry
+// never expire.
+func cardExpired(card PaymentMethodCard, now time.Time) (string, bool) {
+	if card.ExpMonth == nil || card.ExpYear == nil {
+		return "", false
+	}
+	month, errMonth := strconv.Atoi(*card.ExpMonth)
+	year, errYear := strconv.Atoi(*card.ExpYear)
+	if errMonth != nil || errYear != nil {
+		return "", false
+	}
+	now = now.UTC()
+	switch {
+	case year < now.Year():
+		return "$.payment_method.exp_year", true
+	case year == now.Year() && month < int(now.Month()):
+		return "$.payment_method.exp_month", true
+	}
+	return "", false
+}
+
 func normalizeValidationError(err error) error {
 	var validationErrs validator.ValidationErrors
 	if !errors.As(err, &validationErrs) {
@@ -64,7 +148,31 @@ func normalizeValidationError(err error) error {
 	first := validationErrs[0]
 	fieldPath := jsonPath(first)
 	message := validationMessage(first)
-	return fmt.Errorf("%s %s", fieldPath, message)
+	payload := NewInvalidRequestError(fmt.Sprintf("%s %s", fieldPath, message), WithOffendingParam("$."+fieldPath))
+	payload.details = make([]ValidationErrorDetail, 0, len(validationErrs))
+	payload.messages = make([]MessageError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		payload.details = append(payload.details, ValidationErrorDetail{Field: jsonPath(fe), Tag: fe.Tag(), Param: fe.Param()})
+		payload.messages = append(payload.messages, validationMessageError(fe))
+	}
+	return payload
+}
+
+// validationMessageError describes a failed rule as a checkout session message.
+func validationMessageError(fe validator.FieldError) MessageError {
+	fieldPath := jsonPath(fe)
+	param := "$." + fieldPath
+	code := Invalid
+	if strings.HasPrefix(fe.Tag(), "required") {
+		code = Missing
+	}
+	return MessageError{
+		Type:        "error",
+		Code:        code,
+		Content:     fmt.Sprintf("%s %s", fieldPath, validationMessage(fe)),
+		ContentType: MessageErrorContentTypePlain,
+		Param:       &param,
+	}
 }
 
 func jsonPath(fe validator.FieldError) string {
@@ -92,6 +200,16 @@ func validationMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("cannot exceed %s characters", fe.Param())
 	case "numeric":
 		return "must contain digits only"
+	case "month":
+		return "must be a month between 01 and 12"
+	case "required_with":
+		return fmt.Sprintf("is required with %s", fe.Param())
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", field, value)
+	case "excluded_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("must be omitted when %s is %s", field, value)
 	case "gt":
 		return fmt.Sprintf("must be greater than %s", fe.Param())
 	case "gte":
@@ -101,7 +219,12 @@ func validationMessage(fe validator.FieldError) string {
 	case "oneof":
 		return fmt.Sprintf("must be one of [%s]", strings.ReplaceAll(fe.Param(), " ", ", "))
 	case "currency":
+		if value, ok := fe.Value().(string); ok && currencyPattern.MatchString(value) {
+			return fmt.Sprintf("%q is not an ISO-4217 currency code", value)
+		}
 		return "must be a lowercase 3-letter ISO-4217 code"
+	case "country":
+		return countryMessage
 	case "uppercase":
 		return "must be uppercase"
 	default:
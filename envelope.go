@@ -0,0 +1,38 @@
+package acp
+
+import "net/http"
+
+// Envelope keys used by [WithResponseEnvelope].
+const (
+	envelopeCheckoutSession = "checkout_session"
+	envelopeVaultToken      = "vault_token"
+	envelopeDryRun          = "dry_run"
+	envelopeBatchPayment    = "batch_payment"
+)
+
+// WithResponseEnvelope wraps success payloads in a top-level object keyed by
+// resource type, as required by some revisions of the spec. Error responses,
+// the Server-Sent Events stream and the [WithOpenAPI] document stay
+// unwrapped. The keys per route are:
+//
+//	POST /checkout_sessions                         checkout_session
+//	GET  /checkout_sessions/{id}                    checkout_session
+//	POST /checkout_sessions/{id}                    checkout_session
+//	POST /checkout_sessions/{id}/complete           checkout_session
+//	POST /checkout_sessions/{id}/cancel             checkout_session
+//	POST /agentic_commerce/delegate_payment         vault_token (dry_run for dry runs)
+//	POST /agentic_commerce/delegate_payment/batch   batch_payment
+func WithResponseEnvelope(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.responseEnvelope = enabled
+	}
+}
+
+// writeResource writes payload with status, wrapped under key when
+// [WithResponseEnvelope] is enabled.
+func writeResource(w http.ResponseWriter, cfg config, status int, key string, payload any) {
+	if cfg.responseEnvelope && payload != nil {
+		payload = map[string]any{key: payload}
+	}
+	writeJSON(w, status, payload)
+}
@@ -0,0 +1,133 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithResponseEnvelope(t *testing.T) {
+	t.Parallel()
+
+	checkout := &stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			if id != "cs_123" {
+				return nil, NewHTTPError(http.StatusNotFound, InvalidRequest, ErrorCode("not_found"), "session not found")
+			}
+			return &CheckoutSession{ID: id, Status: CheckoutSessionStatusInProgress, Currency: "usd"}, nil
+		},
+	}
+	delegated := &delegatedStubService{
+		delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+			return &VaultToken{ID: "vt_token", Created: time.Now().UTC()}, nil
+		},
+	}
+	body, err := json.Marshal(sampleDelegatePaymentRequest())
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	tests := map[string]struct {
+		handler  func(opts ...Option) http.Handler
+		method   string
+		path     string
+		body     []byte
+		enabled  bool
+		wantCode int
+		wantKey  string
+	}{
+		"checkout session wrapped": {
+			handler:  func(opts ...Option) http.Handler { return NewCheckoutHandler(checkout, opts...) },
+			method:   http.MethodGet,
+			path:     "/checkout_sessions/cs_123",
+			enabled:  true,
+			wantCode: http.StatusOK,
+			wantKey:  "checkout_session",
+		},
+		"checkout session unwrapped by default": {
+			handler:  func(opts ...Option) http.Handler { return NewCheckoutHandler(checkout, opts...) },
+			method:   http.MethodGet,
+			path:     "/checkout_sessions/cs_123",
+			wantCode: http.StatusOK,
+			wantKey:  "id",
+		},
+		"vault token wrapped": {
+			handler:  func(opts ...Option) http.Handler { return NewDelegatedPaymentHandler(delegated, opts...) },
+			method:   http.MethodPost,
+			path:     "/agentic_commerce/delegate_payment",
+			body:     body,
+			enabled:  true,
+			wantCode: http.StatusCreated,
+			wantKey:  "vault_token",
+		},
+		"error unwrapped": {
+			handler:  func(opts ...Option) http.Handler { return NewCheckoutHandler(checkout, opts...) },
+			method:   http.MethodGet,
+			path:     "/checkout_sessions/cs_missing",
+			enabled:  true,
+			wantCode: http.StatusNotFound,
+			wantKey:  "code",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			tt.handler(WithResponseEnvelope(tt.enabled)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d body=%s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			var decoded map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if _, ok := decoded[tt.wantKey]; !ok {
+				t.Fatalf("expected top-level key %q got %s", tt.wantKey, rec.Body.String())
+			}
+			if tt.enabled && tt.wantCode < 300 && len(decoded) != 1 {
+				t.Fatalf("expected a single envelope key got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocumentEnvelope(t *testing.T) {
+	t.Parallel()
+
+	doc := openAPIDocument("test", []Route{
+		{Method: http.MethodPost, Pattern: "/checkout_sessions"},
+	}, true)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema struct {
+						Required   []string                   `json:"required"`
+						Properties map[string]json.RawMessage `json:"properties"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	schema := decoded.Paths["/checkout_sessions"]["post"].Responses["201"].Content["application/json"].Schema
+	if _, ok := schema.Properties["checkout_session"]; !ok || len(schema.Required) != 1 {
+		t.Fatalf("expected checkout_session envelope got %+v", schema)
+	}
+}
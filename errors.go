@@ -19,15 +19,40 @@ const (
 type ErrorCode string
 
 const (
-	DuplicateRequest     ErrorCode = "duplicate_request"     // Safe duplicate with the same idempotency key.
-	IdempotencyConflict  ErrorCode = "idempotency_conflict"  // Same idempotency key but different parameters.
-	InvalidCard          ErrorCode = "invalid_card"          // Credential failed basic validation (such as length or expiry).
-	InvalidSignature     ErrorCode = "invalid_signature"     // Signature is missing or does not match the payload.
-	SignatureRequired    ErrorCode = "signature_required"    // Signed requests are required but headers were missing.
-	StaleTimestamp       ErrorCode = "stale_timestamp"       // Timestamp skew exceeded the allowed window.
-	MissingAuthorization ErrorCode = "missing_authorization" // Authorization header missing.
-	InvalidAuthorization ErrorCode = "invalid_authorization" // Authorization header malformed or API key invalid.
-	RequestNotIdempotent ErrorCode = "request_not_idempotent"
+	DuplicateRequest         ErrorCode = "duplicate_request"     // Safe duplicate with the same idempotency key.
+	IdempotencyConflict      ErrorCode = "idempotency_conflict"  // Same idempotency key but different parameters.
+	InvalidCard              ErrorCode = "invalid_card"          // Credential failed basic validation (such as length or expiry).
+	InvalidSignature         ErrorCode = "invalid_signature"     // Signature is missing or does not match the payload.
+	SignatureRequired        ErrorCode = "signature_required"    // Signed requests are required but headers were missing.
+	StaleTimestamp           ErrorCode = "stale_timestamp"       // Timestamp skew exceeded the allowed window.
+	MissingAuthorization     ErrorCode = "missing_authorization" // Authorization header missing.
+	InvalidAuthorization     ErrorCode = "invalid_authorization" // Authorization header malformed or API key invalid.
+	RequestNotIdempotent     ErrorCode = "request_not_idempotent"
+	UnsupportedMediaType     ErrorCode = "unsupported_media_type"     // Content-Type is not application/json.
+	ThreeDSPending           ErrorCode = "three_ds_pending"           // Completion attempted before the 3-D Secure challenge was resolved.
+	UnsupportedPaymentMethod ErrorCode = "unsupported_payment_method" // No payment method is supported by both buyer and merchant.
+	NotFound                 ErrorCode = "not_found"                  // No route matches the request path.
+	MethodNotAllowed         ErrorCode = "method_not_allowed"         // The route exists but does not accept the request method.
+	PaymentProviderMismatch  ErrorCode = "payment_provider_mismatch"  // Payment data was issued by another provider than the session's.
+	MetadataTooLarge         ErrorCode = "metadata_too_large"         // Metadata map exceeds the key count or size limit.
+	RequestTooLarge          ErrorCode = "request_too_large"          // Request body exceeds MaxRequestBodyBytes.
+	SessionClosed            ErrorCode = "session_closed"             // Checkout session is completed or canceled and can no longer change.
+	AllowanceExceeded        ErrorCode = "allowance_exceeded"         // Order total is above the max_amount of the payment allowance.
+	TLSRequired              ErrorCode = "tls_required"               // Request was not made over HTTPS.
+	PreconditionFailed       ErrorCode = "precondition_failed"        // If-Match does not match the current version of the checkout session.
+)
+
+// Cart error codes let providers report item problems consistently. They are
+// returned with [NewHTTPError] and the InvalidRequest type.
+const (
+	// EmptyCart rejects completing a session without line items; use 400 Bad Request.
+	EmptyCart ErrorCode = "empty_cart"
+	// UnknownItem rejects an item ID the merchant does not sell; use 400 Bad Request.
+	UnknownItem ErrorCode = "unknown_item"
+	// ItemOutOfStock rejects an item that cannot be fulfilled in the requested
+	// quantity; use 409 Conflict. It shares its value with the [OutOfStock]
+	// message code used for in-session warnings.
+	ItemOutOfStock ErrorCode = "out_of_stock"
 )
 
 // Error represents a structured ACP error payload.
@@ -36,9 +61,55 @@ type Error struct {
 	Code    ErrorCode `json:"code"`
 	Message string    `json:"message"`
 	Param   *string   `json:"param,omitempty"`
+	// Errors lists every failed validation rule. Handlers only fill it in
+	// with [WithValidationErrorDetail].
+	Errors []ValidationErrorDetail `json:"errors,omitempty"`
+	// Messages reports every failed validation rule in the shape of checkout
+	// session messages. Handlers only fill it in with [WithValidationMessages].
+	Messages []MessageError `json:"messages,omitempty"`
+
+	status     int                     `json:"-"`
+	statusSet  bool                    `json:"-"`
+	retryAfter time.Duration           `json:"-"`
+	details    []ValidationErrorDetail `json:"-"`
+	messages   []MessageError          `json:"-"`
+}
+
+// ValidationErrorDetail describes a single failed validation rule.
+type ValidationErrorDetail struct {
+	// Field is the JSON path of the offending field, e.g. allowance.currency.
+	Field string `json:"field"`
+	// Tag is the validator rule that failed, e.g. required or oneof.
+	Tag string `json:"tag"`
+	// Param is the rule argument, if any, e.g. 4 for len=4.
+	Param string `json:"param,omitempty"`
+}
 
-	status     int           `json:"-"`
-	retryAfter time.Duration `json:"-"`
+// ValidationError is a validation failure providers can return from their
+// methods, possibly wrapped, instead of building an [Error]. The handlers
+// report it as an invalid_request error with status 400, or 422 with
+// [WithUnprocessableEntityErrors], and param set to Field. Codes with an
+// entry in the status mapping of [WithErrorStatus], such as invalid_card,
+// use that status instead.
+type ValidationError struct {
+	// Field is the JSON path of the offending field, e.g. $.buyer.email; the
+	// leading "$." may be omitted.
+	Field string
+	// Code defaults to invalid_request.
+	Code ErrorCode
+	// Message is returned to the client.
+	Message string
+}
+
+// Error makes *ValidationError satisfy the stdlib error interface.
+func (e *ValidationError) Error() string {
+	if e == nil {
+		return ""
+	}
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
 }
 
 // Error makes *Error satisfy the stdlib error interface.
@@ -66,38 +137,102 @@ func WithOffendingParam(jsonPath string) errorOption {
 	}
 }
 
-// WithStatusCode overrides the HTTP status code returned to the client.
+// WithStatusCode overrides the HTTP status code returned to the client. An
+// explicit status always wins over the defaults of [WithErrorStatus].
 func WithStatusCode(status int) errorOption {
+	return func(er *Error) {
+		er.status = status
+		er.statusSet = true
+	}
+}
+
+// withDefaultStatus sets the status the builders fall back to when the error
+// code has no entry in the status registry.
+func withDefaultStatus(status int) errorOption {
 	return func(er *Error) {
 		er.status = status
 	}
 }
 
-// WithRetryAfter specifies how long clients should wait before retrying.
+// WithRetryAfter specifies how long clients should wait before retrying. The
+// handlers send it as a Retry-After header in whole seconds, rounded up; it
+// is most useful with [NewRateLimitExceededError] and [NewServiceUnavailableError].
 func WithRetryAfter(d time.Duration) errorOption {
 	return func(er *Error) {
 		er.retryAfter = d
 	}
 }
 
+// defaultErrorStatuses maps error codes to the HTTP status sent when the
+// error does not set one explicitly; see [WithErrorStatus].
+var defaultErrorStatuses = map[ErrorCode]int{
+	InvalidCard:          http.StatusPaymentRequired,
+	InvalidSignature:     http.StatusUnauthorized,
+	SignatureRequired:    http.StatusUnauthorized,
+	StaleTimestamp:       http.StatusUnauthorized,
+	MissingAuthorization: http.StatusUnauthorized,
+	InvalidAuthorization: http.StatusUnauthorized,
+	NotFound:             http.StatusNotFound,
+	MethodNotAllowed:     http.StatusMethodNotAllowed,
+	IdempotencyConflict:  http.StatusConflict,
+	SessionClosed:        http.StatusConflict,
+	ThreeDSPending:       http.StatusConflict,
+	ItemOutOfStock:       http.StatusConflict,
+	PreconditionFailed:   http.StatusPreconditionFailed,
+	RequestTooLarge:      http.StatusRequestEntityTooLarge,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+}
+
+// httpStatus resolves the status code written for e: an explicit status,
+// then the entry of statuses for its code, then the builder default, and
+// finally a status derived from its type for bare [Error] literals.
+func (e *Error) httpStatus(statuses map[ErrorCode]int) int {
+	if !e.statusSet {
+		if status, ok := statuses[e.Code]; ok {
+			return status
+		}
+	}
+	if e.status != 0 {
+		return e.status
+	}
+	switch e.Type {
+	case InvalidRequest:
+		return http.StatusBadRequest
+	case RateLimitExceeded:
+		return http.StatusTooManyRequests
+	case ServiceUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // NewRateLimitExceededError builds a Too Many Requests ACP error payload.
 func NewRateLimitExceededError(message string, opts ...errorOption) *Error {
-	return newError(RateLimitExceeded, ErrorCode(RateLimitExceeded), message, append([]errorOption{WithStatusCode(http.StatusTooManyRequests)}, opts...)...)
+	return newError(RateLimitExceeded, ErrorCode(RateLimitExceeded), message, append([]errorOption{withDefaultStatus(http.StatusTooManyRequests)}, opts...)...)
 }
 
 // NewServiceUnavailableError builds a Service Unavailable ACP error payload.
+// Providers reporting a downstream outage can pass [WithRetryAfter] to tell
+// clients when to try again; the 503 response then carries Retry-After.
 func NewServiceUnavailableError(message string, opts ...errorOption) *Error {
-	return newError(ServiceUnavailable, ErrorCode(ServiceUnavailable), message, append([]errorOption{WithStatusCode(http.StatusServiceUnavailable)}, opts...)...)
+	return newError(ServiceUnavailable, ErrorCode(ServiceUnavailable), message, append([]errorOption{withDefaultStatus(http.StatusServiceUnavailable)}, opts...)...)
 }
 
 // NewInvalidRequestError builds a Bad Request ACP error payload.
 func NewInvalidRequestError(message string, opts ...errorOption) *Error {
-	return newError(InvalidRequest, ErrorCode(InvalidRequest), message, append([]errorOption{WithStatusCode(http.StatusBadRequest)}, opts...)...)
+	return newError(InvalidRequest, ErrorCode(InvalidRequest), message, append([]errorOption{withDefaultStatus(http.StatusBadRequest)}, opts...)...)
+}
+
+// NewUnprocessableEntityError builds an Unprocessable Entity ACP error payload
+// for well-formed requests that fail semantic validation.
+func NewUnprocessableEntityError(message string, opts ...errorOption) *Error {
+	return newError(InvalidRequest, ErrorCode(InvalidRequest), message, append([]errorOption{withDefaultStatus(http.StatusUnprocessableEntity)}, opts...)...)
 }
 
 // NewProcessingError builds an Internal Server Error ACP error payload.
 func NewProcessingError(message string, opts ...errorOption) *Error {
-	return newError(ProcessingError, ErrorCode(ProcessingError), message, append([]errorOption{WithStatusCode(http.StatusInternalServerError)}, opts...)...)
+	return newError(ProcessingError, ErrorCode(ProcessingError), message, append([]errorOption{withDefaultStatus(http.StatusInternalServerError)}, opts...)...)
 }
 
 // NewHTTPError allows callers to control the status code explicitly.
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"os"
 	"strings"
@@ -56,6 +55,8 @@ func webhookOptionsFromEnv() (*acp.WebhookOptions, error) {
 		Endpoint:   endpoint,
 		HeaderName: header,
 		SecretKey:  []byte(secret),
+		// Lets the sample deliver to a local receiver during development.
+		AllowInsecureLocalhost: true,
 	}, nil
 }
 
@@ -143,9 +144,24 @@ func (s *memoryService) CreateSession(ctx context.Context, req acp.CheckoutSessi
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	currency := s.currency
+	if req.Currency != nil {
+		currency = strings.ToUpper(*req.Currency)
+	}
+	// The create request carries no buyer payment methods, so every method
+	// the merchant supports is offered; pass the methods the agent reported
+	// out of band to narrow them down.
+	merchant := acp.PaymentProvider{
+		Provider:                acp.PaymentProviderProviderSumUp,
+		SupportedPaymentMethods: []acp.SupportedPaymentMethods{acp.Card},
+	}
+	methods, err := acp.NegotiatePaymentMethods(merchant, nil)
+	if err != nil {
+		return nil, err
+	}
 	session := &acp.CheckoutSession{
 		ID:                 s.nextSessionID(),
-		Currency:           s.currency,
+		Currency:           currency,
 		Buyer:              cloneBuyer(req.Buyer),
 		FulfillmentAddress: cloneAddress(req.FulfillmentAddress),
 		FulfillmentOptions: defaultFulfillmentOptions(),
@@ -155,11 +171,14 @@ func (s *memoryService) CreateSession(ctx context.Context, req acp.CheckoutSessi
 			{Type: acp.TermsOfUse, Url: "https://merchant.example/terms"},
 		},
 		PaymentProvider: &acp.PaymentProvider{
-			Provider:                "sumup",
-			SupportedPaymentMethods: []acp.SupportedPaymentMethods{acp.Card},
+			Provider:                merchant.Provider,
+			SupportedPaymentMethods: methods,
 		},
 	}
 
+	if err := acp.ValidateLinks(session.Links, acp.PrivacyPolicy, acp.TermsOfUse); err != nil {
+		return nil, err
+	}
 	if err := s.rebuildFinancials(session, req.Items); err != nil {
 		return nil, err
 	}
@@ -177,17 +196,20 @@ func (s *memoryService) UpdateSession(ctx context.Context, id string, req acp.Ch
 
 	state, ok := s.sessions[id]
 	if !ok {
-		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.ErrorCode("not_found"), "checkout session not found")
+		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.NotFound, "checkout session not found")
 	}
 
 	session := state.session
-	if req.Buyer != nil {
+	if err := acp.AssertMutable(session); err != nil {
+		return nil, err
+	}
+	if req.Buyer != nil || req.Clears(acp.UpdateFieldBuyer) {
 		session.Buyer = cloneBuyer(req.Buyer)
 	}
-	if req.FulfillmentAddress != nil {
+	if req.FulfillmentAddress != nil || req.Clears(acp.UpdateFieldFulfillmentAddress) {
 		session.FulfillmentAddress = cloneAddress(req.FulfillmentAddress)
 	}
-	if req.FulfillmentOptionId != nil {
+	if req.FulfillmentOptionId != nil || req.Clears(acp.UpdateFieldFulfillmentOptionID) {
 		session.FulfillmentOptionId = req.FulfillmentOptionId
 	}
 	if req.Items != nil {
@@ -206,7 +228,7 @@ func (s *memoryService) GetSession(ctx context.Context, id string) (*acp.Checkou
 
 	state, ok := s.sessions[id]
 	if !ok {
-		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.ErrorCode("not_found"), "checkout session not found")
+		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.NotFound, "checkout session not found")
 	}
 	return cloneSession(state.session), nil
 }
@@ -230,17 +252,23 @@ func (s *memoryService) CompleteSession(ctx context.Context, id string, req acp.
 
 	state, ok := s.sessions[id]
 	if !ok {
-		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.ErrorCode("not_found"), "checkout session not found")
+		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.NotFound, "checkout session not found")
 	}
 	session := state.session
-	if session.Status == acp.CheckoutSessionStatusCanceled {
-		return nil, acp.NewHTTPError(http.StatusConflict, acp.InvalidRequest, acp.ErrorCode("canceled"), "cannot complete a canceled session")
+	if state.order != nil {
+		return state.toOrderSession(), nil
+	}
+	if err := acp.AssertMutable(session); err != nil {
+		return nil, err
+	}
+	if err := acp.RequireNoPendingThreeDS(session); err != nil {
+		return nil, err
 	}
 	if len(session.LineItems) == 0 {
-		return nil, acp.NewHTTPError(http.StatusBadRequest, acp.InvalidRequest, acp.ErrorCode("empty_cart"), "add items before completing the session")
+		return nil, acp.NewHTTPError(http.StatusBadRequest, acp.InvalidRequest, acp.EmptyCart, "add items before completing the session")
 	}
-	if state.order != nil {
-		return state.toOrderSession(), nil
+	if err := acp.RequirePaymentProvider(session, req.PaymentData); err != nil {
+		return nil, err
 	}
 
 	session.Status = acp.CheckoutSessionStatusCompleted
@@ -268,10 +296,10 @@ func (s *memoryService) CancelSession(ctx context.Context, id string) (*acp.Chec
 
 	state, ok := s.sessions[id]
 	if !ok {
-		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.ErrorCode("not_found"), "checkout session not found")
+		return nil, acp.NewHTTPError(http.StatusNotFound, acp.InvalidRequest, acp.NotFound, "checkout session not found")
 	}
 	if state.order != nil {
-		return nil, acp.NewHTTPError(http.StatusConflict, acp.InvalidRequest, acp.ErrorCode("completed"), "completed sessions cannot be canceled")
+		return nil, acp.NewHTTPError(http.StatusConflict, acp.InvalidRequest, acp.SessionClosed, "completed sessions cannot be canceled")
 	}
 
 	state.session.Status = acp.CheckoutSessionStatusCanceled
@@ -284,7 +312,7 @@ func (s *memoryService) rebuildFinancials(session *acp.CheckoutSession, items []
 		return err
 	}
 	session.LineItems = lines
-	session.Totals = buildTotals(lines, session.Currency)
+	session.Totals = acp.BuildTotals(session.Currency, lines)
 	session.Messages = defaultMessages()
 	return nil
 }
@@ -298,22 +326,9 @@ func (s *memoryService) buildLineItems(items []acp.Item) ([]acp.LineItem, error)
 	for idx, item := range items {
 		product, ok := s.catalog[item.ID]
 		if !ok {
-			return nil, acp.NewHTTPError(http.StatusBadRequest, acp.InvalidRequest, acp.ErrorCode("unknown_item"), fmt.Sprintf("items[%d]: %q is not sold by this merchant", idx, item.ID))
+			return nil, acp.NewHTTPError(http.StatusBadRequest, acp.InvalidRequest, acp.UnknownItem, fmt.Sprintf("items[%d]: %q is not sold by this merchant", idx, item.ID))
 		}
-		base := product.Price * item.Quantity
-		discount := 0
-		tax := int(math.Round(product.TaxRate * float64(base)))
-		subtotal := base - discount
-		total := subtotal + tax
-		lines = append(lines, acp.LineItem{
-			ID:         fmt.Sprintf("li_%s_%d", item.ID, idx),
-			Item:       item,
-			BaseAmount: base,
-			Discount:   discount,
-			Subtotal:   subtotal,
-			Tax:        tax,
-			Total:      total,
-		})
+		lines = append(lines, acp.PricingTaxExclusive.LineItem(fmt.Sprintf("li_%s_%d", item.ID, idx), item, product.Price, 0, product.TaxRate))
 	}
 	return lines, nil
 }
@@ -343,39 +358,8 @@ func deriveStatus(session *acp.CheckoutSession) acp.CheckoutSessionStatus {
 	}
 }
 
-func buildTotals(lines []acp.LineItem, currency string) []acp.Total {
-	var (
-		itemsBase int
-		tax       int
-		total     int
-	)
-	for _, line := range lines {
-		itemsBase += line.BaseAmount
-		tax += line.Tax
-		total += line.Total
-	}
-
-	totals := []acp.Total{
-		{Type: acp.TotalTypeItemsBaseAmount, Amount: itemsBase, DisplayText: formatMoney(currency, itemsBase)},
-	}
-	if tax > 0 {
-		totals = append(totals, acp.Total{
-			Type:        acp.TotalTypeTax,
-			Amount:      tax,
-			DisplayText: formatMoney(currency, tax),
-		})
-	}
-	totals = append(totals, acp.Total{
-		Type:        acp.TotalTypeTotal,
-		Amount:      total,
-		DisplayText: formatMoney(currency, total),
-	})
-	return totals
-}
-
 func formatMoney(currency string, cents int) string {
-	value := float64(cents) / 100
-	return fmt.Sprintf("%s %.2f", currency, value)
+	return acp.Money{Amount: cents, Currency: currency}.String()
 }
 
 func defaultMessages() []acp.Message {
@@ -399,7 +383,7 @@ func defaultFulfillmentOptions() []acp.FulfillmentOption {
 		Subtotal:             formatMoney("USD", 500),
 		Tax:                  formatMoney("USD", 0),
 		Total:                formatMoney("USD", 500),
-		Type:                 "shipping",
+		Type:                 acp.FulfillmentOptionTypeShipping,
 		EarliestDeliveryTime: &soon,
 		LatestDeliveryTime:   &later,
 	}
@@ -410,7 +394,7 @@ func defaultFulfillmentOptions() []acp.FulfillmentOption {
 		Subtotal: formatMoney("USD", 0),
 		Tax:      formatMoney("USD", 0),
 		Total:    formatMoney("USD", 0),
-		Type:     "digital",
+		Type:     acp.FulfillmentOptionTypeDigital,
 	}
 
 	opts := make([]acp.FulfillmentOption, 0, 2)
@@ -2,12 +2,10 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"maps"
 	"net/http"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/sumup/acp"
@@ -60,19 +58,20 @@ func (r *statusRecorder) WriteHeader(code int) {
 }
 
 type delegatedMemoryService struct {
-	mu      sync.Mutex
-	tokens  map[string]*acp.VaultToken
-	tokenID uint64
+	mu     sync.Mutex
+	tokens map[string]*acp.VaultToken
+	ids    acp.TokenIDGenerator
 }
 
 func newDelegatedMemoryService() *delegatedMemoryService {
 	return &delegatedMemoryService{
 		tokens: make(map[string]*acp.VaultToken),
+		ids:    acp.RandomTokenIDGenerator{},
 	}
 }
 
 // DelegatePayment issues idempotent tokens keyed by checkout_session_id.
-func (s *delegatedMemoryService) DelegatePayment(_ context.Context, req acp.PaymentRequest) (*acp.VaultToken, error) {
+func (s *delegatedMemoryService) DelegatePayment(ctx context.Context, req acp.PaymentRequest) (*acp.VaultToken, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -81,28 +80,16 @@ func (s *delegatedMemoryService) DelegatePayment(_ context.Context, req acp.Paym
 		return cloneVaultToken(token), nil
 	}
 
-	metadata := cloneStringMap(req.Metadata)
-	if metadata == nil {
-		metadata = make(map[string]string, 2)
-	}
-	metadata["merchant_id"] = req.Allowance.MerchantID
-	metadata["checkout_session_id"] = key
-
-	token := &acp.VaultToken{
-		ID:       s.nextTokenID(),
-		Created:  time.Now().UTC(),
-		Metadata: metadata,
+	id, err := s.ids.NewTokenID()
+	if err != nil {
+		return nil, err
 	}
+	token := acp.NewVaultToken(ctx, id, time.Now(), req, nil)
 
 	s.tokens[key] = token
 	return cloneVaultToken(token), nil
 }
 
-func (s *delegatedMemoryService) nextTokenID() string {
-	id := atomic.AddUint64(&s.tokenID, 1)
-	return fmt.Sprintf("vt_%06d", id)
-}
-
 func cloneVaultToken(src *acp.VaultToken) *acp.VaultToken {
 	if src == nil {
 		return nil
@@ -1,21 +1,53 @@
 package acp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// maxPooledBufferSize caps the capacity of buffers returned to bufferPool so a
+// single oversized response does not pin memory for the lifetime of the process.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// errRequestBodyRequired is returned by decodeJSON for an empty body.
+var errRequestBodyRequired = errors.New("request body required")
+
 func decodeJSON(body io.ReadCloser, v any) error {
 	defer func() { _ = body.Close() }()
 	dec := json.NewDecoder(body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(v); err != nil {
 		if errors.Is(err, io.EOF) {
-			return errors.New("request body required")
+			return errRequestBodyRequired
 		}
 		return err
 	}
@@ -25,12 +57,138 @@ func decodeJSON(body io.ReadCloser, v any) error {
 	return nil
 }
 
-func writeServiceError(w http.ResponseWriter, err error) {
+// MaxRequestBodyBytes is the largest request body the handlers and
+// [DecodeRequest] accept; larger ones are rejected with 413 and
+// [RequestTooLarge].
+//
+// Clients sending Expect: 100-continue get the 100 Continue response from
+// net/http when the body is first read, which is during signature
+// verification or request decoding. A request whose Content-Length already
+// exceeds the limit is rejected before that, so the client never uploads the
+// body.
+const MaxRequestBodyBytes = 1 << 20
+
+// DecodeRequest decodes the JSON body of r into a T the way the ACP handlers
+// do, for custom routes mounted next to them: the Content-Type must be JSON
+// (or absent), the body must not exceed [MaxRequestBodyBytes], and unknown
+// fields or trailing data are rejected. Failures are returned as an *Error
+// ready to write to the client.
+func DecodeRequest[T any](r *http.Request) (T, *Error) {
+	var v T
+	if !hasJSONContentType(r) {
+		return v, NewHTTPError(http.StatusUnsupportedMediaType, InvalidRequest, UnsupportedMediaType, "Content-Type must be application/json")
+	}
+	body := r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if err := decodeJSON(http.MaxBytesReader(nil, body, MaxRequestBodyBytes), &v); err != nil {
+		return v, decodeError(err)
+	}
+	return v, nil
+}
+
+// decodeError converts a failure to read or decode a request body.
+func decodeError(err error) *Error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return requestTooLarge(maxErr.Limit)
+	}
+	var payload *Error
+	if errors.As(err, &payload) {
+		return payload
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return typeError(typeErr)
+	}
+	return NewInvalidRequestError(err.Error())
+}
+
+// typeError reports a JSON value of the wrong type by its path rather than
+// by the Go types of the decoder, which may be internal.
+func typeError(err *json.UnmarshalTypeError) *Error {
+	var path strings.Builder
+	for segment := range strings.SplitSeq(err.Field, ".") {
+		if _, convErr := strconv.Atoi(segment); convErr == nil {
+			path.WriteString("[" + segment + "]")
+			continue
+		}
+		if path.Len() > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(segment)
+	}
+	field := path.String()
+	return NewInvalidRequestError(fmt.Sprintf("%s must be %s", field, jsonTypeName(err.Type)), WithOffendingParam("$."+field))
+}
+
+// jsonTypeName names the JSON type a Go type decodes from.
+func jsonTypeName(t reflect.Type) string {
+	if t == nil {
+		return "of another type"
+	}
+	switch derefType(t).Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "of another type"
+}
+
+func requestTooLarge(limit int64) *Error {
+	return NewHTTPError(http.StatusRequestEntityTooLarge, InvalidRequest, RequestTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+}
+
+// limitRequestBody caps r.Body at [MaxRequestBodyBytes]. Requests declaring a
+// larger Content-Length are answered with 413 right away, before anything
+// reads the body and triggers a 100 Continue; it reports whether r may proceed.
+func limitRequestBody(w http.ResponseWriter, r *http.Request) bool {
+	if r.ContentLength > MaxRequestBodyBytes {
+		writeJSONError(w, requestTooLarge(MaxRequestBodyBytes))
+		return false
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodyBytes)
+	}
+	return true
+}
+
+// hasJSONContentType reports whether the request declares a JSON body. A
+// missing Content-Type is tolerated so lenient clients keep working.
+func hasJSONContentType(r *http.Request) bool {
+	value := r.Header.Get("Content-Type")
+	if value == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func writeServiceError(w http.ResponseWriter, cfg config, err error) {
 	var httpErr *Error
 	if errors.As(err, &httpErr) {
 		writeJSONError(w, httpErr)
 		return
 	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		writeJSONError(w, cfg.providerValidationError(validationErr))
+		return
+	}
 	writeJSONError(w, NewProcessingError("internal server error"))
 }
 
@@ -38,23 +196,37 @@ func writeJSONError(w http.ResponseWriter, payload *Error) {
 	if payload == nil {
 		payload = NewProcessingError("internal server error")
 	}
+	payload = renderError(w, payload)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	_ = json.NewEncoder(buf).Encode(payload)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("API-Version", APIVersion)
 	if seconds := retryAfterSeconds(payload.RetryAfter()); seconds > 0 {
 		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
 	}
-	w.WriteHeader(payload.status)
-	_ = json.NewEncoder(w).Encode(payload)
+	w.WriteHeader(payload.httpStatus(defaultErrorStatuses))
+	_, _ = w.Write(buf.Bytes())
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("API-Version", APIVersion)
-	w.WriteHeader(status)
 	if payload == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("API-Version", APIVersion)
+		w.WriteHeader(status)
 		return
 	}
-	_ = json.NewEncoder(w).Encode(payload)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := json.NewEncoder(buf).Encode(payload); err != nil {
+		writeJSONError(w, NewProcessingError("internal server error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("API-Version", APIVersion)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
 }
 
 func retryAfterSeconds(d time.Duration) int64 {
@@ -70,3 +242,159 @@ func retryAfterSeconds(d time.Duration) int64 {
 	}
 	return int64(seconds)
 }
+
+// serveMux dispatches r through mux. Requests that match no route are handed
+// to the not found handler of cfg instead of net/http's plaintext 404, and
+// method mismatches on known paths to its method not allowed handler instead
+// of the plaintext 405.
+func serveMux(mux *http.ServeMux, cfg config, w http.ResponseWriter, r *http.Request) {
+	handler, pattern := mux.Handler(r)
+	if pattern != "" {
+		mux.ServeHTTP(w, r)
+		return
+	}
+	handler.ServeHTTP(&notFoundWriter{ResponseWriter: w, req: r, notFound: cfg.notFoundHandler, methodNotAllowed: cfg.methodNotAllowed}, r)
+}
+
+func writeNotFound(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, NewHTTPError(http.StatusNotFound, InvalidRequest, NotFound, fmt.Sprintf("no route matches %s %s", r.Method, r.URL.Path)))
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path)
+	if allow := w.Header().Get("Allow"); allow != "" {
+		message += "; allowed: " + allow
+	}
+	writeJSONError(w, NewHTTPError(http.StatusMethodNotAllowed, InvalidRequest, MethodNotAllowed, message))
+}
+
+// notFoundWriter wraps the response of an unmatched request and swaps a 404
+// or 405 written by the mux for the configured handler. The Allow header set
+// by the mux is kept.
+type notFoundWriter struct {
+	http.ResponseWriter
+	req              *http.Request
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	replaced         bool
+}
+
+func (w *notFoundWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *notFoundWriter) WriteHeader(status int) {
+	if w.replaced {
+		return
+	}
+	var replacement http.Handler
+	switch status {
+	case http.StatusNotFound:
+		replacement = w.notFound
+	case http.StatusMethodNotAllowed:
+		replacement = w.methodNotAllowed
+	}
+	if replacement == nil {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.replaced = true
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("X-Content-Type-Options")
+	replacement.ServeHTTP(w.ResponseWriter, w.req)
+}
+
+func (w *notFoundWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// errorWriter carries the handler's error rendering options, such as
+// [WithLocalizer] and [WithParamFormat], to writeJSONError.
+type errorWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	locale      string
+	localizer   Localizer
+	paramFormat ParamFormat
+	detail      bool
+	messages    bool
+	hook        func(context.Context, *Error) *Error
+	statuses    map[ErrorCode]int
+}
+
+// withErrorRendering wraps w when cfg changes how error payloads are rendered.
+func withErrorRendering(w http.ResponseWriter, r *http.Request, cfg config) http.ResponseWriter {
+	ew := &errorWriter{ResponseWriter: w, ctx: r.Context(), localizer: cfg.localizer, paramFormat: cfg.paramFormat, detail: cfg.validationDetail, messages: cfg.validationMessages, hook: cfg.errorHook, statuses: cfg.errorStatuses}
+	if cfg.localizer != nil {
+		ew.locale = preferredLocale(r.Header.Get("Accept-Language"))
+	}
+	if ew.locale == "" && ew.paramFormat == ParamFormatJSONPath && !ew.detail && !ew.messages && ew.hook == nil && ew.statuses == nil {
+		return w
+	}
+	return ew
+}
+
+func (w *errorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *errorWriter) render(payload *Error) *Error {
+	rendered := *payload
+	if w.statuses != nil {
+		rendered.status = rendered.httpStatus(w.statuses)
+		rendered.statusSet = true
+	}
+	if w.locale != "" {
+		if message := w.localizer.Localize(w.ctx, w.locale, payload); message != "" {
+			rendered.Message = message
+		}
+	}
+	if rendered.Param != nil && w.paramFormat == ParamFormatJSONPointer {
+		pointer := jsonPathToPointer(*rendered.Param)
+		rendered.Param = &pointer
+	}
+	if w.detail && len(payload.details) > 0 {
+		rendered.Errors = payload.details
+	}
+	if w.messages && len(payload.messages) > 0 {
+		rendered.Messages = make([]MessageError, len(payload.messages))
+		for i, msg := range payload.messages {
+			if msg.Param != nil && w.paramFormat == ParamFormatJSONPointer {
+				pointer := jsonPathToPointer(*msg.Param)
+				msg.Param = &pointer
+			}
+			rendered.Messages[i] = msg
+		}
+	}
+	if w.hook == nil {
+		return &rendered
+	}
+	hookCopy := rendered
+	hooked := w.hook(w.ctx, &hookCopy)
+	if hooked == nil {
+		return &rendered
+	}
+	if hooked.status == 0 {
+		hooked.status, hooked.statusSet = rendered.status, rendered.statusSet
+	}
+	return hooked
+}
+
+// renderError applies the rendering options of the errorWriter wrapped by w, if any.
+func renderError(w http.ResponseWriter, payload *Error) *Error {
+	for w != nil {
+		switch rw := w.(type) {
+		case *errorWriter:
+			return rw.render(payload)
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return payload
+		}
+	}
+	return payload
+}
@@ -0,0 +1,500 @@
+package acp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSONReusesPooledBuffers(t *testing.T) {
+	t.Parallel()
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		writeJSON(rec, http.StatusOK, map[string]string{"id": "cs_123"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 got %d", rec.Code)
+		}
+		if got, want := rec.Body.String(), "{\"id\":\"cs_123\"}\n"; got != want {
+			t.Fatalf("unexpected body %q want %q", got, want)
+		}
+	}
+}
+
+func TestWriteJSONEncodingFailureReturnsProcessingError(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	writeJSON(rec, http.StatusOK, map[string]any{"bad": make(chan int)})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 got %d", rec.Code)
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(ProcessingError) {
+		t.Fatalf("expected code %s got %s", ProcessingError, got)
+	}
+}
+
+func TestHandlersUnknownRoutes(t *testing.T) {
+	t.Parallel()
+
+	custom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	tests := map[string]struct {
+		handler     http.Handler
+		method      string
+		path        string
+		wantStatus  int
+		wantACPCode string
+		wantAllow   string
+	}{
+		"checkout typo": {
+			handler:     NewCheckoutHandler(&stubService{}),
+			method:      http.MethodPost,
+			path:        "/checkout_session",
+			wantStatus:  http.StatusNotFound,
+			wantACPCode: string(NotFound),
+		},
+		"delegated payment unknown path": {
+			handler:     NewDelegatedPaymentHandler(&delegatedStubService{}),
+			method:      http.MethodPost,
+			path:        "/agentic_commerce/delegate",
+			wantStatus:  http.StatusNotFound,
+			wantACPCode: string(NotFound),
+		},
+		"checkout method mismatch": {
+			handler:     NewCheckoutHandler(&stubService{}),
+			method:      http.MethodDelete,
+			path:        "/checkout_sessions",
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantACPCode: string(MethodNotAllowed),
+			wantAllow:   "POST",
+		},
+		"delegated payment method mismatch": {
+			handler:     NewDelegatedPaymentHandler(&delegatedStubService{}),
+			method:      http.MethodGet,
+			path:        "/agentic_commerce/delegate_payment",
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantACPCode: string(MethodNotAllowed),
+			wantAllow:   "POST",
+		},
+		"custom method not allowed handler": {
+			handler:    NewCheckoutHandler(&stubService{}, WithMethodNotAllowedHandler(custom)),
+			method:     http.MethodDelete,
+			path:       "/checkout_sessions",
+			wantStatus: http.StatusTeapot,
+			wantAllow:  "POST",
+		},
+		"custom not found handler": {
+			handler:    NewCheckoutHandler(&stubService{}, WithNotFoundHandler(custom)),
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantStatus: http.StatusTeapot,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Fatalf("expected Allow %q got %q", tt.wantAllow, got)
+			}
+			if tt.wantACPCode == "" {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Fatalf("expected JSON content type got %q", got)
+			}
+			if got := rec.Header().Get("API-Version"); got != APIVersion {
+				t.Fatalf("expected API-Version %s got %q", APIVersion, got)
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v body=%s", err, rec.Body.String())
+			}
+			if payload.Type != InvalidRequest || string(payload.Code) != tt.wantACPCode {
+				t.Fatalf("unexpected error payload %+v", payload)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	token := &VaultToken{
+		ID:       "vt_123",
+		Created:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Metadata: map[string]string{"source": "bench", "merchant_id": "acme"},
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		w := newDiscardResponseWriter()
+		for b.Loop() {
+			writeJSON(w, http.StatusCreated, token)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		w := newDiscardResponseWriter()
+		for b.Loop() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("API-Version", APIVersion)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(token)
+		}
+	})
+}
+
+func BenchmarkWriteJSONError(b *testing.B) {
+	b.ReportAllocs()
+	payload := NewInvalidRequestError("items[0]: quantity must be positive", WithOffendingParam("$.items[0].quantity"))
+	w := newDiscardResponseWriter()
+	for b.Loop() {
+		writeJSONError(w, payload)
+	}
+}
+
+// discardResponseWriter drops everything written so benchmarks only measure
+// the encoding path.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func newDiscardResponseWriter() *discardResponseWriter {
+	return &discardResponseWriter{header: http.Header{}}
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func TestDecodeRequest(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		ID string `json:"id"`
+	}
+
+	tests := map[string]struct {
+		contentType string
+		body        string
+		want        payload
+		wantStatus  int
+		wantCode    ErrorCode
+	}{
+		"valid": {
+			contentType: "application/json",
+			body:        `{"id":"cs_123"}`,
+			want:        payload{ID: "cs_123"},
+		},
+		"missing content type": {
+			body: `{"id":"cs_123"}`,
+			want: payload{ID: "cs_123"},
+		},
+		"wrong content type": {
+			contentType: "text/plain",
+			body:        `{"id":"cs_123"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    UnsupportedMediaType,
+		},
+		"unknown field": {
+			contentType: "application/json",
+			body:        `{"id":"cs_123","extra":true}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    ErrorCode(InvalidRequest),
+		},
+		"empty body": {
+			contentType: "application/json",
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    ErrorCode(InvalidRequest),
+		},
+		"too large": {
+			contentType: "application/json",
+			body:        `{"id":"` + strings.Repeat("x", MaxRequestBodyBytes) + `"}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    RequestTooLarge,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/custom", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got, err := DecodeRequest[payload](req)
+			if tt.wantCode != "" {
+				if err == nil {
+					t.Fatalf("expected error got %+v", got)
+				}
+				if err.Code != tt.wantCode || err.status != tt.wantStatus {
+					t.Fatalf("expected %d %s got %d %s", tt.wantStatus, tt.wantCode, err.status, err.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeRequest() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %+v got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithErrorHook(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		hook        func(ctx context.Context, err *Error) *Error
+		wantStatus  int
+		wantCode    ErrorCode
+		wantMessage string
+	}{
+		"nil keeps original": {
+			hook:        func(ctx context.Context, err *Error) *Error { return nil },
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    ErrorCode(ProcessingError),
+			wantMessage: "ledger db timeout",
+		},
+		"modified copy": {
+			hook: func(ctx context.Context, err *Error) *Error {
+				err.Message = "internal error"
+				return err
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    ErrorCode(ProcessingError),
+			wantMessage: "internal error",
+		},
+		"replacement keeps status": {
+			hook: func(ctx context.Context, err *Error) *Error {
+				return &Error{Type: ServiceUnavailable, Code: ErrorCode(ServiceUnavailable), Message: "try again later"}
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    ErrorCode(ServiceUnavailable),
+			wantMessage: "try again later",
+		},
+		"replacement with status": {
+			hook: func(ctx context.Context, err *Error) *Error {
+				return NewServiceUnavailableError("try again later")
+			},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    ErrorCode(ServiceUnavailable),
+			wantMessage: "try again later",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var seen []ErrorCode
+			handler := NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return nil, NewProcessingError("ledger db timeout")
+				},
+			}, WithErrorHook(func(ctx context.Context, err *Error) *Error {
+				seen = append(seen, err.Code)
+				return tt.hook(ctx, err)
+			}))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d", tt.wantStatus, rec.Code)
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Code != tt.wantCode || got.Message != tt.wantMessage {
+				t.Fatalf("expected %s %q got %s %q", tt.wantCode, tt.wantMessage, got.Code, got.Message)
+			}
+			if len(seen) != 1 || seen[0] != ErrorCode(ProcessingError) {
+				t.Fatalf("expected hook to observe processing_error once got %v", seen)
+			}
+		})
+	}
+}
+
+func TestProviderValidationError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err        error
+		opts       []Option
+		wantStatus int
+		wantCode   ErrorCode
+		wantParam  string
+	}{
+		"bad request": {
+			err:        &ValidationError{Field: "buyer.email", Message: "email is not deliverable"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCode(InvalidRequest),
+			wantParam:  "$.buyer.email",
+		},
+		"unprocessable entity": {
+			err:        &ValidationError{Field: "$.items[0].id", Code: UnknownItem, Message: "unknown item"},
+			opts:       []Option{WithUnprocessableEntityErrors()},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   UnknownItem,
+			wantParam:  "$.items[0].id",
+		},
+		"wrapped": {
+			err:        fmt.Errorf("update: %w", &ValidationError{Field: "fulfillment_option_id", Message: "option is no longer available"}),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCode(InvalidRequest),
+			wantParam:  "$.fulfillment_option_id",
+		},
+		"without field": {
+			err:        &ValidationError{Message: "cart is locked"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrorCode(InvalidRequest),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+					return nil, tt.err
+				},
+			}, tt.opts...)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(`{}`)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d", tt.wantStatus, rec.Code)
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Type != InvalidRequest || got.Code != tt.wantCode {
+				t.Fatalf("expected invalid_request %s got %s %s", tt.wantCode, got.Type, got.Code)
+			}
+			var param string
+			if got.Param != nil {
+				param = *got.Param
+			}
+			if param != tt.wantParam {
+				t.Fatalf("expected param %q got %q", tt.wantParam, param)
+			}
+		})
+	}
+}
+
+func TestExpectContinue(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(NewCheckoutHandler(&stubService{
+		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: "cs_123"}, nil
+		},
+	}))
+	t.Cleanup(server.Close)
+
+	body := `{"items":[{"id":"sku_1","quantity":1}]}`
+	send := func(t *testing.T, contentLength int) (*bufio.Reader, net.Conn) {
+		t.Helper()
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "POST /checkout_sessions HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", contentLength)
+		return bufio.NewReader(conn), conn
+	}
+
+	t.Run("continue before body", func(t *testing.T) {
+		t.Parallel()
+
+		reader, conn := send(t, len(body))
+		interim, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read interim response: %v", err)
+		}
+		if strings.TrimSpace(interim) != "HTTP/1.1 100 Continue" {
+			t.Fatalf("expected 100 Continue got %q", interim)
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatalf("read interim response: %v", err)
+		}
+		if _, err := conn.Write([]byte(body)); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201 got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("oversized body rejected without continue", func(t *testing.T) {
+		t.Parallel()
+
+		reader, _ := send(t, MaxRequestBodyBytes+1)
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413 got %d", resp.StatusCode)
+		}
+		var payload Error
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if payload.Code != RequestTooLarge {
+			t.Fatalf("expected code %s got %s", RequestTooLarge, payload.Code)
+		}
+	})
+}
+
+func TestHandlersRejectOversizedChunkedBody(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCheckoutHandler(&stubService{})
+	body := `{"items":[{"id":"` + strings.Repeat("x", MaxRequestBodyBytes) + `","quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(RequestTooLarge) {
+		t.Fatalf("expected code %s got %s", RequestTooLarge, got)
+	}
+}
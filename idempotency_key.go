@@ -0,0 +1,28 @@
+package acp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithRequireIdempotencyKey rejects POST requests without an Idempotency-Key
+// header with a 400 [RequestNotIdempotent] error, covering session create,
+// update, complete and cancel as well as delegate_payment. GET requests are
+// not affected. The check runs after authentication, so unauthenticated
+// requests still get their authentication error first.
+func WithRequireIdempotencyKey() Option {
+	return func(cfg *config) {
+		cfg.requireIdempotencyKey = true
+	}
+}
+
+// requireIdempotencyKey enforces [WithRequireIdempotencyKey].
+func requireIdempotencyKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.TrimSpace(r.Header.Get("Idempotency-Key")) == "" {
+			writeJSONError(w, NewHTTPError(http.StatusBadRequest, InvalidRequest, RequestNotIdempotent, "Idempotency-Key header is required"))
+			return
+		}
+		next(w, r)
+	}
+}
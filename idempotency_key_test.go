@@ -0,0 +1,120 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequireIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	checkout := NewCheckoutHandler(&stubService{
+		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: "cs_123"}, nil
+		},
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id}, nil
+		},
+		update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id}, nil
+		},
+		complete: func(ctx context.Context, id string, req CheckoutSessionCompleteRequest) (*SessionWithOrder, error) {
+			return &SessionWithOrder{CheckoutSession: CheckoutSession{ID: id}}, nil
+		},
+		cancel: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id}, nil
+		},
+	}, WithRequireIdempotencyKey())
+	delegated := NewDelegatedPaymentHandler(successService(), WithRequireIdempotencyKey())
+	encoded, err := json.Marshal(sampleDelegatePaymentRequest())
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	delegateBody := string(encoded)
+
+	tests := map[string]struct {
+		handler    http.Handler
+		method     string
+		path       string
+		body       string
+		key        string
+		wantStatus int
+	}{
+		"create without key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions",
+			body: `{"items":[{"id":"sku_1","quantity":1}]}`, wantStatus: http.StatusBadRequest,
+		},
+		"create with key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions",
+			body: `{"items":[{"id":"sku_1","quantity":1}]}`, key: "idem_1", wantStatus: http.StatusCreated,
+		},
+		"blank key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions",
+			body: `{"items":[{"id":"sku_1","quantity":1}]}`, key: "  ", wantStatus: http.StatusBadRequest,
+		},
+		"update without key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123",
+			body: `{}`, wantStatus: http.StatusBadRequest,
+		},
+		"update with key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123",
+			body: `{}`, key: "idem_2", wantStatus: http.StatusOK,
+		},
+		"complete without key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123/complete",
+			body: `{"payment_data":{"token":"vt_123","provider":"stripe"}}`, wantStatus: http.StatusBadRequest,
+		},
+		"complete with key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123/complete",
+			body: `{"payment_data":{"token":"vt_123","provider":"stripe"}}`, key: "idem_3", wantStatus: http.StatusOK,
+		},
+		"cancel without key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123/cancel",
+			wantStatus: http.StatusBadRequest,
+		},
+		"cancel with key": {
+			handler: checkout, method: http.MethodPost, path: "/checkout_sessions/cs_123/cancel",
+			key: "idem_4", wantStatus: http.StatusOK,
+		},
+		"get without key": {
+			handler: checkout, method: http.MethodGet, path: "/checkout_sessions/cs_123",
+			wantStatus: http.StatusOK,
+		},
+		"delegate payment without key": {
+			handler: delegated, method: http.MethodPost, path: "/agentic_commerce/delegate_payment",
+			body: delegateBody, wantStatus: http.StatusBadRequest,
+		},
+		"delegate payment with key": {
+			handler: delegated, method: http.MethodPost, path: "/agentic_commerce/delegate_payment",
+			body: delegateBody, key: "idem_5", wantStatus: http.StatusCreated,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if got := getErrorCode(rec.Body.Bytes()); got != string(RequestNotIdempotent) {
+					t.Fatalf("expected code %s got %s", RequestNotIdempotent, got)
+				}
+			}
+		})
+	}
+}
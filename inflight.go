@@ -0,0 +1,33 @@
+package acp
+
+import (
+	"net/http"
+	"time"
+)
+
+// maxInFlightRetryAfter is the Retry-After hint sent with shed requests.
+const maxInFlightRetryAfter = time.Second
+
+// NewMaxInFlightMiddleware caps the number of requests served concurrently
+// across every route it wraps. Requests beyond n are rejected immediately with
+// [NewServiceUnavailableError] and a Retry-After hint instead of queueing.
+// Install it with [WithMiddleware]; sharing the returned value between
+// handlers makes them share the cap.
+func NewMaxInFlightMiddleware(n int) Middleware {
+	if n <= 0 {
+		panic("acp: max in-flight requests must be positive")
+	}
+	sem := make(chan struct{}, n)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+			default:
+				writeJSONError(w, NewServiceUnavailableError("too many requests in flight", WithRetryAfter(maxInFlightRetryAfter)))
+				return
+			}
+			defer func() { <-sem }()
+			next(w, r)
+		}
+	}
+}
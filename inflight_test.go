@@ -0,0 +1,71 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestMaxInFlightMiddleware(t *testing.T) {
+	t.Parallel()
+
+	const limit = 2
+	started := make(chan struct{}, limit)
+	release := make(chan struct{})
+	handler := NewCheckoutHandler(&stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			started <- struct{}{}
+			<-release
+			return &CheckoutSession{ID: id}, nil
+		},
+	}, WithMiddleware(NewMaxInFlightMiddleware(limit)))
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+		return rec
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, limit)
+	for i := range limit {
+		wg.Go(func() { results[i] = get() })
+	}
+	for range limit {
+		<-started
+	}
+
+	rec := get()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while saturated got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("expected Retry-After 1 got %q", got)
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(ServiceUnavailable) {
+		t.Fatalf("expected code %s got %s", ServiceUnavailable, got)
+	}
+
+	close(release)
+	wg.Wait()
+	for i, res := range results {
+		if res.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 got %d", i, res.Code)
+		}
+	}
+	if rec := get(); rec.Code != http.StatusOK {
+		t.Fatalf("expected slots to be released got %d", rec.Code)
+	}
+}
+
+func TestNewMaxInFlightMiddlewareRejectsNonPositive(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewMaxInFlightMiddleware(0)
+}
@@ -0,0 +1,176 @@
+package acp
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDialect is the $schema of the documents built by [JSONSchema].
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema document describing the JSON encoding of
+// v, a model struct such as [PaymentRequest] or [CheckoutSessionCreateRequest]
+// or a pointer to one, so payloads can be checked outside Go. Nested structs
+// are listed under $defs. Besides the json tags, the document carries the
+// validate tags that map to JSON Schema (required, len, min, max, gt, gte,
+// lt, lte, eq, oneof and the string formats) and the constants of the enum
+// types, such as [AllowanceReason] and [RiskSignalType]. Rules that need
+// code, such as [CheckoutSessionCreateRequest.Validate], are not included.
+// It returns nil when v is not a struct.
+func JSONSchema(v any) map[string]any {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	b := openAPIBuilder{schemas: map[string]any{}, refPrefix: "#/$defs/"}
+	doc := b.schema(t)
+	doc["$schema"] = JSONSchemaDialect
+	doc["title"] = t.Name()
+	doc["$defs"] = b.schemas
+	return doc
+}
+
+// enumSchemas lists the values of the string enum types of the models.
+var enumSchemas = map[reflect.Type][]string{
+	reflect.TypeFor[CheckoutSessionStatus]():   enumValues(CheckoutSessionStatusCanceled, CheckoutSessionStatusCompleted, CheckoutSessionStatusInProgress, CheckoutSessionStatusNotReadyForPayment, CheckoutSessionStatusReadyForPayment),
+	reflect.TypeFor[LinkType]():                enumValues(PrivacyPolicy, SellerShopPolicies, TermsOfUse),
+	reflect.TypeFor[MessageErrorCode]():        enumValues(Invalid, Missing, OutOfStock, PaymentDeclined, Requires3ds, RequiresSignIn),
+	reflect.TypeFor[MessageErrorContentType](): enumValues(MessageErrorContentTypeMarkdown, MessageErrorContentTypePlain),
+	reflect.TypeFor[MessageInfoContentType]():  enumValues(MessageInfoContentTypeMarkdown, MessageInfoContentTypePlain),
+	reflect.TypeFor[SupportedPaymentMethods](): enumValues(Card),
+	reflect.TypeFor[TotalType]():               enumValues(TotalTypeDiscount, TotalTypeFee, TotalTypeFulfillment, TotalTypeItemsBaseAmount, TotalTypeItemsDiscount, TotalTypeSubtotal, TotalTypeTax, TotalTypeTotal),
+	reflect.TypeFor[PaymentDataProvider]():     enumValues(PaymentDataProviderStripe, PaymentDataProviderSumUp),
+	reflect.TypeFor[PaymentProviderProvider](): enumValues(PaymentProviderProviderStripe, PaymentProviderProviderSumUp),
+	reflect.TypeFor[CancellationReason]():      enumValues(CancellationReasonBuyerAbandoned, CancellationReasonFraudSuspected, CancellationReasonTimeout, CancellationReasonOutOfStock, CancellationReasonOther),
+	reflect.TypeFor[PaymentMethodCardType]():   enumValues(PaymentMethodCardTypeCard),
+	reflect.TypeFor[CardNumberType]():          enumValues(CardCardNumberTypeFPAN, CardCardNumberTypeNetworkToken),
+	reflect.TypeFor[CardFundingType]():         enumValues(CardFundingTypeCredit, CardFundingTypeDebit, CardFundingTypePrepaid),
+	reflect.TypeFor[CardChecksPerformed]():     enumValues(CardChecksPerformedAVS, CardChecksPerformedCVV, CardChecksPerformedANI, CardChecksPerformedAUTH),
+	reflect.TypeFor[AllowanceReason]():         enumValues(AllowanceReasonOneTime),
+	reflect.TypeFor[RiskSignalType]():          enumValues(RiskSignalTypeCardTesting),
+	reflect.TypeFor[RiskSignalAction]():        enumValues(RiskSignalActionManualReview, RiskSignalActionAuthorized, RiskSignalActionBlocked),
+	reflect.TypeFor[ErrorType]():               enumValues(InvalidRequest, ProcessingError, RateLimitExceeded, ServiceUnavailable),
+	reflect.TypeFor[OrderStatus]():             enumValues(OrderStatusCreated, OrderStatusManualReview, OrderStatusConfirmed, OrderStatusCanceled, OrderStatusShipped, OrderStatusFulfilled),
+}
+
+func enumValues[T ~string](values ...T) []string {
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = string(value)
+	}
+	return out
+}
+
+// stringFormats maps validate tags on strings to JSON Schema keywords.
+var stringFormats = map[string]map[string]any{
+	"numeric":  {"pattern": "^[0-9]+$"},
+	"currency": {"pattern": "^[a-z]{3}$"},
+	"country":  {"pattern": "^[A-Z]{2}$"},
+	"month":    {"pattern": "^(0[1-9]|1[0-2])$"},
+	"email":    {"format": "email"},
+	"e164":     {"pattern": `^\+[1-9][0-9]{1,14}$`},
+}
+
+// constrain adds the JSON Schema keywords of a validate tag to schema, the
+// property schema of a field of type t. It reports whether the tag makes the
+// property required.
+func constrain(schema map[string]any, t reflect.Type, tag string) bool {
+	required, elements := false, false
+	target := schema
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		t = derefType(t)
+		switch name {
+		case "required":
+			required = required || !elements
+		case "dive":
+			// Later rules apply to the elements.
+			items, _ := target["items"].(map[string]any)
+			if t.Kind() == reflect.Map {
+				items, _ = target["additionalProperties"].(map[string]any)
+			}
+			if items == nil {
+				return required
+			}
+			target, t, elements = items, t.Elem(), true
+		case "len":
+			setBound(target, t, "min", param)
+			setBound(target, t, "max", param)
+		case "min", "max":
+			setBound(target, t, name, param)
+		case "gt":
+			setNumber(target, "exclusiveMinimum", param)
+		case "gte":
+			setNumber(target, "minimum", param)
+		case "lt":
+			setNumber(target, "exclusiveMaximum", param)
+		case "lte":
+			setNumber(target, "maximum", param)
+		case "eq":
+			target["const"] = typedValue(t, param)
+		case "oneof":
+			var values []any
+			for value := range strings.FieldsSeq(param) {
+				values = append(values, typedValue(t, value))
+			}
+			target["enum"] = values
+		default:
+			for keyword, value := range stringFormats[name] {
+				target[keyword] = value
+			}
+		}
+	}
+	return required
+}
+
+// setBound sets the length, size or value bound named kind (min or max)
+// that fits the type of the field.
+func setBound(schema map[string]any, t reflect.Type, kind, param string) {
+	var keyword string
+	switch t.Kind() {
+	case reflect.String:
+		keyword = kind + "Length"
+	case reflect.Slice, reflect.Array:
+		keyword = kind + "Items"
+	case reflect.Map:
+		keyword = kind + "Properties"
+	default:
+		if isNumber(t) {
+			setNumber(schema, map[string]string{"min": "minimum", "max": "maximum"}[kind], param)
+		}
+		return
+	}
+	if n, err := strconv.Atoi(param); err == nil {
+		schema[keyword] = n
+	}
+}
+
+func setNumber(schema map[string]any, keyword, param string) {
+	if n, err := strconv.ParseFloat(param, 64); err == nil {
+		schema[keyword] = n
+	}
+}
+
+// typedValue converts a validate tag parameter to the JSON type of t.
+func typedValue(t reflect.Type, param string) any {
+	if isNumber(t) {
+		if n, err := strconv.ParseFloat(param, 64); err == nil {
+			return n
+		}
+	}
+	return param
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package acp
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	type property struct {
+		Enum      []any    `json:"enum"`
+		Const     any      `json:"const"`
+		Pattern   string   `json:"pattern"`
+		MinLength *int     `json:"minLength"`
+		MaxLength *int     `json:"maxLength"`
+		Minimum   *float64 `json:"minimum"`
+		Format    string   `json:"format"`
+	}
+	type document struct {
+		Schema string `json:"$schema"`
+		Ref    string `json:"$ref"`
+		Defs   map[string]struct {
+			Properties map[string]property `json:"properties"`
+			Required   []string            `json:"required"`
+		} `json:"$defs"`
+	}
+	decode := func(t *testing.T, v any) document {
+		t.Helper()
+		data, err := json.Marshal(JSONSchema(v))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var doc document
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return doc
+	}
+	one := func(n int) *int { return &n }
+
+	tests := map[string]struct {
+		model    any
+		def      string
+		field    string
+		want     property
+		required bool
+	}{
+		"allowance reason enum": {
+			model: PaymentRequest{}, def: "Allowance", field: "reason",
+			want: property{Enum: []any{"one_time"}, Const: "one_time"}, required: true,
+		},
+		"risk signal type enum": {
+			model: &PaymentRequest{}, def: "RiskSignal", field: "type",
+			want: property{Enum: []any{"card_testing"}}, required: true,
+		},
+		"card expiry month": {
+			model: PaymentRequest{}, def: "PaymentMethodCard", field: "exp_month",
+			want: property{Pattern: "^(0[1-9]|1[0-2])$", MinLength: one(2), MaxLength: one(2)},
+		},
+		"card type const": {
+			model: PaymentRequest{}, def: "PaymentMethodCard", field: "type",
+			want: property{Enum: []any{"card"}, Const: "card"}, required: true,
+		},
+		"allowance currency": {
+			model: PaymentRequest{}, def: "Allowance", field: "currency",
+			want: property{Pattern: "^[a-z]{3}$"}, required: true,
+		},
+		"risk signal score": {
+			model: PaymentRequest{}, def: "RiskSignal", field: "score",
+			want: property{Minimum: func() *float64 { v := 0.0; return &v }()}, required: true,
+		},
+		"checkout items": {
+			model: CheckoutSessionCreateRequest{}, def: "CheckoutSessionCreateRequest", field: "items",
+			want: property{}, required: true,
+		},
+		"checkout total type enum": {
+			model: CheckoutSession{}, def: "Total", field: "type",
+			want: property{Enum: []any{"discount", "fee", "fulfillment", "items_base_amount", "items_discount", "subtotal", "tax", "total"}}, required: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doc := decode(t, tt.model)
+			if doc.Schema != JSONSchemaDialect {
+				t.Fatalf("expected $schema %s got %s", JSONSchemaDialect, doc.Schema)
+			}
+			def, ok := doc.Defs[tt.def]
+			if !ok {
+				t.Fatalf("expected $defs/%s got %v", tt.def, doc.Defs)
+			}
+			got, ok := def.Properties[tt.field]
+			if !ok {
+				t.Fatalf("expected property %s.%s", tt.def, tt.field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %s.%s %+v got %+v", tt.def, tt.field, tt.want, got)
+			}
+			if slices.Contains(def.Required, tt.field) != tt.required {
+				t.Fatalf("expected %s.%s required=%t got %v", tt.def, tt.field, tt.required, def.Required)
+			}
+		})
+	}
+
+	if doc := decode(t, PaymentRequest{}); doc.Ref != "#/$defs/PaymentRequest" {
+		t.Fatalf("expected root reference got %q", doc.Ref)
+	}
+	if JSONSchema("not a struct") != nil {
+		t.Fatal("expected nil schema for non-struct values")
+	}
+}
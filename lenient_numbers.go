@@ -0,0 +1,173 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithLenientNumbers makes the handlers accept integer fields of request
+// bodies, such as items[].quantity and allowance.max_amount, encoded as JSON
+// strings like "2000" as well as numbers. Strings that are not integers are
+// rejected with invalid_request and the param of the field. Off by default,
+// since strict integrations expect a type error for quoted numbers.
+func WithLenientNumbers() Option {
+	return func(cfg *config) {
+		cfg.lenientNumbers = true
+	}
+}
+
+// decodeBody decodes a request body like decodeJSON, applying
+// [WithLenientNumbers].
+func (cfg config) decodeBody(body io.ReadCloser, v any) error {
+	if !cfg.lenientNumbers {
+		return decodeJSON(body, v)
+	}
+	return decodeLenientJSON(body, v)
+}
+
+// decodeRequest is [DecodeRequest] applying [WithLenientNumbers].
+func decodeRequest[T any](r *http.Request, cfg config) (T, *Error) {
+	if !cfg.lenientNumbers {
+		return DecodeRequest[T](r)
+	}
+	var v T
+	if !hasJSONContentType(r) {
+		return v, NewHTTPError(http.StatusUnsupportedMediaType, InvalidRequest, UnsupportedMediaType, "Content-Type must be application/json")
+	}
+	body := r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if err := decodeLenientJSON(http.MaxBytesReader(nil, body, MaxRequestBodyBytes), &v); err != nil {
+		return v, decodeError(err)
+	}
+	return v, nil
+}
+
+// decodeLenientJSON converts the quoted integers of body that belong to
+// integer fields of v, a pointer, into numbers before decoding it with
+// decodeJSON.
+func decodeLenientJSON(body io.ReadCloser, v any) error {
+	data, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		// Let decodeJSON report empty or malformed bodies.
+		return decodeJSON(io.NopCloser(bytes.NewReader(data)), v)
+	}
+	if dec.More() {
+		return decodeJSON(io.NopCloser(bytes.NewReader(data)), v)
+	}
+	raw, err = numberStrings(raw, reflect.TypeOf(v).Elem(), "$")
+	if err != nil {
+		return err
+	}
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(io.NopCloser(bytes.NewReader(normalized)), v)
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// numberStrings walks value, decoded with UseNumber, along t and replaces the
+// strings found where t has an integer with the number they hold. Values
+// that do not fit t are left for the decoder to reject.
+func numberStrings(value any, t reflect.Type, path string) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Leaf types with their own decoding, such as unions, are left alone;
+	// structs like CheckoutSessionUpdateRequest that only post-process
+	// their fields are still walked.
+	if t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return value, nil
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, NewInvalidRequestError(fmt.Sprintf("%s must be an integer", strings.TrimPrefix(path, "$.")), WithOffendingParam(path))
+		}
+		return json.Number(s), nil
+	case reflect.Slice, reflect.Array:
+		elements, ok := value.([]any)
+		if !ok {
+			return value, nil
+		}
+		for i, element := range elements {
+			converted, err := numberStrings(element, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = converted
+		}
+	case reflect.Map:
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return value, nil
+		}
+		for key, entry := range entries {
+			converted, err := numberStrings(entry, t.Elem(), path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = converted
+		}
+	case reflect.Struct:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return value, nil
+		}
+		return value, structNumberStrings(fields, t, path)
+	}
+	return value, nil
+}
+
+func structNumberStrings(fields map[string]any, t reflect.Type, path string) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct {
+			if err := structNumberStrings(fields, derefType(field.Type), path); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		converted, err := numberStrings(value, field.Type, path+"."+name)
+		if err != nil {
+			return err
+		}
+		fields[name] = converted
+	}
+	return nil
+}
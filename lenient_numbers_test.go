@@ -0,0 +1,199 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithLenientNumbersCheckout(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts         []Option
+		body         string
+		wantStatus   int
+		wantQuantity int
+		wantParam    string
+	}{
+		"number": {
+			opts:         []Option{WithLenientNumbers()},
+			body:         `{"items":[{"id":"sku_1","quantity":2}]}`,
+			wantStatus:   http.StatusCreated,
+			wantQuantity: 2,
+		},
+		"quoted number": {
+			opts:         []Option{WithLenientNumbers()},
+			body:         `{"items":[{"id":"sku_1","quantity":"2"}]}`,
+			wantStatus:   http.StatusCreated,
+			wantQuantity: 2,
+		},
+		"non-numeric string": {
+			opts:       []Option{WithLenientNumbers()},
+			body:       `{"items":[{"id":"sku_1","quantity":1},{"id":"sku_2","quantity":"two"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantParam:  "$.items[1].quantity",
+		},
+		"decimal string": {
+			opts:       []Option{WithLenientNumbers()},
+			body:       `{"items":[{"id":"sku_1","quantity":"2.5"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantParam:  "$.items[0].quantity",
+		},
+		"unknown field still rejected": {
+			opts:       []Option{WithLenientNumbers()},
+			body:       `{"items":[{"id":"sku_1","quantity":"2"}],"extra":true}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		"strict by default": {
+			body:       `{"items":[{"id":"sku_1","quantity":"2"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var quantity int
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					quantity = req.Items[0].Quantity
+					return &CheckoutSession{ID: "cs_123"}, nil
+				},
+			}, tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusCreated && quantity != tt.wantQuantity {
+				t.Fatalf("expected quantity %d got %d", tt.wantQuantity, quantity)
+			}
+			if tt.wantParam == "" {
+				return
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Type != InvalidRequest || payload.Param == nil || *payload.Param != tt.wantParam {
+				t.Fatalf("expected invalid_request on %s got %+v", tt.wantParam, payload)
+			}
+		})
+	}
+}
+
+func TestWithLenientNumbersCheckoutUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body         string
+		wantStatus   int
+		wantQuantity int
+		wantParam    string
+		wantCleared  bool
+	}{
+		"quoted number": {
+			body:         `{"items":[{"id":"sku_1","quantity":"2"}]}`,
+			wantStatus:   http.StatusOK,
+			wantQuantity: 2,
+		},
+		"cleared field kept": {
+			body:         `{"items":[{"id":"sku_1","quantity":"3"}],"fulfillment_address":null}`,
+			wantStatus:   http.StatusOK,
+			wantQuantity: 3,
+			wantCleared:  true,
+		},
+		"non-numeric string": {
+			body:       `{"items":[{"id":"sku_1","quantity":"two"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantParam:  "$.items[0].quantity",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got CheckoutSessionUpdateRequest
+			handler := NewCheckoutHandler(&stubService{
+				update: func(ctx context.Context, id string, req CheckoutSessionUpdateRequest) (*CheckoutSession, error) {
+					got = req
+					return &CheckoutSession{ID: id}, nil
+				},
+			}, WithLenientNumbers())
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got.Items == nil || len(*got.Items) != 1 || (*got.Items)[0].Quantity != tt.wantQuantity {
+					t.Fatalf("expected quantity %d got %+v", tt.wantQuantity, got.Items)
+				}
+				if got.Clears(UpdateFieldFulfillmentAddress) != tt.wantCleared {
+					t.Fatalf("expected fulfillment_address cleared %v", tt.wantCleared)
+				}
+				return
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Param == nil || *payload.Param != tt.wantParam {
+				t.Fatalf("expected param %s got %+v", tt.wantParam, payload)
+			}
+		})
+	}
+}
+
+func TestWithLenientNumbersDelegatedPayment(t *testing.T) {
+	t.Parallel()
+
+	encoded, err := json.Marshal(sampleDelegatePaymentRequest())
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("unmarshal fields: %v", err)
+	}
+	fields["allowance"].(map[string]any)["max_amount"] = "2000"
+	body, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("marshal fields: %v", err)
+	}
+
+	var maxAmount int
+	service := successService()
+	delegate := service.delegate
+	service.delegate = func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+		maxAmount = req.Allowance.MaxAmount
+		return delegate(ctx, req)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	NewDelegatedPaymentHandler(service, WithLenientNumbers()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if maxAmount != 2000 {
+		t.Fatalf("expected max_amount 2000 got %d", maxAmount)
+	}
+}
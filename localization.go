@@ -0,0 +1,61 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Localizer translates ACP error messages into the language a client asked
+// for through Accept-Language.
+type Localizer interface {
+	// Localize returns err's message in locale, or "" to keep err.Message.
+	Localize(ctx context.Context, locale string, err *Error) string
+}
+
+// LocalizerFunc lifts bare functions into [Localizer].
+type LocalizerFunc func(ctx context.Context, locale string, err *Error) string
+
+// Localize delegates to the wrapped function.
+func (f LocalizerFunc) Localize(ctx context.Context, locale string, err *Error) string {
+	return f(ctx, locale, err)
+}
+
+// WithLocalizer localizes the message of every error the handler writes,
+// including validation, signature and authentication failures, using the
+// preferred language of the request's Accept-Language header. Requests
+// without the header get the original messages.
+func WithLocalizer(localizer Localizer) Option {
+	if localizer == nil {
+		return invalidOption(errors.New("acp: localizer is required"))
+	}
+	return func(cfg *config) {
+		cfg.localizer = localizer
+	}
+}
+
+// preferredLocale returns the language tag with the highest quality value in
+// an Accept-Language header, ignoring the "*" wildcard.
+func preferredLocale(header string) string {
+	best, bestQ := "", 0.0
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
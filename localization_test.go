@@ -0,0 +1,119 @@
+package acp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sumup/acp/signature"
+)
+
+func TestWithLocalizer(t *testing.T) {
+	t.Parallel()
+
+	spanish := map[ErrorCode]string{
+		ErrorCode(InvalidRequest): "La solicitud no es válida",
+		InvalidSignature:          "La firma no es válida",
+	}
+	localizer := LocalizerFunc(func(ctx context.Context, locale string, err *Error) string {
+		if !strings.HasPrefix(locale, "es") {
+			return ""
+		}
+		return spanish[err.Code]
+	})
+	now := time.Now()
+
+	tests := map[string]struct {
+		acceptLanguage string
+		signature      string
+		wantCode       ErrorCode
+		wantMessage    string
+	}{
+		"localized invalid request": {
+			acceptLanguage: "es-ES",
+			wantCode:       ErrorCode(InvalidRequest),
+			wantMessage:    "La solicitud no es válida",
+		},
+		"localized invalid signature": {
+			acceptLanguage: "es-ES",
+			signature:      "bogus",
+			wantCode:       InvalidSignature,
+			wantMessage:    "La firma no es válida",
+		},
+		"unsupported locale keeps message": {
+			acceptLanguage: "de-DE",
+			wantCode:       ErrorCode(InvalidRequest),
+			wantMessage:    "items must contain at least one entry",
+		},
+		"no accept language keeps message": {
+			wantCode:    ErrorCode(InvalidRequest),
+			wantMessage: "items must contain at least one entry",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{},
+				WithSignatureVerifier(signature.HMACVerifier{Key: []byte("secret")}),
+				WithClock(func() time.Time { return now }),
+				WithLocalizer(localizer),
+			)
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(`{"items":[]}`))
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.signature != "" {
+				req.Header.Set("Signature", tt.signature)
+				req.Header.Set("Timestamp", now.UTC().Format(time.RFC3339))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v body=%s", err, rec.Body.String())
+			}
+			if payload.Type != InvalidRequest {
+				t.Fatalf("expected type %s got %s", InvalidRequest, payload.Type)
+			}
+			if payload.Code != tt.wantCode {
+				t.Fatalf("expected code %s got %s", tt.wantCode, payload.Code)
+			}
+			if payload.Message != tt.wantMessage {
+				t.Fatalf("expected message %q got %q", tt.wantMessage, payload.Message)
+			}
+		})
+	}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		header string
+		want   string
+	}{
+		"single tag":       {header: "es-ES", want: "es-ES"},
+		"quality ordering": {header: "en;q=0.5, es-ES;q=0.9, fr;q=0.1", want: "es-ES"},
+		"default quality":  {header: "de-DE, en;q=0.8", want: "de-DE"},
+		"wildcard ignored": {header: "*, it;q=0.2", want: "it"},
+		"empty":            {header: "", want: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := preferredLocale(tt.header); got != tt.want {
+				t.Fatalf("preferredLocale(%q) = %q want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
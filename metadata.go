@@ -0,0 +1,66 @@
+package acp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMetadataMaxKeys is the default number of keys allowed in a metadata map.
+	DefaultMetadataMaxKeys = 50
+	// DefaultMetadataMaxBytes is the default JSON-encoded size allowed for a metadata map.
+	DefaultMetadataMaxBytes = 8 << 10
+)
+
+// WithMetadataLimits bounds the metadata maps of delegated payment requests
+// (metadata and payment_method.metadata) to maxKeys keys and maxBytes of
+// encoded JSON. Larger maps are rejected with the [MetadataTooLarge] code.
+// Vault tokens returned by the provider are held to the same limits.
+// Defaults to [DefaultMetadataMaxKeys] and [DefaultMetadataMaxBytes].
+func WithMetadataLimits(maxKeys, maxBytes int) Option {
+	if maxKeys <= 0 || maxBytes <= 0 {
+		return invalidOption(errors.New("acp: metadata limits must be positive"))
+	}
+	return func(cfg *config) {
+		cfg.metadataMaxKeys = maxKeys
+		cfg.metadataMaxBytes = maxBytes
+	}
+}
+
+// checkMetadata reports the first metadata map of req exceeding the limits.
+func (cfg config) checkMetadata(req PaymentRequest) *Error {
+	if err := cfg.metadataError("metadata", req.Metadata); err != nil {
+		return err
+	}
+	card, err := req.PaymentMethod.AsCard()
+	if err != nil {
+		return nil
+	}
+	return cfg.metadataError("payment_method.metadata", card.Metadata)
+}
+
+func (cfg config) metadataError(field string, metadata map[string]string) *Error {
+	message := cfg.metadataViolation(metadata)
+	if message == "" {
+		return nil
+	}
+	payload := cfg.newValidationError(fmt.Sprintf("%s %s", field, message), WithOffendingParam("$."+field))
+	payload.Code = MetadataTooLarge
+	return payload
+}
+
+// metadataViolation describes how metadata exceeds the limits, or returns "".
+func (cfg config) metadataViolation(metadata map[string]string) string {
+	if len(metadata) > cfg.metadataMaxKeys {
+		return fmt.Sprintf("has %d keys, at most %d are allowed", len(metadata), cfg.metadataMaxKeys)
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err.Error()
+	}
+	if len(encoded) > cfg.metadataMaxBytes {
+		return fmt.Sprintf("is %d bytes, at most %d are allowed", len(encoded), cfg.metadataMaxBytes)
+	}
+	return ""
+}
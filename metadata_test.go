@@ -0,0 +1,111 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDelegatedPaymentMetadataLimits(t *testing.T) {
+	t.Parallel()
+
+	manyKeys := make(map[string]string, DefaultMetadataMaxKeys+1)
+	for i := range DefaultMetadataMaxKeys + 1 {
+		manyKeys[fmt.Sprintf("key_%d", i)] = "v"
+	}
+
+	tests := map[string]struct {
+		opts       []Option
+		mutate     func(*PaymentRequest)
+		token      map[string]string
+		wantStatus int
+		wantCode   ErrorCode
+		wantParam  string
+	}{
+		"within defaults": {
+			mutate:     func(*PaymentRequest) {},
+			wantStatus: http.StatusCreated,
+		},
+		"too many request keys": {
+			mutate:     func(r *PaymentRequest) { r.Metadata = manyKeys },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   MetadataTooLarge,
+			wantParam:  "$.metadata",
+		},
+		"card metadata too large": {
+			mutate: func(r *PaymentRequest) {
+				updateCard(r, func(c *PaymentMethodCard) {
+					c.Metadata = map[string]string{"blob": strings.Repeat("x", DefaultMetadataMaxBytes)}
+				})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   MetadataTooLarge,
+			wantParam:  "$.payment_method.metadata",
+		},
+		"custom limits": {
+			opts:       []Option{WithMetadataLimits(1, 1024)},
+			mutate:     func(r *PaymentRequest) { r.Metadata = map[string]string{"a": "1", "b": "2"} },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   MetadataTooLarge,
+			wantParam:  "$.metadata",
+		},
+		"vault token guard": {
+			mutate:     func(*PaymentRequest) {},
+			token:      manyKeys,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   ErrorCode(ProcessingError),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &delegatedStubService{delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+				return &VaultToken{ID: "vt_123", Created: time.Now(), Metadata: tt.token}, nil
+			}}
+			payload := sampleDelegatePaymentRequest()
+			tt.mutate(&payload)
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(service, tt.opts...).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var got Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if got.Code != tt.wantCode {
+				t.Fatalf("expected code %s got %s", tt.wantCode, got.Code)
+			}
+			if tt.wantParam != "" && (got.Param == nil || *got.Param != tt.wantParam) {
+				t.Fatalf("expected param %q got %v", tt.wantParam, got.Param)
+			}
+		})
+	}
+}
+
+func TestWithMetadataLimitsRejectsNonPositive(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewDelegatedPaymentHandlerWithError(successService(), WithMetadataLimits(0, 1024)); err == nil {
+		t.Fatalf("expected error for non-positive limits")
+	}
+}
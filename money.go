@@ -0,0 +1,107 @@
+package acp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor units of an ISO-4217 currency, the model
+// behind both the integer fields of [LineItem], [Total] and [Allowance] and
+// the formatted "USD 5.00" strings of fulfillment options.
+type Money struct {
+	// Amount in minor units, e.g. 500 for USD 5.00.
+	Amount int
+	// Currency is the ISO-4217 code; it is normalized to lowercase on the wire.
+	Currency string
+}
+
+// minorUnitExponents lists currencies that do not use two decimal places.
+var minorUnitExponents = map[string]int{
+	"bif": 0, "clp": 0, "djf": 0, "gnf": 0, "jpy": 0, "kmf": 0, "krw": 0, "mga": 0,
+	"pyg": 0, "rwf": 0, "ugx": 0, "vnd": 0, "vuv": 0, "xaf": 0, "xof": 0, "xpf": 0,
+	"bhd": 3, "iqd": 3, "jod": 3, "kwd": 3, "lyd": 3, "omr": 3, "tnd": 3,
+}
+
+// minorUnitExponent returns the number of decimal places of currency.
+func minorUnitExponent(currency string) int {
+	if exp, ok := minorUnitExponents[strings.ToLower(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// String formats m the way fulfillment option amounts are sent, e.g. "USD 5.00".
+func (m Money) String() string {
+	currency := strings.ToUpper(m.Currency)
+	exp := minorUnitExponent(m.Currency)
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if exp == 0 {
+		return fmt.Sprintf("%s %s%d", currency, sign, amount)
+	}
+	scale := 1
+	for range exp {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s %s%d.%0*d", currency, sign, amount/scale, exp, amount%scale)
+}
+
+// ParseMoney parses a formatted amount such as "USD 5.00" as produced by
+// [Money.String]. Amounts with more decimals than the currency allows are rejected.
+func ParseMoney(value string) (Money, error) {
+	currency, amount, ok := strings.Cut(strings.TrimSpace(value), " ")
+	currency = strings.ToLower(currency)
+	if !ok || !isISO4217Currency(currency) {
+		return Money{}, fmt.Errorf("acp: money %q must be formatted as \"<CURRENCY> <amount>\"", value)
+	}
+	exp := minorUnitExponent(currency)
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	if whole == "" || len(frac) > exp || strings.ContainsAny(whole+frac, "+-") {
+		return Money{}, fmt.Errorf("acp: invalid amount in money %q", value)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	minor, err := strconv.Atoi(whole + frac)
+	if err != nil {
+		return Money{}, fmt.Errorf("acp: invalid amount in money %q", value)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+type moneyJSON struct {
+	Amount   int    `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes m as {"amount":500,"currency":"usd"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: strings.ToLower(m.Currency)})
+}
+
+// UnmarshalJSON accepts both the object form written by [Money.MarshalJSON]
+// and a formatted string such as "USD 5.00".
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var formatted string
+	if err := json.Unmarshal(data, &formatted); err == nil {
+		parsed, err := ParseMoney(formatted)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Money{Amount: raw.Amount, Currency: strings.ToLower(raw.Currency)}
+	return nil
+}
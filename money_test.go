@@ -0,0 +1,70 @@
+package acp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyFormatAndParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		money     Money
+		formatted string
+	}{
+		"two decimals":   {money: Money{Amount: 500, Currency: "usd"}, formatted: "USD 5.00"},
+		"leading zeros":  {money: Money{Amount: 7, Currency: "eur"}, formatted: "EUR 0.07"},
+		"negative":       {money: Money{Amount: -1250, Currency: "usd"}, formatted: "USD -12.50"},
+		"zero decimals":  {money: Money{Amount: 1500, Currency: "jpy"}, formatted: "JPY 1500"},
+		"three decimals": {money: Money{Amount: 1005, Currency: "kwd"}, formatted: "KWD 1.005"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.money.String(); got != tt.formatted {
+				t.Fatalf("String() = %q want %q", got, tt.formatted)
+			}
+			parsed, err := ParseMoney(tt.formatted)
+			if err != nil {
+				t.Fatalf("ParseMoney() error = %v", err)
+			}
+			if parsed != tt.money {
+				t.Fatalf("ParseMoney() = %+v want %+v", parsed, tt.money)
+			}
+		})
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"", "5.00", "US 5.00", "USD", "USD 5.001", "JPY 5.5", "USD abc", "USD --5"} {
+		if _, err := ParseMoney(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Parallel()
+
+	raw, err := json.Marshal(Money{Amount: 500, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if got, want := string(raw), `{"amount":500,"currency":"usd"}`; got != want {
+		t.Fatalf("MarshalJSON() = %s want %s", got, want)
+	}
+
+	for _, input := range []string{`{"amount":500,"currency":"usd"}`, `"USD 5.00"`} {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); err != nil {
+			t.Fatalf("unmarshal %s: %v", input, err)
+		}
+		if m != (Money{Amount: 500, Currency: "usd"}) {
+			t.Fatalf("unmarshal %s = %+v", input, m)
+		}
+	}
+}
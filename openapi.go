@@ -0,0 +1,305 @@
+package acp
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIPath is the path served by [WithOpenAPI].
+const OpenAPIPath = "/openapi.json"
+
+// WithOpenAPI serves GET /openapi.json, a minimal OpenAPI 3.1 document
+// describing the routes of the handler (see [CheckoutHandler.Routes]) with
+// the schemas of their request and response bodies, built like [JSONSchema].
+// The endpoint sits behind
+// the same middleware as the other routes, so tooling fetching it must sign
+// and authenticate its requests like any client.
+func WithOpenAPI() Option {
+	return func(cfg *config) {
+		cfg.openAPI = true
+	}
+}
+
+// operationDoc describes the bodies of a route for the OpenAPI document.
+type operationDoc struct {
+	summary  string
+	request  reflect.Type
+	response reflect.Type
+	status   int
+	// accepted marks routes that may also answer 202 Accepted with the same body.
+	accepted bool
+	// envelope is the [WithResponseEnvelope] key of the response.
+	envelope string
+	// eventStream marks Server-Sent Events responses.
+	eventStream bool
+	// optionalBody marks routes that accept an empty request body.
+	optionalBody bool
+}
+
+var operationDocs = map[string]operationDoc{
+	"POST /checkout_sessions": {
+		summary:  "Create a checkout session",
+		request:  reflect.TypeFor[CheckoutSessionCreateRequest](),
+		response: reflect.TypeFor[CheckoutSession](),
+		status:   http.StatusCreated,
+		envelope: envelopeCheckoutSession,
+	},
+	"GET /checkout_sessions/{id}": {
+		summary:  "Retrieve a checkout session",
+		response: reflect.TypeFor[CheckoutSession](),
+		status:   http.StatusOK,
+		envelope: envelopeCheckoutSession,
+	},
+	"POST /checkout_sessions/{id}": {
+		summary:  "Update a checkout session",
+		request:  reflect.TypeFor[CheckoutSessionUpdateRequest](),
+		response: reflect.TypeFor[CheckoutSession](),
+		status:   http.StatusOK,
+		envelope: envelopeCheckoutSession,
+	},
+	"POST /checkout_sessions/{id}/complete": {
+		summary:  "Complete a checkout session",
+		request:  reflect.TypeFor[CheckoutSessionCompleteRequest](),
+		response: reflect.TypeFor[SessionWithOrder](),
+		status:   http.StatusOK,
+		accepted: true,
+		envelope: envelopeCheckoutSession,
+	},
+	"POST /checkout_sessions/{id}/cancel": {
+		summary:      "Cancel a checkout session",
+		request:      reflect.TypeFor[CheckoutSessionCancelRequest](),
+		response:     reflect.TypeFor[CheckoutSession](),
+		status:       http.StatusOK,
+		envelope:     envelopeCheckoutSession,
+		optionalBody: true,
+	},
+	"GET /checkout_sessions/{id}/events": {
+		summary:     "Stream checkout session updates",
+		response:    reflect.TypeFor[CheckoutSession](),
+		status:      http.StatusOK,
+		eventStream: true,
+	},
+	"POST /agentic_commerce/delegate_payment": {
+		summary:  "Delegate a payment credential",
+		request:  reflect.TypeFor[PaymentRequest](),
+		response: reflect.TypeFor[VaultToken](),
+		status:   http.StatusCreated,
+		envelope: envelopeVaultToken,
+	},
+	"POST /agentic_commerce/delegate_payment/batch": {
+		summary:  "Delegate several payment credentials",
+		request:  reflect.TypeFor[BatchPaymentRequest](),
+		response: reflect.TypeFor[BatchPaymentResponse](),
+		status:   http.StatusOK,
+		envelope: envelopeBatchPayment,
+	},
+}
+
+// unionSchemas lists the variants of the union types, whose fields are not
+// visible to reflection.
+var unionSchemas = map[reflect.Type][]reflect.Type{
+	reflect.TypeFor[Message]():           {reflect.TypeFor[MessageInfo](), reflect.TypeFor[MessageError]()},
+	reflect.TypeFor[FulfillmentOption](): {reflect.TypeFor[FulfillmentOptionShipping](), reflect.TypeFor[FulfillmentOptionDigital]()},
+	reflect.TypeFor[PaymentMethod]():     {reflect.TypeFor[PaymentMethodCard]()},
+}
+
+// handleOpenAPI registers the [WithOpenAPI] endpoint describing routes.
+func handleOpenAPI(mux *http.ServeMux, routes *[]Route, title string, envelope bool, middleware ...Middleware) {
+	doc := openAPIDocument(title, *routes, envelope)
+	handleRoute(mux, routes, http.MethodGet, OpenAPIPath, applyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, doc)
+	}, middleware...))
+}
+
+// openAPIDocument builds an OpenAPI 3.1 document for routes, describing
+// responses wrapped by [WithResponseEnvelope] when envelope is set.
+func openAPIDocument(title string, routes []Route, envelope bool) map[string]any {
+	b := openAPIBuilder{schemas: map[string]any{}, refPrefix: "#/components/schemas/"}
+	errorRef := b.schema(reflect.TypeFor[Error]())
+	paths := map[string]any{}
+	for _, route := range routes {
+		doc, ok := operationDocs[route.String()]
+		if !ok {
+			continue
+		}
+		mediaType := "application/json"
+		if doc.eventStream {
+			mediaType = "text/event-stream"
+		}
+		responseSchema := b.schema(doc.response)
+		if envelope && !doc.eventStream {
+			responseSchema = map[string]any{
+				"type":       "object",
+				"required":   []string{doc.envelope},
+				"properties": map[string]any{doc.envelope: responseSchema},
+			}
+		}
+		responses := map[string]any{
+			strconv.Itoa(doc.status): map[string]any{
+				"description": http.StatusText(doc.status),
+				"content":     map[string]any{mediaType: map[string]any{"schema": responseSchema}},
+			},
+			"default": map[string]any{
+				"description": "ACP error",
+				"content":     map[string]any{"application/json": map[string]any{"schema": errorRef}},
+			},
+		}
+		if doc.accepted {
+			responses[strconv.Itoa(http.StatusAccepted)] = map[string]any{
+				"description": http.StatusText(http.StatusAccepted),
+				"content":     map[string]any{mediaType: map[string]any{"schema": responseSchema}},
+			}
+		}
+		operation := map[string]any{
+			"summary":   doc.summary,
+			"responses": responses,
+		}
+		var params []any
+		for segment := range strings.SplitSeq(route.Pattern, "/") {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				params = append(params, map[string]any{
+					"name":     strings.TrimSuffix(name, "}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]any{"type": "string"},
+				})
+			}
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if doc.request != nil {
+			body := map[string]any{
+				"content": map[string]any{"application/json": map[string]any{"schema": b.schema(doc.request)}},
+			}
+			if !doc.optionalBody {
+				body["required"] = true
+			}
+			operation["requestBody"] = body
+		}
+		item, _ := paths[route.Pattern].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[route.Pattern] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+	return map[string]any{
+		"openapi":    "3.1.0",
+		"info":       map[string]any{"title": title, "version": APIVersion},
+		"paths":      paths,
+		"components": map[string]any{"schemas": b.schemas},
+	}
+}
+
+// openAPIBuilder derives JSON schemas from Go types, collecting named
+// structs in schemas, referenced as refPrefix followed by the struct name.
+type openAPIBuilder struct {
+	schemas   map[string]any
+	refPrefix string
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (b openAPIBuilder) schema(t reflect.Type) map[string]any {
+	if variants, ok := unionSchemas[t]; ok {
+		oneOf := make([]any, len(variants))
+		for i, variant := range variants {
+			oneOf[i] = b.schema(variant)
+		}
+		return map[string]any{"oneOf": oneOf}
+	}
+	if values, ok := enumSchemas[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	// Secret values encode as the value they wrap.
+	if value, ok := t.MethodByName("Value"); ok && t.Kind() == reflect.Struct && t.PkgPath() == "github.com/sumup/acp/secret" {
+		return b.schema(value.Type.Out(0))
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// json.RawMessage and other byte slices.
+			return map[string]any{}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Implements(textMarshalerType) {
+			return map[string]any{"type": "string"}
+		}
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		ref := map[string]any{"$ref": b.refPrefix + t.Name()}
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = nil // guards against recursive types
+			b.schemas[t.Name()] = b.object(t)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+// object describes the JSON object encoding a struct, flattening embedded
+// structs the way encoding/json does.
+func (b openAPIBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				collect(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property := b.schema(field.Type)
+			mustSet := constrain(property, field.Type, field.Tag.Get("validate"))
+			properties[name] = property
+			optional := strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+			if mustSet || !optional && field.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+	}
+	collect(t)
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
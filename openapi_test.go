@@ -0,0 +1,143 @@
+package acp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestWithOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		handler   http.Handler
+		wantPaths map[string][]string
+	}{
+		"checkout": {
+			handler: NewCheckoutHandler(&subscriberStub{}, WithOpenAPI()),
+			wantPaths: map[string][]string{
+				"/checkout_sessions":               {"post"},
+				"/checkout_sessions/{id}":          {"get", "post"},
+				"/checkout_sessions/{id}/complete": {"post"},
+				"/checkout_sessions/{id}/cancel":   {"post"},
+				"/checkout_sessions/{id}/events":   {"get"},
+			},
+		},
+		"delegated payment": {
+			handler: NewDelegatedPaymentHandler(&delegatedStubService{}, WithOpenAPI()),
+			wantPaths: map[string][]string{
+				"/agentic_commerce/delegate_payment": {"post"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 got %d body=%s", rec.Code, rec.Body.String())
+			}
+			var doc struct {
+				OpenAPI string `json:"openapi"`
+				Info    struct {
+					Version string `json:"version"`
+				} `json:"info"`
+				Paths      map[string]map[string]json.RawMessage `json:"paths"`
+				Components struct {
+					Schemas map[string]json.RawMessage `json:"schemas"`
+				} `json:"components"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("decode document: %v", err)
+			}
+			if doc.OpenAPI != "3.1.0" || doc.Info.Version != APIVersion {
+				t.Fatalf("unexpected header openapi=%s version=%s", doc.OpenAPI, doc.Info.Version)
+			}
+			if len(doc.Paths) != len(tt.wantPaths) {
+				t.Fatalf("expected %d paths got %d: %v", len(tt.wantPaths), len(doc.Paths), doc.Paths)
+			}
+			for path, methods := range tt.wantPaths {
+				var got []string
+				for method := range doc.Paths[path] {
+					got = append(got, method)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, methods) {
+					t.Fatalf("expected %s methods %v got %v", path, methods, got)
+				}
+			}
+			if _, ok := doc.Components.Schemas["Error"]; !ok {
+				t.Fatalf("expected Error schema")
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocumentSchemas(t *testing.T) {
+	t.Parallel()
+
+	doc := openAPIDocument("test", []Route{
+		{Method: http.MethodPost, Pattern: "/checkout_sessions/{id}/complete"},
+		{Method: http.MethodPost, Pattern: "/checkout_sessions/{id}/cancel"},
+		{Method: http.MethodPost, Pattern: "/agentic_commerce/delegate_payment"},
+	}, false)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	params, _ := decoded.Paths["/checkout_sessions/{id}/complete"]["post"]["parameters"].([]any)
+	if len(params) != 1 || params[0].(map[string]any)["name"] != "id" {
+		t.Fatalf("expected id path parameter got %v", params)
+	}
+	responses, _ := decoded.Paths["/checkout_sessions/{id}/complete"]["post"]["responses"].(map[string]any)
+	if _, ok := responses["202"]; !ok {
+		t.Fatalf("expected a 202 response for completions got %v", responses)
+	}
+	completeBody, _ := decoded.Paths["/checkout_sessions/{id}/complete"]["post"]["requestBody"].(map[string]any)
+	if completeBody["required"] != true {
+		t.Fatalf("expected a required completion body got %v", completeBody)
+	}
+	cancelBody, _ := decoded.Paths["/checkout_sessions/{id}/cancel"]["post"]["requestBody"].(map[string]any)
+	if cancelBody == nil || cancelBody["required"] != nil {
+		t.Fatalf("expected an optional cancel body got %v", cancelBody)
+	}
+	session := decoded.Components.Schemas["SessionWithOrder"]
+	if _, ok := session.Properties["order"]; !ok {
+		t.Fatalf("expected order property got %v", session.Properties)
+	}
+	if _, ok := session.Properties["line_items"]; !ok {
+		t.Fatalf("expected embedded CheckoutSession properties got %v", session.Properties)
+	}
+	if _, ok := session.Properties["messages"]["items"].(map[string]any)["oneOf"]; !ok {
+		t.Fatalf("expected messages to be a union got %v", session.Properties["messages"])
+	}
+	card := decoded.Components.Schemas["PaymentMethodCard"]
+	if card.Properties["number"]["type"] != "string" {
+		t.Fatalf("expected card number to be a string got %v", card.Properties["number"])
+	}
+	if !slices.Contains(decoded.Components.Schemas["PaymentRequest"].Required, "payment_method") {
+		t.Fatalf("expected payment_method to be required")
+	}
+	if slices.Contains(decoded.Components.Schemas["CheckoutSession"].Required, "buyer") {
+		t.Fatalf("expected optional buyer")
+	}
+}
@@ -1,7 +1,14 @@
 package acp
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -13,9 +20,138 @@ type config struct {
 	maxClockSkew          time.Duration
 	requireSignedRequests bool
 	middleware            []Middleware
+	innerMiddleware       []Middleware
 	authenticator         Authenticator
 	clock                 func() time.Time
 	webhook               *webhookConfig
+	acceptedCurrencies    map[string]struct{}
+	signedHeaders         []string
+	unprocessableEntity   bool
+	completionStore       CompletionStore
+	defaultCurrency       string
+	notFoundHandler       http.Handler
+	methodNotAllowed      http.Handler
+	localizer             Localizer
+	paramFormat           ParamFormat
+	validationMode        ValidationMode
+	validationDetail      bool
+	validationMessages    bool
+	metadataMaxKeys       int
+	metadataMaxBytes      int
+	logger                *slog.Logger
+	panicRecovery         bool
+	signatureFormat       SignatureFormat
+	errorHook             func(context.Context, *Error) *Error
+	immutableSessions     bool
+	openAPI               bool
+	timestampParser       func(string) (time.Time, error)
+	responseEnvelope      bool
+	sessionETags          bool
+	cartLimits            cartLimits
+	webhookOutbox         WebhookOutbox
+	requestIDGenerator    RequestIDGenerator
+	lenientNumbers        bool
+	requireIdempotencyKey bool
+	errorStatuses         map[ErrorCode]int
+
+	// errs collects invalid option arguments reported by config.validate.
+	errs []error
+}
+
+// newConfig applies opts on top of the handler defaults.
+func newConfig(opts []Option) config {
+	cfg := config{
+		maxClockSkew:     5 * time.Minute,
+		clock:            time.Now,
+		notFoundHandler:  http.HandlerFunc(writeNotFound),
+		methodNotAllowed: http.HandlerFunc(writeMethodNotAllowed),
+		metadataMaxKeys:  DefaultMetadataMaxKeys,
+		metadataMaxBytes: DefaultMetadataMaxBytes,
+		cartLimits:       defaultCartLimits,
+		panicRecovery:    true,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// validate reports invalid option arguments and options that contradict each other.
+func (cfg config) validate() error {
+	errs := append([]error(nil), cfg.errs...)
+	if cfg.requireSignedRequests && cfg.signatureVerifier == nil {
+		errs = append(errs, errors.New("acp: signature verifier required when signed requests are enforced"))
+	}
+	if cfg.defaultCurrency != "" && !cfg.currencyAccepted(cfg.defaultCurrency) {
+		errs = append(errs, fmt.Errorf("acp: default currency %q must be one of the accepted currencies", cfg.defaultCurrency))
+	}
+	if len(cfg.signedHeaders) > 0 && cfg.signatureVerifier == nil {
+		errs = append(errs, errors.New("acp: signed headers require a signature verifier"))
+	}
+	if cfg.webhookOutbox != nil && cfg.webhook == nil {
+		errs = append(errs, errors.New("checkout: webhook outbox requires webhook options"))
+	}
+	return errors.Join(errs...)
+}
+
+// invalidOption records err so the handler constructors can report it.
+func invalidOption(err error) Option {
+	return func(cfg *config) {
+		cfg.errs = append(cfg.errs, err)
+	}
+}
+
+// newValidationError reports a request that decoded fine but failed
+// validation, as 400 by default or 422 with [WithUnprocessableEntityErrors].
+func (cfg config) newValidationError(message string, opts ...errorOption) *Error {
+	if cfg.unprocessableEntity {
+		return NewUnprocessableEntityError(message, opts...)
+	}
+	return NewInvalidRequestError(message, opts...)
+}
+
+// validationError converts a Validate failure with [config.newValidationError],
+// keeping the offending param when the validator reported one.
+func (cfg config) validationError(err error) *Error {
+	var httpErr *Error
+	if errors.As(err, &httpErr) && httpErr.Param != nil {
+		payload := cfg.newValidationError(httpErr.Message, WithOffendingParam(*httpErr.Param))
+		payload.details = httpErr.details
+		payload.messages = httpErr.messages
+		return payload
+	}
+	return cfg.newValidationError(err.Error())
+}
+
+// providerValidationError converts a [ValidationError] returned by a provider
+// with [config.newValidationError].
+func (cfg config) providerValidationError(err *ValidationError) *Error {
+	var opts []errorOption
+	if err.Field != "" {
+		param := err.Field
+		if !strings.HasPrefix(param, "$") {
+			param = "$." + param
+		}
+		opts = append(opts, WithOffendingParam(param))
+	}
+	payload := cfg.newValidationError(err.Message, opts...)
+	if err.Code != "" {
+		payload.Code = err.Code
+	}
+	return payload
+}
+
+// currencyAccepted reports whether the ISO-4217 code is allowed by
+// [WithAcceptedCurrencies]. Every currency is accepted when none were declared.
+func (cfg config) currencyAccepted(currency string) bool {
+	if len(cfg.acceptedCurrencies) == 0 {
+		return true
+	}
+	_, ok := cfg.acceptedCurrencies[strings.ToLower(strings.TrimSpace(currency))]
+	return ok
 }
 
 type webhookConfig struct {
@@ -27,6 +163,38 @@ type webhookConfig struct {
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
+// handlerMiddleware assembles the route middleware, innermost first as
+// expected by applyMiddleware. Requests run through [WithMiddleware]
+// middleware, signature verification, authentication (when authentication is
+// not nil), the [WithRequireIdempotencyKey] check and [WithInnerMiddleware]
+// middleware, in that order, all wrapped by [RecoverMiddleware] unless
+// disabled with [WithPanicRecovery].
+func (cfg config) handlerMiddleware(authentication Middleware) []Middleware {
+	middleware := append([]Middleware(nil), cfg.innerMiddleware...)
+	if cfg.requireIdempotencyKey {
+		middleware = append(middleware, requireIdempotencyKey)
+	}
+	if authentication != nil {
+		middleware = append(middleware, authentication)
+	}
+	if mw := newSignatureMiddleware(signatureMiddlewareConfig{
+		Verifier:       cfg.signatureVerifier,
+		RequireSigned:  cfg.requireSignedRequests,
+		MaxClockSkew:   cfg.maxClockSkew,
+		Clock:          cfg.clock,
+		SignedHeaders:  cfg.signedHeaders,
+		Format:         cfg.signatureFormat,
+		ParseTimestamp: cfg.timestampParser,
+	}); mw != nil {
+		middleware = append(middleware, Middleware(mw))
+	}
+	middleware = append(middleware, cfg.middleware...)
+	if cfg.panicRecovery {
+		middleware = append(middleware, RecoverMiddleware(cfg.logger))
+	}
+	return middleware
+}
+
 func applyMiddleware(h http.HandlerFunc, middleware ...Middleware) http.HandlerFunc {
 	for _, m := range middleware {
 		h = m(h)
@@ -37,24 +205,52 @@ func applyMiddleware(h http.HandlerFunc, middleware ...Middleware) http.HandlerF
 // Option customizes the handler behavior.
 type Option func(*config)
 
-// WithSignatureVerifier enables canonical JSON signature enforcement.
+// WithSignatureVerifier enables canonical JSON signature enforcement. Pass a
+// [signature.MultiVerifier] to accept several keys while rotating secrets,
+// or [signature.AlgorithmVerifiers] to pick the verifier by the
+// Signature-Algorithm header.
 func WithSignatureVerifier(verifier signature.Verifier) Option {
 	return func(cfg *config) {
 		cfg.signatureVerifier = verifier
 	}
 }
 
+// WithSignedHeaders includes the named request headers (for example
+// Idempotency-Key) in the signing payload verified by [WithSignatureVerifier].
+// See [signature.BuildSigningPayloadWithHeaders] for the canonical layout.
+func WithSignedHeaders(names ...string) Option {
+	canonical := signature.CanonicalHeaderNames(names)
+	if len(canonical) == 0 {
+		return invalidOption(errors.New("acp: at least one signed header is required"))
+	}
+	return func(cfg *config) {
+		cfg.signedHeaders = canonical
+	}
+}
+
 // WithMaxClockSkew sets the tolerated absolute difference between the
 // Timestamp header and the server clock when verifying signed requests.
 func WithMaxClockSkew(skew time.Duration) Option {
 	if skew <= 0 {
-		panic("checkout: max clock skew must be positive")
+		return invalidOption(errors.New("checkout: max clock skew must be positive"))
 	}
 	return func(cfg *config) {
 		cfg.maxClockSkew = skew
 	}
 }
 
+// WithTimestampParser replaces [signature.ParseTimestamp] when reading the
+// Timestamp header of signed requests, for example with
+// [signature.ParseTimestampOrEpoch] for partners that send Unix seconds.
+func WithTimestampParser(fn func(string) (time.Time, error)) Option {
+	if fn == nil {
+		return invalidOption(errors.New("acp: timestamp parser is required"))
+	}
+	return func(cfg *config) {
+		cfg.timestampParser = fn
+	}
+}
+
 // WithRequireSignedRequests enforces that every request carries Signature and
 // Timestamp headers when a verifier is configured.
 func WithRequireSignedRequests() Option {
@@ -63,7 +259,18 @@ func WithRequireSignedRequests() Option {
 	}
 }
 
-// WithMiddleware appends custom middleware in the order provided.
+// WithMiddleware appends custom middleware in the order provided. It wraps the
+// built-in middleware, so it runs before signature verification and
+// authentication and also sees requests they reject.
+//
+// Handlers run their middleware in this order:
+//
+//  1. panic recovery ([WithPanicRecovery])
+//  2. [WithMiddleware] middleware
+//  3. signature verification ([WithSignatureVerifier])
+//  4. authentication ([WithAuthenticator], delegated payment only)
+//  5. [WithInnerMiddleware] middleware
+//  6. the route handler
 func WithMiddleware(mw ...Middleware) Option {
 	return func(cfg *config) {
 		for _, m := range mw {
@@ -75,15 +282,201 @@ func WithMiddleware(mw ...Middleware) Option {
 	}
 }
 
-// WithAuthenticator enables Authorization header API key validation.
+// WithLogger sets the logger handlers report internal failures to, such as
+// recovered panics. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	if logger == nil {
+		return invalidOption(errors.New("acp: logger is required"))
+	}
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
+
+// log returns the logger of [WithLogger], or slog.Default.
+func (cfg config) log() *slog.Logger {
+	if cfg.logger == nil {
+		return slog.Default()
+	}
+	return cfg.logger
+}
+
+// WithPanicRecovery controls whether handlers install [RecoverMiddleware],
+// which is enabled by default. Disable it when an outer server middleware
+// already recovers panics.
+func WithPanicRecovery(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.panicRecovery = enabled
+	}
+}
+
+// WithInnerMiddleware appends custom middleware that runs after signature
+// verification and authentication, right before the route handler, so it only
+// sees requests the built-in middleware accepted. See [WithMiddleware] for the
+// full order.
+func WithInnerMiddleware(mw ...Middleware) Option {
+	return func(cfg *config) {
+		for _, m := range mw {
+			if m == nil {
+				continue
+			}
+			cfg.innerMiddleware = append(cfg.innerMiddleware, m)
+		}
+	}
+}
+
+// WithAuthenticator enables Authorization header API key validation, or any
+// other credential check with a [RequestAuthenticator]. Use
+// [MultiAuthenticator] to accept several kinds of credentials.
 func WithAuthenticator(auth Authenticator) Option {
 	return func(cfg *config) {
 		cfg.authenticator = auth
 	}
 }
 
-// withClock provides deterministic time in tests.
-func checkoutWithClock(fn func() time.Time) Option {
+// WithNotFoundHandler replaces the response for requests no ACP route
+// matches. By default they get an [InvalidRequest] error with the
+// [NotFound] code instead of net/http's plaintext 404.
+func WithNotFoundHandler(handler http.Handler) Option {
+	if handler == nil {
+		return invalidOption(errors.New("acp: not found handler is required"))
+	}
+	return func(cfg *config) {
+		cfg.notFoundHandler = handler
+	}
+}
+
+// WithMethodNotAllowedHandler replaces the response for requests whose path
+// matches an ACP route but not its method. The Allow header listing the
+// accepted methods is already set when handler runs. By default they get an
+// [InvalidRequest] error with the [MethodNotAllowed] code instead of
+// net/http's plaintext 405.
+func WithMethodNotAllowedHandler(handler http.Handler) Option {
+	if handler == nil {
+		return invalidOption(errors.New("acp: method not allowed handler is required"))
+	}
+	return func(cfg *config) {
+		cfg.methodNotAllowed = handler
+	}
+}
+
+// WithDefaultCurrency sets the currency applied to checkout session create
+// requests that omit one, before they reach the [CheckoutProvider].
+func WithDefaultCurrency(currency string) Option {
+	code := strings.ToLower(strings.TrimSpace(currency))
+	if !isISO4217Currency(code) {
+		return invalidOption(fmt.Errorf("acp: invalid default currency %q", currency))
+	}
+	return func(cfg *config) {
+		cfg.defaultCurrency = code
+	}
+}
+
+// WithUnprocessableEntityErrors reports requests that are well-formed JSON but
+// fail validation with 422 Unprocessable Entity instead of 400 Bad Request.
+// Malformed JSON keeps returning 400; the error type stays invalid_request.
+func WithUnprocessableEntityErrors() Option {
+	return func(cfg *config) {
+		cfg.unprocessableEntity = true
+	}
+}
+
+// WithValidationErrorDetail adds an errors array listing every failed
+// validation rule (field, tag and param) to delegated payment validation
+// errors, next to the top-level message that only reports the first one.
+// It is off by default since the detail exposes validation internals; only
+// enable it for trusted callers, such as support tooling or sandboxes.
+func WithValidationErrorDetail(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.validationDetail = enabled
+	}
+}
+
+// WithValidationMessages adds a messages array to delegated payment
+// validation errors, next to the top-level error, with one [MessageError]
+// per failed validation rule: code missing for required fields and invalid
+// otherwise, plain text content and the offending param. Agents can render
+// it like the messages of a checkout session. It is off by default.
+func WithValidationMessages(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.validationMessages = enabled
+	}
+}
+
+// WithErrorHook calls hook with every error the handler is about to write,
+// after localization and param formatting, so it can record metrics or
+// replace the payload, e.g. to hide internal messages in production. The
+// hook receives a copy it may modify; returning nil keeps the original. A
+// returned error without a status code keeps the original status.
+func WithErrorHook(hook func(ctx context.Context, err *Error) *Error) Option {
+	if hook == nil {
+		return invalidOption(errors.New("acp: error hook is required"))
+	}
+	return func(cfg *config) {
+		cfg.errorHook = hook
+	}
+}
+
+// WithErrorStatus sets the HTTP status sent for errors with code that do not
+// set one with [WithStatusCode] or [NewHTTPError], such as a [ValidationError]
+// returned by a provider. It overrides the default mapping:
+//
+//   - invalid_card: 402 Payment Required
+//   - invalid_signature, signature_required, stale_timestamp,
+//     missing_authorization, invalid_authorization: 401 Unauthorized
+//   - not_found: 404 Not Found
+//   - method_not_allowed: 405 Method Not Allowed
+//   - idempotency_conflict, session_closed, three_ds_pending, out_of_stock: 409 Conflict
+//   - precondition_failed: 412 Precondition Failed
+//   - request_too_large: 413 Request Entity Too Large
+//   - unsupported_media_type: 415 Unsupported Media Type
+//
+// Every other code keeps the status of its builder, e.g. 400 for
+// [NewInvalidRequestError] or 422 with [WithUnprocessableEntityErrors].
+// status must be a 4xx or 5xx code.
+func WithErrorStatus(code ErrorCode, status int) Option {
+	if code == "" {
+		return invalidOption(errors.New("acp: error status code is required"))
+	}
+	if status < http.StatusBadRequest || status > 599 {
+		return invalidOption(fmt.Errorf("acp: error status %d for %s must be 4xx or 5xx", status, code))
+	}
+	return func(cfg *config) {
+		if cfg.errorStatuses == nil {
+			cfg.errorStatuses = maps.Clone(defaultErrorStatuses)
+		}
+		cfg.errorStatuses[code] = status
+	}
+}
+
+// WithAcceptedCurrencies restricts the ISO-4217 currencies the handler accepts.
+// Delegated payment requests are checked against allowance.currency and
+// checkout session creations against the requested currency, or the one of
+// [WithDefaultCurrency], before the provider is called; anything else is
+// rejected with an invalid_request error. Codes are case-insensitive.
+func WithAcceptedCurrencies(currencies ...string) Option {
+	if len(currencies) == 0 {
+		return invalidOption(errors.New("acp: at least one accepted currency is required"))
+	}
+	accepted := make(map[string]struct{}, len(currencies))
+	for _, currency := range currencies {
+		code := strings.ToLower(strings.TrimSpace(currency))
+		if !isISO4217Currency(code) {
+			return invalidOption(fmt.Errorf("acp: invalid accepted currency %q", currency))
+		}
+		accepted[code] = struct{}{}
+	}
+	return func(cfg *config) {
+		cfg.acceptedCurrencies = accepted
+	}
+}
+
+// WithClock overrides the time source used to check Timestamp header skew,
+// letting integrators write hermetic tests against signed request verification.
+func WithClock(fn func() time.Time) Option {
+	if fn == nil {
+		return invalidOption(errors.New("acp: clock function is required"))
+	}
 	return func(cfg *config) {
 		cfg.clock = fn
 	}
@@ -99,20 +492,26 @@ type WebhookOptions struct {
 	SecretKey []byte
 	// Client allows overriding the HTTP client used for delivering webhook events.
 	Client *http.Client
+	// AllowInsecureLocalhost permits plain http:// endpoints on loopback hosts,
+	// such as an httptest server. Any other endpoint must use https://.
+	AllowInsecureLocalhost bool
 }
 
 // WithWebhookOptions configures webhook delivery for [CheckoutHandler.SendWebhook].
 func WithWebhookOptions(opts WebhookOptions) Option {
 	endpoint := strings.TrimSpace(opts.Endpoint)
 	if endpoint == "" {
-		panic("checkout: webhook endpoint is required")
+		return invalidOption(errors.New("checkout: webhook endpoint is required"))
+	}
+	if err := validateWebhookEndpoint(endpoint, opts.AllowInsecureLocalhost); err != nil {
+		return invalidOption(fmt.Errorf("checkout: %w", err))
 	}
 	header := strings.TrimSpace(opts.HeaderName)
 	if header == "" {
-		panic("checkout: webhook header name is required")
+		return invalidOption(errors.New("checkout: webhook header name is required"))
 	}
 	if len(opts.SecretKey) == 0 {
-		panic("checkout: webhook secret key is required")
+		return invalidOption(errors.New("checkout: webhook secret key is required"))
 	}
 	secret := append([]byte(nil), opts.SecretKey...)
 	client := opts.Client
@@ -128,3 +527,28 @@ func WithWebhookOptions(opts WebhookOptions) Option {
 		}
 	}
 }
+
+// validateWebhookEndpoint requires an absolute https:// URL so the signed
+// payloads are never sent in the clear; loopback http:// URLs are accepted
+// when allowLocalhost is set.
+func validateWebhookEndpoint(endpoint string, allowLocalhost bool) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("webhook endpoint %q must be an absolute URL", endpoint)
+	}
+	switch {
+	case u.Scheme == "https":
+		return nil
+	case u.Scheme == "http" && allowLocalhost && isLoopbackHost(u.Hostname()):
+		return nil
+	}
+	return fmt.Errorf("webhook endpoint %q must use https", endpoint)
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
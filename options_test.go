@@ -0,0 +1,187 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sumup/acp/signature"
+)
+
+func TestNewHandlerWithErrorRejectsInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts    []Option
+		wantErr string
+	}{
+		"require signed without verifier": {
+			opts:    []Option{WithRequireSignedRequests()},
+			wantErr: "signature verifier required",
+		},
+		"signed headers without verifier": {
+			opts:    []Option{WithSignedHeaders("Idempotency-Key")},
+			wantErr: "signed headers require a signature verifier",
+		},
+		"non-positive clock skew": {
+			opts:    []Option{WithMaxClockSkew(0)},
+			wantErr: "max clock skew must be positive",
+		},
+		"default currency not accepted": {
+			opts:    []Option{WithAcceptedCurrencies("eur"), WithDefaultCurrency("usd")},
+			wantErr: "default currency",
+		},
+		"unknown accepted currency": {
+			opts:    []Option{WithAcceptedCurrencies("zzz")},
+			wantErr: "invalid accepted currency",
+		},
+		"nil error hook": {
+			opts:    []Option{WithErrorHook(nil)},
+			wantErr: "error hook is required",
+		},
+		"nil webhook outbox": {
+			opts:    []Option{WithWebhookOutbox(nil)},
+			wantErr: "webhook outbox is required",
+		},
+		"webhook outbox without webhook options": {
+			opts:    []Option{WithWebhookOutbox(NewMemoryWebhookOutbox())},
+			wantErr: "webhook outbox requires webhook options",
+		},
+		"non-positive cart limits": {
+			opts:    []Option{WithCartLimits(0, 10)},
+			wantErr: "cart limits must be positive",
+		},
+		"nil timestamp parser": {
+			opts:    []Option{WithTimestampParser(nil)},
+			wantErr: "timestamp parser is required",
+		},
+		"nil method not allowed handler": {
+			opts:    []Option{WithMethodNotAllowedHandler(nil)},
+			wantErr: "method not allowed handler is required",
+		},
+		"nil request id generator": {
+			opts:    []Option{WithRequestIDGenerator(nil)},
+			wantErr: "request id generator is required",
+		},
+		"error status out of range": {
+			opts:    []Option{WithErrorStatus(InvalidCard, http.StatusOK)},
+			wantErr: "error status 200 for invalid_card must be 4xx or 5xx",
+		},
+		"several problems": {
+			opts:    []Option{WithClock(nil), WithRequireSignedRequests()},
+			wantErr: "clock function is required\nacp: signature verifier required",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewCheckoutHandlerWithError(&stubService{}, tt.opts...); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkout: expected error containing %q got %v", tt.wantErr, err)
+			}
+			if _, err := NewDelegatedPaymentHandlerWithError(&delegatedStubService{}, tt.opts...); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("delegated payment: expected error containing %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewHandlerWithErrorAcceptsValidOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{
+		WithSignatureVerifier(signature.HMACVerifier{Key: []byte("secret")}),
+		WithRequireSignedRequests(),
+		WithMaxClockSkew(time.Minute),
+		WithAcceptedCurrencies("usd"),
+		WithDefaultCurrency("usd"),
+	}
+	if _, err := NewCheckoutHandlerWithError(&stubService{}, opts...); err != nil {
+		t.Fatalf("NewCheckoutHandlerWithError() error = %v", err)
+	}
+	if _, err := NewDelegatedPaymentHandlerWithError(&delegatedStubService{}, opts...); err != nil {
+		t.Fatalf("NewDelegatedPaymentHandlerWithError() error = %v", err)
+	}
+	if _, err := NewCheckoutHandlerWithError(nil); err == nil {
+		t.Fatalf("expected error for nil service")
+	}
+}
+
+func TestNewCheckoutHandlerPanicsOnInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	NewCheckoutHandler(&stubService{}, WithRequireSignedRequests())
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+	service := &delegatedStubService{delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+		order = append(order, "provider")
+		return &VaultToken{ID: "vt_123", Created: time.Now()}, nil
+	}}
+	handler := NewDelegatedPaymentHandler(service,
+		WithAuthenticator(AuthenticatorFunc(func(ctx context.Context, key string) error {
+			order = append(order, "authentication")
+			return nil
+		})),
+		WithMiddleware(record("outer")),
+		WithInnerMiddleware(record("inner")),
+	)
+	req := newDelegatePaymentHTTPRequest(t)
+	req.Header.Set("Authorization", "Bearer key")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	want := []string{"outer", "authentication", "inner", "provider"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("expected order %v got %v", want, order)
+	}
+}
+
+func TestSignatureVerifiedBeforeAuthentication(t *testing.T) {
+	t.Parallel()
+
+	authenticated := false
+	handler := NewDelegatedPaymentHandler(successService(),
+		WithSignatureVerifier(signature.HMACVerifier{Key: []byte("secret")}),
+		WithRequireSignedRequests(),
+		WithAuthenticator(AuthenticatorFunc(func(ctx context.Context, key string) error {
+			authenticated = true
+			return nil
+		})),
+	)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, newDelegatePaymentHTTPRequest(t))
+
+	if code := getErrorCode(rec.Body.Bytes()); code != string(SignatureRequired) {
+		t.Fatalf("expected error code %s got %s (status %d)", SignatureRequired, code, rec.Code)
+	}
+	if authenticated {
+		t.Fatalf("expected authentication to be skipped for unsigned requests")
+	}
+}
@@ -0,0 +1,67 @@
+package acp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParamFormat selects how [Error] params are rendered in responses.
+type ParamFormat int
+
+const (
+	// ParamFormatJSONPath renders params as RFC 9535 JSONPath, e.g. $.items[0].id.
+	ParamFormatJSONPath ParamFormat = iota
+	// ParamFormatJSONPointer renders params as RFC 6901 JSON Pointer, e.g. /items/0/id.
+	ParamFormatJSONPointer
+)
+
+// WithParamFormat controls the notation of the param field in error
+// responses. Errors are still built with JSONPath params, as expected by
+// [WithOffendingParam]; they are converted when written. Defaults to
+// [ParamFormatJSONPath].
+func WithParamFormat(format ParamFormat) Option {
+	switch format {
+	case ParamFormatJSONPath, ParamFormatJSONPointer:
+	default:
+		return invalidOption(fmt.Errorf("acp: unknown param format %d", format))
+	}
+	return func(cfg *config) {
+		cfg.paramFormat = format
+	}
+}
+
+// jsonPathToPointer converts the normalized JSONPath used in error params
+// ($.a.b[0], $['a'], [*]) to a JSON Pointer. Paths it does not recognize are
+// returned unchanged.
+func jsonPathToPointer(path string) string {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return path
+	}
+	var b strings.Builder
+	for rest != "" {
+		var token string
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			token, rest = rest[1:end+1], rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return path
+			}
+			token, rest = rest[1:end], rest[end+1:]
+			if len(token) >= 2 && (token[0] == '\'' || token[0] == '"') && token[len(token)-1] == token[0] {
+				token = token[1 : len(token)-1]
+			}
+		default:
+			return path
+		}
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package acp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithParamFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts      []Option
+		wantParam string
+	}{
+		"default JSONPath": {
+			wantParam: "$.items[0].quantity",
+		},
+		"explicit JSONPath": {
+			opts:      []Option{WithParamFormat(ParamFormatJSONPath)},
+			wantParam: "$.items[0].quantity",
+		},
+		"JSON Pointer": {
+			opts:      []Option{WithParamFormat(ParamFormatJSONPointer)},
+			wantParam: "/items/0/quantity",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{}, tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(`{"items":[{"id":"sku_1","quantity":0}]}`))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Param == nil || *payload.Param != tt.wantParam {
+				t.Fatalf("expected param %q got %v", tt.wantParam, payload.Param)
+			}
+		})
+	}
+}
+
+func TestJSONPathToPointer(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		path string
+		want string
+	}{
+		"root":              {path: "$", want: ""},
+		"nested field":      {path: "$.allowance.currency", want: "/allowance/currency"},
+		"array index":       {path: "$.items[0].id", want: "/items/0/id"},
+		"bracket key":       {path: "$['a/b']['c~d']", want: "/a~1b/c~0d"},
+		"wildcard":          {path: "$.risk_signals[*].type", want: "/risk_signals/*/type"},
+		"not a JSONPath":    {path: "items[0]", want: "items[0]"},
+		"unterminated path": {path: "$.items[0", want: "$.items[0"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := jsonPathToPointer(tt.path); got != tt.want {
+				t.Fatalf("jsonPathToPointer(%q) = %q want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package acp
+
+import "math"
+
+// PricingMode tells whether catalog prices include tax.
+type PricingMode int
+
+const (
+	// PricingTaxExclusive treats prices as net amounts; tax is added on top,
+	// as is common in the US.
+	PricingTaxExclusive PricingMode = iota
+	// PricingTaxInclusive treats prices as gross amounts that already contain
+	// tax, as is required for consumer prices in the EU; the tax share is
+	// extracted from them.
+	PricingTaxInclusive
+)
+
+// LineItem prices quantity units of item at unitPrice minor units, minus
+// discount (in the same terms as unitPrice), at the fractional taxRate, e.g.
+// 0.2 for 20%.
+//
+// In both modes the result satisfies subtotal = base_amount - discount and
+// total = subtotal + tax. With [PricingTaxInclusive] the total is the gross
+// price the buyer sees and base_amount, discount and subtotal are reported
+// net of tax: a 1200 price at 20% yields a subtotal of 1000, tax of 200 and
+// a total of 1200. Amounts are rounded half away from zero.
+func (m PricingMode) LineItem(id string, item Item, unitPrice, discount int, taxRate float64) LineItem {
+	base := unitPrice * item.Quantity
+	line := LineItem{
+		ID:       id,
+		Item:     item,
+		Discount: discount,
+		TaxRate:  &taxRate,
+	}
+	if m == PricingTaxInclusive {
+		line.Total = base - discount
+		line.Subtotal = roundMinor(float64(line.Total) / (1 + taxRate))
+		line.Tax = line.Total - line.Subtotal
+		line.BaseAmount = roundMinor(float64(base) / (1 + taxRate))
+		line.Discount = line.BaseAmount - line.Subtotal
+		return line
+	}
+	line.BaseAmount = base
+	line.Subtotal = base - discount
+	line.Tax = roundMinor(float64(line.Subtotal) * taxRate)
+	line.Total = line.Subtotal + line.Tax
+	return line
+}
+
+// BuildTotals sums lines, as built by [PricingMode.LineItem], into the
+// session totals: items_base_amount, items_discount when non-zero, subtotal,
+// tax when non-zero, then extra (such as fulfillment or fee totals, which are
+// added to the total as is) and finally total. Display texts are formatted
+// with [Money] in currency unless extra already sets them.
+func BuildTotals(currency string, lines []LineItem, extra ...Total) []Total {
+	var base, discount, subtotal, tax int
+	for _, line := range lines {
+		base += line.BaseAmount
+		discount += line.Discount
+		subtotal += line.Subtotal
+		tax += line.Tax
+	}
+	total := subtotal + tax
+	newTotal := func(typ TotalType, amount int) Total {
+		return Total{Type: typ, Amount: amount, DisplayText: Money{Amount: amount, Currency: currency}.String()}
+	}
+	totals := []Total{newTotal(TotalTypeItemsBaseAmount, base)}
+	if discount != 0 {
+		totals = append(totals, newTotal(TotalTypeItemsDiscount, discount))
+	}
+	totals = append(totals, newTotal(TotalTypeSubtotal, subtotal))
+	if tax != 0 {
+		totals = append(totals, newTotal(TotalTypeTax, tax))
+	}
+	for _, t := range extra {
+		if t.DisplayText == "" {
+			t.DisplayText = newTotal(t.Type, t.Amount).DisplayText
+		}
+		totals = append(totals, t)
+		total += t.Amount
+	}
+	return append(totals, newTotal(TotalTypeTotal, total))
+}
+
+func roundMinor(amount float64) int {
+	return int(math.Round(amount))
+}
@@ -0,0 +1,123 @@
+package acp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPricingModeLineItem(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mode      PricingMode
+		unitPrice int
+		quantity  int
+		discount  int
+		taxRate   float64
+		want      LineItem
+	}{
+		"exclusive": {
+			mode:      PricingTaxExclusive,
+			unitPrice: 650,
+			quantity:  2,
+			taxRate:   0.07,
+			want:      LineItem{BaseAmount: 1300, Subtotal: 1300, Tax: 91, Total: 1391},
+		},
+		"exclusive with discount": {
+			mode:      PricingTaxExclusive,
+			unitPrice: 1000,
+			quantity:  1,
+			discount:  100,
+			taxRate:   0.2,
+			want:      LineItem{BaseAmount: 1000, Discount: 100, Subtotal: 900, Tax: 180, Total: 1080},
+		},
+		"inclusive 20% VAT": {
+			mode:      PricingTaxInclusive,
+			unitPrice: 1200,
+			quantity:  1,
+			taxRate:   0.2,
+			want:      LineItem{BaseAmount: 1000, Subtotal: 1000, Tax: 200, Total: 1200},
+		},
+		"inclusive with discount": {
+			mode:      PricingTaxInclusive,
+			unitPrice: 1200,
+			quantity:  1,
+			discount:  120,
+			taxRate:   0.2,
+			want:      LineItem{BaseAmount: 1000, Discount: 100, Subtotal: 900, Tax: 180, Total: 1080},
+		},
+		"inclusive rounding": {
+			mode:      PricingTaxInclusive,
+			unitPrice: 999,
+			quantity:  1,
+			taxRate:   0.19,
+			want:      LineItem{BaseAmount: 839, Subtotal: 839, Tax: 160, Total: 999},
+		},
+		"inclusive zero rate": {
+			mode:      PricingTaxInclusive,
+			unitPrice: 2400,
+			quantity:  1,
+			want:      LineItem{BaseAmount: 2400, Subtotal: 2400, Total: 2400},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			item := Item{ID: "sku_1", Quantity: tt.quantity}
+			got := tt.mode.LineItem("li_1", item, tt.unitPrice, tt.discount, tt.taxRate)
+			if got.TaxRate == nil || *got.TaxRate != tt.taxRate {
+				t.Fatalf("expected tax rate %v got %v", tt.taxRate, got.TaxRate)
+			}
+			want := tt.want
+			want.ID, want.Item, want.TaxRate = "li_1", item, got.TaxRate
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("expected %+v got %+v", want, got)
+			}
+			if got.Subtotal != got.BaseAmount-got.Discount || got.Total != got.Subtotal+got.Tax {
+				t.Fatalf("line item amounts do not add up: %+v", got)
+			}
+		})
+	}
+}
+
+func TestBuildTotals(t *testing.T) {
+	t.Parallel()
+
+	lines := []LineItem{
+		PricingTaxInclusive.LineItem("li_1", Item{ID: "sku_1", Quantity: 1}, 1200, 0, 0.2),
+		PricingTaxInclusive.LineItem("li_2", Item{ID: "sku_2", Quantity: 1}, 600, 60, 0.2),
+	}
+
+	got := BuildTotals("eur", lines, Total{Type: TotalTypeFulfillment, Amount: 500})
+
+	want := []Total{
+		{Type: TotalTypeItemsBaseAmount, Amount: 1500, DisplayText: "EUR 15.00"},
+		{Type: TotalTypeItemsDiscount, Amount: 50, DisplayText: "EUR 0.50"},
+		{Type: TotalTypeSubtotal, Amount: 1450, DisplayText: "EUR 14.50"},
+		{Type: TotalTypeTax, Amount: 290, DisplayText: "EUR 2.90"},
+		{Type: TotalTypeFulfillment, Amount: 500, DisplayText: "EUR 5.00"},
+		{Type: TotalTypeTotal, Amount: 2240, DisplayText: "EUR 22.40"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v got %+v", want, got)
+	}
+}
+
+func TestBuildTotalsOmitsZeroDiscountAndTax(t *testing.T) {
+	t.Parallel()
+
+	lines := []LineItem{PricingTaxExclusive.LineItem("li_1", Item{ID: "sku_1", Quantity: 1}, 2400, 0, 0)}
+
+	got := BuildTotals("usd", lines)
+
+	want := []Total{
+		{Type: TotalTypeItemsBaseAmount, Amount: 2400, DisplayText: "USD 24.00"},
+		{Type: TotalTypeSubtotal, Amount: 2400, DisplayText: "USD 24.00"},
+		{Type: TotalTypeTotal, Amount: 2400, DisplayText: "USD 24.00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v got %+v", want, got)
+	}
+}
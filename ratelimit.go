@@ -0,0 +1,138 @@
+package acp
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Burst requests may be served at once and
+// the bucket refills at Rate requests per second. The zero value disables
+// limiting.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// RateLimitOptions configure [RateLimitMiddleware].
+type RateLimitOptions struct {
+	// Key identifies the caller a bucket belongs to, for example the API key
+	// or the client IP. Requests yielding the same key share a bucket per route.
+	Key func(*http.Request) string
+	// Default applies to every route without an entry in Routes.
+	Default RateLimit
+	// Routes overrides the limit per ServeMux pattern, for example
+	// "POST /agentic_commerce/delegate_payment".
+	Routes map[string]RateLimit
+}
+
+// RateLimitMiddleware enforces per-caller token bucket limits and rejects
+// excess requests with [NewRateLimitExceededError], populating Retry-After with
+// the time until the next token is available. Install it with [WithMiddleware].
+func RateLimitMiddleware(opts RateLimitOptions) Middleware {
+	return newRateLimiter(opts, time.Now).middleware
+}
+
+// idleBucketSweepInterval controls how often buckets that have fully refilled
+// are dropped so the limiter does not grow with every caller ever seen.
+const idleBucketSweepInterval = time.Minute
+
+type rateLimiter struct {
+	key     func(*http.Request) string
+	def     RateLimit
+	routes  map[string]RateLimit
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*tokenBucket
+	swept   time.Time
+}
+
+type rateLimitKey struct {
+	route  string
+	caller string
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(opts RateLimitOptions, now func() time.Time) *rateLimiter {
+	if opts.Key == nil {
+		panic("acp: rate limit key function is required")
+	}
+	routes := make(map[string]RateLimit, len(opts.Routes))
+	for pattern, limit := range opts.Routes {
+		routes[pattern] = limit
+	}
+	return &rateLimiter{
+		key:     opts.Key,
+		def:     opts.Default,
+		routes:  routes,
+		now:     now,
+		buckets: make(map[rateLimitKey]*tokenBucket),
+		swept:   now(),
+	}
+}
+
+func (l *rateLimiter) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := l.routes[r.Pattern]
+		if !ok {
+			limit = l.def
+		}
+		if !limit.enabled() {
+			next(w, r)
+			return
+		}
+		if wait, allowed := l.take(rateLimitKey{route: r.Pattern, caller: l.key(r)}, limit); !allowed {
+			writeJSONError(w, NewRateLimitExceededError("rate limit exceeded", WithRetryAfter(wait)))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// take consumes a token from the bucket, returning how long to wait for the
+// next one when the bucket is empty.
+func (l *rateLimiter) take(key rateLimitKey, limit RateLimit) (time.Duration, bool) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= idleBucketSweepInterval {
+		for k, b := range l.buckets {
+			if b.refill(now) >= float64(b.limit.Burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	if b.refill(now) >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / limit.Rate * float64(time.Second)))
+	return wait, false
+}
+
+func (b *tokenBucket) refill(now time.Time) float64 {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+	return b.tokens
+}
@@ -0,0 +1,154 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddlewareEnforcesBucketPerCaller(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := newRateLimiter(RateLimitOptions{
+		Key: func(r *http.Request) string { return r.Header.Get("Authorization") },
+		Routes: map[string]RateLimit{
+			"POST /agentic_commerce/delegate_payment": {Rate: 0.5, Burst: 2},
+		},
+	}, clock.Now)
+	handler := NewDelegatedPaymentHandler(successService(), WithMiddleware(limiter.middleware))
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := newDelegatePaymentHTTPRequest(t)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := send("key_a"); rec.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected 201 got %d", i, rec.Code)
+		}
+	}
+	rec := send("key_a")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 got %d", rec.Code)
+	}
+	if got := getErrorCode(rec.Body.Bytes()); got != string(RateLimitExceeded) {
+		t.Fatalf("expected code %s got %s", RateLimitExceeded, got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("expected Retry-After 2 got %q", got)
+	}
+
+	if rec := send("key_b"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected other caller to be allowed, got %d", rec.Code)
+	}
+
+	clock.Advance(2 * time.Second)
+	if rec := send("key_a"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected refilled bucket to allow request, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddlewareAppliesPerRouteLimits(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := newRateLimiter(RateLimitOptions{
+		Key:     func(r *http.Request) string { return "caller" },
+		Default: RateLimit{Rate: 1, Burst: 1},
+		Routes: map[string]RateLimit{
+			"GET /checkout_sessions/{id}": {},
+		},
+	}, clock.Now)
+	handler := NewCheckoutHandler(&stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id}, nil
+		},
+		cancel: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: id}, nil
+		},
+	}, WithMiddleware(limiter.middleware))
+
+	for i := range 3 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get %d: expected unlimited route to return 200 got %d", i, rec.Code)
+		}
+	}
+
+	wantStatus := []int{http.StatusOK, http.StatusTooManyRequests}
+	for i, want := range wantStatus {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/cancel", nil))
+		if rec.Code != want {
+			t.Fatalf("cancel %d: expected %d got %d", i, want, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddlewareRequiresKey(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic without key function")
+		}
+	}()
+	RateLimitMiddleware(RateLimitOptions{Default: RateLimit{Rate: 1, Burst: 1}})
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimitMiddlewareCoversTrailingSlash(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := newRateLimiter(RateLimitOptions{
+		Key: func(r *http.Request) string { return "caller" },
+		Routes: map[string]RateLimit{
+			"POST /agentic_commerce/delegate_payment": {Rate: 0.5, Burst: 1},
+		},
+	}, clock.Now)
+	handler := NewDelegatedPaymentHandler(successService(), WithMiddleware(limiter.middleware))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := newDelegatePaymentHTTPRequest(t)
+		req.URL.Path = path
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send("/agentic_commerce/delegate_payment"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected first request to be allowed, got %d", rec.Code)
+	}
+	if rec := send("/agentic_commerce/delegate_payment/"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected trailing-slash request to share the route limit, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// redactedHeaderValue replaces sensitive header values in a [Recording].
+const redactedHeaderValue = "[REDACTED]"
+
+// redactedHeaders are never written to a [RecordSink] verbatim.
+var redactedHeaders = []string{"Authorization", "Signature"}
+
+// Recording is one request/response exchange captured by [NewRecordingHandler].
+type Recording struct {
+	Method         string
+	Path           string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+}
+
+// RecordSink receives the exchanges captured by [NewRecordingHandler], for
+// example to write them as conformance fixtures. Implementations must be safe
+// for concurrent use.
+type RecordSink interface {
+	Record(ctx context.Context, recording Recording)
+}
+
+// RecordSinkFunc lifts bare functions into [RecordSink].
+type RecordSinkFunc func(ctx context.Context, recording Recording)
+
+// Record delegates to the wrapped function.
+func (f RecordSinkFunc) Record(ctx context.Context, recording Recording) {
+	f(ctx, recording)
+}
+
+// NewRecordingHandler wraps inner and reports every exchange to sink once
+// inner returns. Authorization and Signature headers are redacted; the
+// request and response seen by inner and the client are left untouched.
+// Only the part of the request body that inner reads is recorded, and both
+// bodies are cut at [MaxRequestBodyBytes]. Event stream responses are
+// recorded without a body.
+func NewRecordingHandler(inner http.Handler, sink RecordSink) http.Handler {
+	if inner == nil || sink == nil {
+		panic("acp: recording handler requires an inner handler and a sink")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body *recordingBody
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &recordingBody{ReadCloser: r.Body}
+			r.Body = body
+		}
+		rw := &recordingWriter{ResponseWriter: w}
+		inner.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		header := rw.header
+		if header == nil {
+			header = w.Header().Clone()
+		}
+		var requestBody []byte
+		if body != nil {
+			requestBody = body.buf.Bytes()
+		}
+		sink.Record(r.Context(), Recording{
+			Method:         r.Method,
+			Path:           r.URL.RequestURI(),
+			RequestHeader:  redactHeaders(r.Header),
+			RequestBody:    requestBody,
+			StatusCode:     status,
+			ResponseHeader: header,
+			ResponseBody:   rw.body.Bytes(),
+		})
+	})
+}
+
+func redactHeaders(header http.Header) http.Header {
+	out := header.Clone()
+	for _, name := range redactedHeaders {
+		if len(out.Values(name)) > 0 {
+			out.Set(name, redactedHeaderValue)
+		}
+	}
+	return out
+}
+
+// recordingBody tees the request body into buf as inner reads it, so the
+// body limits and Expect: 100-continue handling of inner still apply.
+type recordingBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	capture(&b.buf, p[:n])
+	return n, err
+}
+
+// capture appends p to buf up to [MaxRequestBodyBytes].
+func capture(buf *bytes.Buffer, p []byte) {
+	if room := MaxRequestBodyBytes - buf.Len(); room > 0 {
+		buf.Write(p[:min(len(p), room)])
+	}
+}
+
+// recordingWriter tees the response into a buffer and snapshots the headers
+// when they are sent.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+	stream bool
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.Header().Clone()
+		w.stream = strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.stream {
+		capture(&w.body, b)
+	}
+	return w.ResponseWriter.Write(b)
+}
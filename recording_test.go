@@ -0,0 +1,115 @@
+package acp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewRecordingHandler(t *testing.T) {
+	t.Parallel()
+
+	const body = `{"items":[{"id":"sku_1","quantity":1}]}`
+	var (
+		mu         sync.Mutex
+		recordings []Recording
+	)
+	sink := RecordSinkFunc(func(ctx context.Context, recording Recording) {
+		mu.Lock()
+		defer mu.Unlock()
+		recordings = append(recordings, recording)
+	})
+	handler := NewRecordingHandler(NewCheckoutHandler(&stubService{
+		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+			return &CheckoutSession{ID: "cs_123"}, nil
+		},
+	}), sink)
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer api_key_123")
+	req.Header.Set("Signature", "c2lnbmF0dXJl")
+	req.Header.Set("Idempotency-Key", "idem_1")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(recordings) != 1 {
+		t.Fatalf("expected 1 recording got %d", len(recordings))
+	}
+	got := recordings[0]
+	if got.Method != http.MethodPost || got.Path != "/checkout_sessions" {
+		t.Fatalf("unexpected request line %s %s", got.Method, got.Path)
+	}
+	if string(got.RequestBody) != body {
+		t.Fatalf("expected request body %s got %s", body, got.RequestBody)
+	}
+	for _, name := range []string{"Authorization", "Signature"} {
+		if value := got.RequestHeader.Get(name); value != redactedHeaderValue {
+			t.Fatalf("expected %s to be redacted got %q", name, value)
+		}
+	}
+	if value := got.RequestHeader.Get("Idempotency-Key"); value != "idem_1" {
+		t.Fatalf("expected Idempotency-Key to be kept got %q", value)
+	}
+	if req.Header.Get("Authorization") != "Bearer api_key_123" {
+		t.Fatalf("expected the original request headers to be untouched")
+	}
+	if got.StatusCode != http.StatusCreated {
+		t.Fatalf("expected recorded status 201 got %d", got.StatusCode)
+	}
+	if got.ResponseHeader.Get("API-Version") != APIVersion {
+		t.Fatalf("expected recorded API-Version header")
+	}
+	if string(got.ResponseBody) != rec.Body.String() {
+		t.Fatalf("expected recorded response %s got %s", rec.Body.String(), got.ResponseBody)
+	}
+}
+
+func TestNewRecordingHandlerCapsBodies(t *testing.T) {
+	t.Parallel()
+
+	var got Recording
+	sink := RecordSinkFunc(func(ctx context.Context, recording Recording) {
+		got = recording
+	})
+
+	t.Run("oversized request", func(t *testing.T) {
+		handler := NewRecordingHandler(NewCheckoutHandler(&stubService{}), sink)
+		body := `{"items":[{"id":"` + strings.Repeat("x", 2*MaxRequestBodyBytes) + `","quantity":1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413 got %d", rec.Code)
+		}
+		if len(got.RequestBody) > MaxRequestBodyBytes {
+			t.Fatalf("expected recorded request body of at most %d bytes got %d", MaxRequestBodyBytes, len(got.RequestBody))
+		}
+	})
+
+	t.Run("event stream", func(t *testing.T) {
+		handler := NewRecordingHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("event: checkout_session.updated\ndata: {}\n\n"))
+		}), sink)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123/events", nil))
+
+		if rec.Body.Len() == 0 {
+			t.Fatalf("expected the event to reach the client")
+		}
+		if len(got.ResponseBody) != 0 {
+			t.Fatalf("expected event stream body not to be recorded got %q", got.ResponseBody)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package acp
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoverMiddleware turns panics in later middleware and providers into a
+// generic [ProcessingError] 500 response and logs them with logger, or
+// slog.Default when logger is nil. Handlers install it by default as the
+// outermost middleware; see [WithPanicRecovery]. [http.ErrAbortHandler] is
+// re-panicked so net/http can abort the response as intended.
+func RecoverMiddleware(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoverWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+				logger.ErrorContext(r.Context(), "acp: panic serving request",
+					slog.Any("panic", rec),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if !rw.wroteHeader {
+					writeJSONError(rw, NewProcessingError("internal server error"))
+				}
+			}()
+			next(rw, r)
+		}
+	}
+}
+
+// recoverWriter tracks whether the response was started, after which a
+// recovered panic can no longer send an error payload.
+type recoverWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recoverWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoverWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,72 @@
+package acp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	handler := NewCheckoutHandler(&stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			panic("provider bug")
+		},
+	}, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 got %d", rec.Code)
+	}
+	if code := getErrorCode(rec.Body.Bytes()); code != string(ProcessingError) {
+		t.Fatalf("expected error code %s got %s", ProcessingError, code)
+	}
+	if strings.Contains(rec.Body.String(), "provider bug") {
+		t.Fatalf("expected panic value to stay out of the response got %s", rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "provider bug") {
+		t.Fatalf("expected panic to be logged got %q", logs.String())
+	}
+}
+
+func TestRecoverMiddlewareRepanicsAbortHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCheckoutHandler(&stubService{
+		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+			panic(http.ErrAbortHandler)
+		},
+	}, WithLogger(slog.New(slog.DiscardHandler)))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to propagate got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+}
+
+func TestWithPanicRecoveryDisabled(t *testing.T) {
+	t.Parallel()
+
+	handler := NewDelegatedPaymentHandler(&delegatedStubService{
+		delegate: func(ctx context.Context, req PaymentRequest) (*VaultToken, error) {
+			panic("provider bug")
+		},
+	}, WithPanicRecovery(false))
+
+	defer func() {
+		if rec := recover(); rec != "provider bug" {
+			t.Fatalf("expected panic to propagate got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), newDelegatePaymentHTTPRequest(t))
+}
@@ -39,6 +39,12 @@ type RequestContext struct {
 	//
 	// Example: 2025-09-12
 	APIVersion string
+	// Outcome of the signature check; read it with [SignatureStatusFromContext].
+	SignatureStatus SignatureStatus
+	// Entity tags the checkout session must match, see [AssertIfMatch]
+	//
+	// Example: "v42"
+	IfMatch string
 }
 
 func requestContextFromRequest(r *http.Request) *RequestContext {
@@ -51,6 +57,19 @@ func requestContextFromRequest(r *http.Request) *RequestContext {
 		Signature:      strings.TrimSpace(r.Header.Get("Signature")),
 		Timestamp:      strings.TrimSpace(r.Header.Get("Timestamp")),
 		APIVersion:     strings.TrimSpace(r.Header.Get("API-Version")),
+		IfMatch:        strings.TrimSpace(r.Header.Get("If-Match")),
+	}
+}
+
+// echoRequestMetadata reflects the correlation headers of the request on the
+// response. It runs before routing so every response, errors included, carries them.
+func echoRequestMetadata(w http.ResponseWriter, requestCtx *RequestContext) {
+	header := w.Header()
+	if requestCtx.RequestID != "" {
+		header.Set("Request-Id", requestCtx.RequestID)
+	}
+	if requestCtx.IdempotencyKey != "" {
+		header.Set("Idempotency-Key", requestCtx.IdempotencyKey)
 	}
 }
 
@@ -76,3 +95,24 @@ func RequestContextFromContext(ctx context.Context) *RequestContext {
 	}
 	return nil
 }
+
+type responseHeaderKey struct{}
+
+// ResponseHeaderFromContext returns the header map of the response being
+// written for the request, letting providers set headers such as
+// Cache-Control or tracing headers from their methods. The headers are sent
+// with the response, errors included, but the handlers always set
+// Content-Type and API-Version themselves, so those cannot be overridden.
+// Outside of a handler it returns an empty, detached header.
+func ResponseHeaderFromContext(ctx context.Context) http.Header {
+	if ctx != nil {
+		if header, ok := ctx.Value(responseHeaderKey{}).(http.Header); ok {
+			return header
+		}
+	}
+	return http.Header{}
+}
+
+func contextWithResponseHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, header)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -67,3 +68,122 @@ func TestRequestContextRoundTrip(t *testing.T) {
 		t.Fatalf("expected nil when request context not set")
 	}
 }
+
+func TestHandlersEchoRequestMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		handler http.Handler
+		method  string
+		path    string
+		body    string
+	}{
+		"checkout success": {
+			handler: NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: id}, nil
+				},
+			}),
+			method: http.MethodGet,
+			path:   "/checkout_sessions/cs_123",
+		},
+		"checkout validation error": {
+			handler: NewCheckoutHandler(&stubService{}),
+			method:  http.MethodPost,
+			path:    "/checkout_sessions",
+			body:    `{"items":[]}`,
+		},
+		"delegated payment error": {
+			handler: NewDelegatedPaymentHandler(&delegatedStubService{}),
+			method:  http.MethodPost,
+			path:    "/agentic_commerce/delegate_payment",
+			body:    `{}`,
+		},
+		"unknown route": {
+			handler: NewCheckoutHandler(&stubService{}),
+			method:  http.MethodGet,
+			path:    "/unknown",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Request-Id", "req_123")
+			req.Header.Set("Idempotency-Key", "idem_123")
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Request-Id"); got != "req_123" {
+				t.Fatalf("expected Request-Id req_123 got %q (status %d)", got, rec.Code)
+			}
+			if got := rec.Header().Get("Idempotency-Key"); got != "idem_123" {
+				t.Fatalf("expected Idempotency-Key idem_123 got %q (status %d)", got, rec.Code)
+			}
+		})
+	}
+}
+
+func TestResponseHeaderFromContext(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err        error
+		wantStatus int
+	}{
+		"success": {wantStatus: http.StatusOK},
+		"error":   {err: NewInvalidRequestError("bad"), wantStatus: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					header := ResponseHeaderFromContext(ctx)
+					header.Set("Cache-Control", "no-store")
+					header.Set("X-Trace-Id", "trace-123")
+					header.Set("API-Version", "1999-01-01")
+					header.Set("Content-Type", "text/plain")
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &CheckoutSession{ID: id}, nil
+				},
+			})
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Fatalf("expected Cache-Control no-store got %q", got)
+			}
+			if got := rec.Header().Get("X-Trace-Id"); got != "trace-123" {
+				t.Fatalf("expected X-Trace-Id trace-123 got %q", got)
+			}
+			if got := rec.Header().Get("API-Version"); got != APIVersion {
+				t.Fatalf("expected API-Version %s got %q", APIVersion, got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Fatalf("expected JSON content type got %q", got)
+			}
+		})
+	}
+}
+
+func TestResponseHeaderFromContextOutsideHandler(t *testing.T) {
+	t.Parallel()
+
+	header := ResponseHeaderFromContext(context.Background())
+	if header == nil {
+		t.Fatalf("expected detached header")
+	}
+	header.Set("X-Test", "ok")
+}
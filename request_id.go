@@ -0,0 +1,71 @@
+package acp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDGenerator mints the Request-Id of requests that arrive without
+// one, letting deployments pick the id format their tracing expects, such
+// as ULIDs or UUIDv7. Implementations must be safe for concurrent use.
+type RequestIDGenerator interface {
+	NewRequestID() string
+}
+
+// UUIDRequestIDGenerator is the default [RequestIDGenerator]. It returns
+// random (version 4) UUIDs.
+type UUIDRequestIDGenerator struct{}
+
+// NewRequestID returns a new random UUID.
+func (UUIDRequestIDGenerator) NewRequestID() string {
+	return uuid.NewString()
+}
+
+// WithRequestIDGenerator sets the generator [RequestIDMiddleware] uses for
+// requests without a Request-Id header. Defaults to [UUIDRequestIDGenerator].
+func WithRequestIDGenerator(generator RequestIDGenerator) Option {
+	if generator == nil {
+		return invalidOption(errors.New("acp: request id generator is required"))
+	}
+	return func(cfg *config) {
+		cfg.requestIDGenerator = generator
+	}
+}
+
+type requestIDGeneratorKey struct{}
+
+func contextWithRequestIDGenerator(ctx context.Context, generator RequestIDGenerator) context.Context {
+	return context.WithValue(ctx, requestIDGeneratorKey{}, generator)
+}
+
+func requestIDGeneratorFromContext(ctx context.Context) RequestIDGenerator {
+	if generator, ok := ctx.Value(requestIDGeneratorKey{}).(RequestIDGenerator); ok {
+		return generator
+	}
+	return UUIDRequestIDGenerator{}
+}
+
+// RequestIDMiddleware propagates the Request-Id header: the client's value is
+// kept, one is generated with the [WithRequestIDGenerator] generator when it
+// is absent, and the result is stored in [RequestContext] and echoed on every
+// response, including errors. Install it with [WithMiddleware].
+func RequestIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get("Request-Id"))
+		if id == "" {
+			id = requestIDGeneratorFromContext(r.Context()).NewRequestID()
+		}
+		requestCtx := RequestContextFromContext(r.Context())
+		if requestCtx == nil {
+			requestCtx = requestContextFromRequest(r)
+			r = r.WithContext(contextWithRequestContext(r.Context(), requestCtx))
+		}
+		requestCtx.RequestID = id
+		w.Header().Set("Request-Id", id)
+		next(w, r)
+	}
+}
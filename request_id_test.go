@@ -0,0 +1,127 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		requestID  string
+		body       string
+		wantStatus int
+	}{
+		"client request id on success": {
+			requestID:  "req_123",
+			body:       `{"items":[{"id":"sku_1","quantity":1}]}`,
+			wantStatus: http.StatusCreated,
+		},
+		"generated request id on success": {
+			body:       `{"items":[{"id":"sku_1","quantity":1}]}`,
+			wantStatus: http.StatusCreated,
+		},
+		"client request id on error": {
+			requestID:  "req_456",
+			body:       `{"items":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var seen string
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					seen = RequestContextFromContext(ctx).RequestID
+					return &CheckoutSession{ID: "cs_123"}, nil
+				},
+			}, WithMiddleware(RequestIDMiddleware))
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", strings.NewReader(tt.body))
+			if tt.requestID != "" {
+				req.Header.Set("Request-Id", tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			got := rec.Header().Get("Request-Id")
+			if tt.requestID != "" && got != tt.requestID {
+				t.Fatalf("expected Request-Id %q got %q", tt.requestID, got)
+			}
+			if tt.requestID == "" {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Fatalf("expected generated UUID Request-Id got %q", got)
+				}
+			}
+			if tt.wantStatus == http.StatusCreated && seen != got {
+				t.Fatalf("expected RequestContext.RequestID %q got %q", got, seen)
+			}
+		})
+	}
+}
+
+type sequenceRequestIDs struct{ next atomic.Int64 }
+
+func (g *sequenceRequestIDs) NewRequestID() string {
+	return fmt.Sprintf("req_%d", g.next.Add(1))
+}
+
+func TestWithRequestIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	generator := &sequenceRequestIDs{}
+	handlers := map[string]struct {
+		handler http.Handler
+		method  string
+		path    string
+	}{
+		"checkout": {
+			handler: NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: id}, nil
+				},
+			}, WithMiddleware(RequestIDMiddleware), WithRequestIDGenerator(generator)),
+			method: http.MethodGet,
+			path:   "/checkout_sessions/cs_123",
+		},
+		"delegated payment": {
+			handler: NewDelegatedPaymentHandler(successService(), WithMiddleware(RequestIDMiddleware), WithRequestIDGenerator(generator)),
+			method:  http.MethodPost,
+			path:    "/agentic_commerce/delegate_payment",
+		},
+	}
+
+	for name, tt := range handlers {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`)))
+			if got := rec.Header().Get("Request-Id"); !strings.HasPrefix(got, "req_") {
+				t.Fatalf("expected generated Request-Id got %q", got)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			req.Header.Set("Request-Id", "client_1")
+			rec = httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if got := rec.Header().Get("Request-Id"); got != "client_1" {
+				t.Fatalf("expected client Request-Id got %q", got)
+			}
+		})
+	}
+}
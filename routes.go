@@ -0,0 +1,47 @@
+package acp
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Route is a method and ServeMux path pattern served by a handler, such as
+// POST /checkout_sessions/{id}/complete.
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// String returns the route in ServeMux pattern syntax.
+func (r Route) String() string {
+	return r.Method + " " + r.Pattern
+}
+
+// handleRoute registers fn on mux and records the route in routes. The
+// trailing-slash variant of the pattern is served by fn as well, without a
+// redirect, so that request bodies and signed paths reach it unchanged; only
+// the canonical pattern is recorded, and fn sees it as r.Pattern either way
+// so that middleware keyed on the pattern, such as [RateLimitMiddleware],
+// cannot be bypassed with a trailing slash.
+func handleRoute(mux *http.ServeMux, routes *[]Route, method, pattern string, fn http.HandlerFunc) {
+	route := Route{Method: method, Pattern: pattern}
+	canonical := route.String()
+	mux.HandleFunc(canonical, fn)
+	mux.HandleFunc(canonical+"/{$}", func(w http.ResponseWriter, r *http.Request) {
+		r.Pattern = canonical
+		fn(w, r)
+	})
+	*routes = append(*routes, route)
+}
+
+// Routes lists the routes the handler serves in registration order,
+// including those enabled by optional [CheckoutProvider] capabilities.
+func (h *CheckoutHandler) Routes() []Route {
+	return slices.Clone(h.routes)
+}
+
+// Routes lists the routes the handler serves in registration order,
+// including those enabled by optional [DelegatedPaymentProvider] capabilities.
+func (h *DelegatedPaymentHandler) Routes() []Route {
+	return slices.Clone(h.routes)
+}
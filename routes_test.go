@@ -0,0 +1,140 @@
+package acp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckoutHandlerRoutesList(t *testing.T) {
+	t.Parallel()
+
+	want := []Route{
+		{Method: "POST", Pattern: "/checkout_sessions"},
+		{Method: "GET", Pattern: "/checkout_sessions/{id}"},
+		{Method: "POST", Pattern: "/checkout_sessions/{id}"},
+		{Method: "POST", Pattern: "/checkout_sessions/{id}/complete"},
+		{Method: "POST", Pattern: "/checkout_sessions/{id}/cancel"},
+	}
+
+	tests := map[string]struct {
+		service CheckoutProvider
+		want    []Route
+	}{
+		"base provider": {service: &stubService{}, want: want},
+		// Cancellation reasons reuse the cancel route.
+		"reason canceler": {service: &reasonCancelStub{}, want: want},
+		"session subscriber": {
+			service: &subscriberStub{},
+			want:    append(want[:len(want):len(want)], Route{Method: "GET", Pattern: "/checkout_sessions/{id}/events"}),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewCheckoutHandler(tt.service).Routes()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected routes %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDelegatedPaymentHandlerRoutesList(t *testing.T) {
+	t.Parallel()
+
+	want := []Route{{Method: "POST", Pattern: "/agentic_commerce/delegate_payment"}}
+
+	tests := map[string]struct {
+		service DelegatedPaymentProvider
+		want    []Route
+	}{
+		"base provider": {service: &delegatedStubService{}, want: want},
+		// Dry runs are served by the delegate payment route.
+		"dry runner": {service: &dryRunStubService{}, want: want},
+		"batcher": {
+			service: &batchStubService{},
+			want:    append(want[:len(want):len(want)], Route{Method: "POST", Pattern: "/agentic_commerce/delegate_payment/batch"}),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewDelegatedPaymentHandler(tt.service).Routes()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected routes %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRoutesReturnsCopy(t *testing.T) {
+	t.Parallel()
+
+	handler := NewCheckoutHandler(&stubService{})
+	handler.Routes()[0].Pattern = "/changed"
+	if got := handler.Routes()[0].Pattern; got != "/checkout_sessions" {
+		t.Fatalf("expected routes to be unaffected by callers got %q", got)
+	}
+}
+
+func TestRoutesAcceptTrailingSlash(t *testing.T) {
+	t.Parallel()
+
+	// probe answers from inside the route, after the mux matched it.
+	probe := WithMiddleware(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Route", r.Pattern)
+			w.Header().Set("X-ID", r.PathValue("id"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	handlers := map[string]interface {
+		http.Handler
+		Routes() []Route
+	}{
+		"checkout":          NewCheckoutHandler(&subscriberStub{}, probe),
+		"delegated payment": NewDelegatedPaymentHandler(&batchStubService{}, probe),
+	}
+
+	for name, handler := range handlers {
+		for _, route := range handler.Routes() {
+			path := strings.ReplaceAll(route.Pattern, "{id}", "cs_123")
+			for _, target := range []string{path, path + "/"} {
+				t.Run(name+" "+route.Method+" "+target, func(t *testing.T) {
+					t.Parallel()
+
+					rec := httptest.NewRecorder()
+					handler.ServeHTTP(rec, httptest.NewRequest(route.Method, target, nil))
+
+					if rec.Code != http.StatusNoContent {
+						t.Fatalf("expected 204 got %d body=%s", rec.Code, rec.Body.String())
+					}
+					if got := rec.Header().Get("X-Route"); got != route.String() {
+						t.Fatalf("expected route %s got %s", route, got)
+					}
+					if strings.Contains(route.Pattern, "{id}") && rec.Header().Get("X-ID") != "cs_123" {
+						t.Fatalf("expected id cs_123 got %q", rec.Header().Get("X-ID"))
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRoutesTrailingSlashKeepsUnknownPathsNotFound(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	NewCheckoutHandler(&stubService{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout_sessions/cs_123/complete/extra/", nil))
+
+	if rec.Code != http.StatusNotFound || getErrorCode(rec.Body.Bytes()) != string(NotFound) {
+		t.Fatalf("expected ACP 404 got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,6 +1,10 @@
 package acp
 
 import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,11 +13,47 @@ import (
 	"github.com/sumup/acp/signature"
 )
 
+// SignatureFormat selects the headers signed requests carry.
+type SignatureFormat int
+
+const (
+	// SignatureFormatTimestamp expects Signature and Timestamp headers
+	// covering the canonical JSON body (see [signature.BuildSigningPayload]).
+	SignatureFormatTimestamp SignatureFormat = iota
+	// SignatureFormatHTTPMessage expects RFC 9421 Signature-Input and
+	// Signature headers. The created parameter is required and checked
+	// against [WithMaxClockSkew], an expires parameter must lie in the
+	// future, and signatures must cover @method and @path or @target-uri.
+	// Requests with a body must also cover a Content-Digest header (RFC 9530)
+	// matching it. Verifiers receive the
+	// signature base through [signature.Material.SigningString] and the
+	// keyid in [signature.Material.KeyID].
+	SignatureFormatHTTPMessage
+)
+
+// WithSignatureFormat selects how [WithSignatureVerifier] reads signatures.
+// Defaults to [SignatureFormatTimestamp].
+func WithSignatureFormat(format SignatureFormat) Option {
+	switch format {
+	case SignatureFormatTimestamp, SignatureFormatHTTPMessage:
+	default:
+		return invalidOption(fmt.Errorf("acp: unknown signature format %d", format))
+	}
+	return func(cfg *config) {
+		cfg.signatureFormat = format
+	}
+}
+
 type signatureMiddlewareConfig struct {
 	Verifier      signature.Verifier
 	RequireSigned bool
 	MaxClockSkew  time.Duration
 	Clock         func() time.Time
+	SignedHeaders []string
+	Format        SignatureFormat
+	// ParseTimestamp parses the Timestamp header; defaults to
+	// [signature.ParseTimestamp].
+	ParseTimestamp func(string) (time.Time, error)
 }
 
 func newSignatureMiddleware(cfg signatureMiddlewareConfig) func(http.HandlerFunc) http.HandlerFunc {
@@ -23,6 +63,13 @@ func newSignatureMiddleware(cfg signatureMiddlewareConfig) func(http.HandlerFunc
 	if cfg.Clock == nil {
 		cfg.Clock = time.Now
 	}
+	if cfg.ParseTimestamp == nil {
+		cfg.ParseTimestamp = signature.ParseTimestamp
+	}
+	readMaterial := cfg.timestampMaterial
+	if cfg.Format == SignatureFormatHTTPMessage {
+		readMaterial = cfg.messageMaterial
+	}
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			verifier := cfg.Verifier
@@ -30,57 +77,202 @@ func newSignatureMiddleware(cfg signatureMiddlewareConfig) func(http.HandlerFunc
 				next(w, r)
 				return
 			}
-			sig := strings.TrimSpace(r.Header.Get("Signature"))
-			timestampHeader := strings.TrimSpace(r.Header.Get("Timestamp"))
-			if sig == "" && timestampHeader == "" {
+			material, signed, errPayload := readMaterial(r)
+			if errPayload != nil {
+				setSignatureStatus(r.Context(), SignatureStatusFailed)
+				writeJSONError(w, errPayload)
+				return
+			}
+			if !signed {
+				setSignatureStatus(r.Context(), SignatureStatusUnsigned)
 				if cfg.RequireSigned {
-					writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, SignatureRequired, "Signature and Timestamp headers are required"))
+					writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, SignatureRequired, "signature headers are required"))
 					return
 				}
 				next(w, r)
 				return
 			}
-			if sig == "" || timestampHeader == "" {
-				writeJSONError(w, NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidSignature, "Signature and Timestamp headers must both be provided"))
-				return
-			}
-			ts, err := signature.ParseTimestamp(timestampHeader)
-			if err != nil {
-				writeJSONError(w, NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidSignature, "Timestamp must be RFC3339"))
-				return
-			}
-			ts = ts.UTC()
-			if cfg.MaxClockSkew > 0 {
-				skew := signature.AbsDuration(cfg.Clock().Sub(ts))
-				if skew > cfg.MaxClockSkew {
-					writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, StaleTimestamp, fmt.Sprintf("timestamp skew exceeds %s", cfg.MaxClockSkew)))
-					return
+			if err := verifier.Verify(r.Context(), material); err != nil {
+				setSignatureStatus(r.Context(), SignatureStatusFailed)
+				message := "signature verification failed"
+				if errors.Is(err, signature.ErrUnsupportedAlgorithm) {
+					algorithm := cmp.Or(material.Algorithm, signature.AlgorithmHMACSHA256)
+					message = fmt.Sprintf("signature algorithm %q is not supported", algorithm)
 				}
-			}
-			raw, err := signature.ReadAndBufferBody(r)
-			if err != nil {
-				writeJSONError(w, NewInvalidRequestError("unable to read request body"))
-				return
-			}
-			canonicalBody, err := signature.CanonicalizeJSONBody(raw)
-			if err != nil {
-				writeJSONError(w, NewInvalidRequestError("request body must be valid JSON"))
+				writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidSignature, message))
 				return
 			}
-			material := signature.Material{
-				Signature:     sig,
-				Timestamp:     ts,
-				CanonicalBody: canonicalBody,
-				Method:        r.Method,
-				Path:          r.URL.Path,
-				RawQuery:      r.URL.RawQuery,
-				Headers:       r.Header.Clone(),
-			}
-			if err := verifier.Verify(r.Context(), material); err != nil {
-				writeJSONError(w, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidSignature, "signature verification failed"))
-				return
+			setSignatureStatus(r.Context(), SignatureStatusVerified)
+			if material.CanonicalBody != nil {
+				r = r.WithContext(context.WithValue(r.Context(), canonicalBodyKey{}, material.CanonicalBody))
 			}
 			next(w, r)
 		}
 	}
 }
+
+// SignatureStatus is the outcome of the [WithSignatureVerifier] check of a
+// request.
+type SignatureStatus string
+
+const (
+	// SignatureStatusUnsigned marks requests without signature headers, and
+	// every request when no verifier is configured.
+	SignatureStatusUnsigned SignatureStatus = "unsigned"
+	// SignatureStatusVerified marks requests whose signature was verified.
+	SignatureStatusVerified SignatureStatus = "verified"
+	// SignatureStatusFailed marks requests rejected for a malformed, stale or
+	// invalid signature. They never reach the provider, but middleware
+	// installed with [WithMiddleware] and [WithErrorHook] can observe it.
+	SignatureStatusFailed SignatureStatus = "failed"
+)
+
+// SignatureStatusFromContext returns the [SignatureStatus] of the request,
+// letting providers apply stricter rules to unsigned requests (for example
+// refusing to mint tokens) when [WithRequireSignedRequests] is not set.
+func SignatureStatusFromContext(ctx context.Context) SignatureStatus {
+	if requestCtx := RequestContextFromContext(ctx); requestCtx != nil && requestCtx.SignatureStatus != "" {
+		return requestCtx.SignatureStatus
+	}
+	return SignatureStatusUnsigned
+}
+
+func setSignatureStatus(ctx context.Context, status SignatureStatus) {
+	if requestCtx := RequestContextFromContext(ctx); requestCtx != nil {
+		requestCtx.SignatureStatus = status
+	}
+}
+
+type canonicalBodyKey struct{}
+
+// CanonicalBodyFromContext returns the canonical JSON body of a request whose
+// signature was verified (see [signature.Material.CanonicalBody]), so
+// providers can keep an audit trail, for instance of
+// [signature.Material.BodyHash], without reading the body again. It reports
+// false for unsigned requests and for message signatures over a body that
+// is not JSON.
+func CanonicalBodyFromContext(ctx context.Context) ([]byte, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	body, ok := ctx.Value(canonicalBodyKey{}).([]byte)
+	return body, ok
+}
+
+// checkSkew rejects timestamps further than MaxClockSkew from the clock.
+func (cfg signatureMiddlewareConfig) checkSkew(ts time.Time) *Error {
+	if cfg.MaxClockSkew > 0 && signature.AbsDuration(cfg.Clock().Sub(ts)) > cfg.MaxClockSkew {
+		return NewHTTPError(http.StatusUnauthorized, InvalidRequest, StaleTimestamp, fmt.Sprintf("timestamp skew exceeds %s", cfg.MaxClockSkew))
+	}
+	return nil
+}
+
+// timestampMaterial reads a [SignatureFormatTimestamp] signature. It reports
+// signed=false when neither header is present.
+func (cfg signatureMiddlewareConfig) timestampMaterial(r *http.Request) (signature.Material, bool, *Error) {
+	sig := strings.TrimSpace(r.Header.Get("Signature"))
+	timestampHeader := strings.TrimSpace(r.Header.Get("Timestamp"))
+	if sig == "" && timestampHeader == "" {
+		return signature.Material{}, false, nil
+	}
+	if sig == "" || timestampHeader == "" {
+		return signature.Material{}, false, NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidSignature, "Signature and Timestamp headers must both be provided")
+	}
+	ts, err := cfg.ParseTimestamp(timestampHeader)
+	if err != nil {
+		return signature.Material{}, false, NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidSignature, "Timestamp is invalid")
+	}
+	ts = ts.UTC()
+	if errPayload := cfg.checkSkew(ts); errPayload != nil {
+		return signature.Material{}, false, errPayload
+	}
+	raw, err := signature.ReadAndBufferBody(r)
+	if err != nil {
+		return signature.Material{}, false, readBodyError(err)
+	}
+	canonicalBody, err := signature.CanonicalizeJSONBody(raw)
+	if err != nil {
+		return signature.Material{}, false, NewInvalidRequestError("request body must be valid JSON")
+	}
+	return signature.Material{
+		Signature:     sig,
+		Timestamp:     ts,
+		CanonicalBody: canonicalBody,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		RawQuery:      r.URL.RawQuery,
+		Headers:       r.Header.Clone(),
+		SignedHeaders: cfg.SignedHeaders,
+		Algorithm:     strings.TrimSpace(r.Header.Get("Signature-Algorithm")),
+	}, true, nil
+}
+
+// messageMaterial reads a [SignatureFormatHTTPMessage] signature. It reports
+// signed=false when neither header is present.
+func (cfg signatureMiddlewareConfig) messageMaterial(r *http.Request) (signature.Material, bool, *Error) {
+	if r.Header.Get("Signature-Input") == "" && r.Header.Get("Signature") == "" {
+		return signature.Material{}, false, nil
+	}
+	invalid := func(message string) *Error {
+		return NewHTTPError(http.StatusBadRequest, InvalidRequest, InvalidSignature, message)
+	}
+	sig, err := signature.ParseMessageSignature(r.Header)
+	if err != nil {
+		return signature.Material{}, false, invalid(err.Error())
+	}
+	if sig.Created.IsZero() {
+		return signature.Material{}, false, invalid("Signature-Input must include the created parameter")
+	}
+	if !sig.Covers("@method") || !sig.Covers("@path") && !sig.Covers("@target-uri") {
+		return signature.Material{}, false, invalid("signatures must cover @method and @path or @target-uri")
+	}
+	if errPayload := cfg.checkSkew(sig.Created); errPayload != nil {
+		return signature.Material{}, false, errPayload
+	}
+	if !sig.Expires.IsZero() && !cfg.Clock().Before(sig.Expires) {
+		return signature.Material{}, false, NewHTTPError(http.StatusUnauthorized, InvalidRequest, StaleTimestamp, "signature has expired")
+	}
+	raw, err := signature.ReadAndBufferBody(r)
+	if err != nil {
+		return signature.Material{}, false, readBodyError(err)
+	}
+	if len(raw) > 0 {
+		if !sig.Covers("content-digest") {
+			return signature.Material{}, false, invalid("signatures of requests with a body must cover content-digest")
+		}
+		if err := signature.VerifyContentDigest(r.Header.Get("Content-Digest"), raw); err != nil {
+			return signature.Material{}, false, NewHTTPError(http.StatusUnauthorized, InvalidRequest, InvalidSignature, "Content-Digest does not match the request body")
+		}
+	}
+	base, err := sig.SignatureBase(r)
+	if err != nil {
+		return signature.Material{}, false, invalid(err.Error())
+	}
+	var canonicalBody []byte
+	if len(raw) > 0 {
+		// The body is covered by Content-Digest rather than canonicalized for
+		// signing; the canonical form only serves CanonicalBodyFromContext.
+		canonicalBody, _ = signature.CanonicalizeJSONBody(raw)
+	}
+	return signature.Material{
+		Signature:     base64.RawURLEncoding.EncodeToString(sig.Signature),
+		Timestamp:     sig.Created,
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		RawQuery:      r.URL.RawQuery,
+		Headers:       r.Header.Clone(),
+		CanonicalBody: canonicalBody,
+		KeyID:         sig.KeyID,
+		SignatureBase: base,
+		Algorithm:     sig.Alg,
+	}, true, nil
+}
+
+// readBodyError reports a failure to buffer the body for verification.
+func readBodyError(err error) *Error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return requestTooLarge(maxErr.Limit)
+	}
+	return NewInvalidRequestError("unable to read request body")
+}
@@ -0,0 +1,64 @@
+package signature
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Signature algorithm names, as sent in the Signature-Algorithm header or the
+// alg parameter of an RFC 9421 message signature.
+const (
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	AlgorithmEd25519    = "ed25519"
+)
+
+// ErrUnsupportedAlgorithm is returned by [AlgorithmVerifiers] when no
+// verifier is registered for the algorithm a client declared.
+var ErrUnsupportedAlgorithm = errors.New("signature: unsupported algorithm")
+
+// AlgorithmVerifiers dispatches to the verifier registered for the
+// algorithm declared by the client in [Material.Algorithm]. Requests that
+// declare none are verified as [AlgorithmHMACSHA256], matching clients that
+// predate the Signature-Algorithm header. Keys are matched case-insensitively.
+type AlgorithmVerifiers map[string]Verifier
+
+// Verify implements [Verifier]. It wraps [ErrUnsupportedAlgorithm] when the
+// declared algorithm is not registered.
+func (v AlgorithmVerifiers) Verify(ctx context.Context, material Material) error {
+	algorithm := strings.ToLower(material.Algorithm)
+	if algorithm == "" {
+		algorithm = AlgorithmHMACSHA256
+	}
+	for name, verifier := range v {
+		if strings.ToLower(name) == algorithm && verifier != nil {
+			return verifier.Verify(ctx, material)
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnsupportedAlgorithm, algorithm)
+}
+
+// Ed25519Verifier validates base64url-encoded Ed25519 signatures over
+// [Material.SigningString].
+type Ed25519Verifier struct {
+	PublicKey ed25519.PublicKey
+	// Encoding of the signature; defaults to [EncodingRawURL].
+	Encoding Encoding
+}
+
+// Verify implements [Verifier] by checking the signature against PublicKey.
+func (v Ed25519Verifier) Verify(_ context.Context, material Material) error {
+	if len(v.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("signature: Ed25519Verifier requires a valid public key")
+	}
+	decoded, err := v.Encoding.DecodeString(material.Signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(v.PublicKey, material.SigningString(), decoded) {
+		return errors.New("signature: invalid signature")
+	}
+	return nil
+}
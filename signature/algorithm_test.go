@@ -0,0 +1,67 @@
+package signature
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlgorithmVerifiers(t *testing.T) {
+	t.Parallel()
+
+	hmacKey := []byte("secret")
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	material := Material{
+		Timestamp:     time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+		CanonicalBody: []byte(`{"id":"cs_123"}`),
+	}
+	mac := hmac.New(sha256.New, hmacKey)
+	_, _ = mac.Write(material.SigningString())
+	hmacSignature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	ed25519Signature := base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, material.SigningString()))
+
+	verifiers := AlgorithmVerifiers{
+		AlgorithmHMACSHA256: HMACVerifier{Key: hmacKey},
+		AlgorithmEd25519:    Ed25519Verifier{PublicKey: publicKey},
+	}
+
+	tests := map[string]struct {
+		verifiers       AlgorithmVerifiers
+		algorithm       string
+		signature       string
+		wantErr         bool
+		wantUnsupported bool
+	}{
+		"default hmac":           {verifiers: verifiers, signature: hmacSignature},
+		"declared hmac":          {verifiers: verifiers, algorithm: "HMAC-SHA256", signature: hmacSignature},
+		"declared ed25519":       {verifiers: verifiers, algorithm: "ed25519", signature: ed25519Signature},
+		"wrong algorithm":        {verifiers: verifiers, algorithm: "ed25519", signature: hmacSignature, wantErr: true},
+		"unknown algorithm":      {verifiers: verifiers, algorithm: "rsa-pss-sha512", signature: hmacSignature, wantErr: true, wantUnsupported: true},
+		"default not registered": {verifiers: AlgorithmVerifiers{AlgorithmEd25519: Ed25519Verifier{PublicKey: publicKey}}, signature: hmacSignature, wantErr: true, wantUnsupported: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			material := material
+			material.Algorithm = tt.algorithm
+			material.Signature = tt.signature
+			err := tt.verifiers.Verify(context.Background(), material)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v got %v", tt.wantErr, err)
+			}
+			if got := errors.Is(err, ErrUnsupportedAlgorithm); got != tt.wantUnsupported {
+				t.Fatalf("expected ErrUnsupportedAlgorithm=%v got %v", tt.wantUnsupported, err)
+			}
+		})
+	}
+}
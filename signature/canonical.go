@@ -0,0 +1,157 @@
+package signature
+
+import "bytes"
+
+// isCanonicalJSON reports whether raw is already in the canonical form that
+// [CanonicalizeJSONBody] would produce, so the decode/re-encode round trip can
+// be skipped.
+//
+// The check is deliberately conservative: it only accepts documents without
+// insignificant whitespace, with strictly sorted and unique object keys,
+// integer numbers without leading zeros or a negative zero, and strings made
+// of printable ASCII without escape sequences. Anything else (fractions,
+// exponents, escapes, non-ASCII text) falls back to the slow path, which is
+// always correct. A false negative only costs the re-marshal; a false positive
+// would change the signed bytes, so the rules never guess.
+func isCanonicalJSON(raw []byte) bool {
+	s := canonicalScanner{data: raw}
+	if !s.value() {
+		return false
+	}
+	return s.pos == len(s.data)
+}
+
+type canonicalScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *canonicalScanner) value() bool {
+	if s.pos >= len(s.data) {
+		return false
+	}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		return s.object()
+	case c == '[':
+		return s.array()
+	case c == '"':
+		_, ok := s.string()
+		return ok
+	case c == '-' || (c >= '0' && c <= '9'):
+		return s.number()
+	default:
+		return s.literal("true") || s.literal("false") || s.literal("null")
+	}
+}
+
+func (s *canonicalScanner) object() bool {
+	s.pos++ // '{'
+	if s.consume('}') {
+		return true
+	}
+	var prev []byte
+	for {
+		key, ok := s.string()
+		if !ok {
+			return false
+		}
+		// Keys are ASCII-only here, so byte order equals code point order.
+		if prev != nil && bytes.Compare(prev, key) >= 0 {
+			return false
+		}
+		prev = key
+		if !s.consume(':') || !s.value() {
+			return false
+		}
+		if s.consume('}') {
+			return true
+		}
+		if !s.consume(',') {
+			return false
+		}
+	}
+}
+
+func (s *canonicalScanner) array() bool {
+	s.pos++ // '['
+	if s.consume(']') {
+		return true
+	}
+	for {
+		if !s.value() {
+			return false
+		}
+		if s.consume(']') {
+			return true
+		}
+		if !s.consume(',') {
+			return false
+		}
+	}
+}
+
+func (s *canonicalScanner) string() ([]byte, bool) {
+	if !s.consume('"') {
+		return nil, false
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			return s.data[start : s.pos-1], true
+		case c == '\\' || c < 0x20 || c >= 0x7f:
+			return nil, false
+		}
+		s.pos++
+	}
+	return nil, false
+}
+
+func (s *canonicalScanner) number() bool {
+	start := s.pos
+	s.consume('-')
+	if s.pos >= len(s.data) {
+		return false
+	}
+	if s.data[s.pos] == '0' {
+		s.pos++
+		// Negative zero canonicalizes to 0.
+		if s.pos-start > 1 {
+			return false
+		}
+	} else {
+		digits := s.pos
+		for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+			s.pos++
+		}
+		if s.pos == digits {
+			return false
+		}
+	}
+	if s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '.', 'e', 'E', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			return false
+		}
+	}
+	return true
+}
+
+func (s *canonicalScanner) literal(lit string) bool {
+	if !bytes.HasPrefix(s.data[s.pos:], []byte(lit)) {
+		return false
+	}
+	s.pos += len(lit)
+	return true
+}
+
+func (s *canonicalScanner) consume(c byte) bool {
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
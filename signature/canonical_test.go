@@ -0,0 +1,106 @@
+package signature
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestCanonicalFormGolden(t *testing.T) {
+	t.Parallel()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "canonical", "*.json"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no golden inputs found")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			raw, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("read input: %v", err)
+			}
+			got, err := CanonicalForm(raw)
+			if err != nil {
+				t.Fatalf("CanonicalForm: %v", err)
+			}
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("CanonicalForm mismatch\ngot:  %s\nwant: %s", got, want)
+			}
+			again, err := CanonicalForm(got)
+			if err != nil {
+				t.Fatalf("CanonicalForm(canonical): %v", err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Fatalf("canonical form is not stable\nfirst:  %s\nsecond: %s", got, again)
+			}
+		})
+	}
+}
+
+func TestCanonicalEqual(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"reordered keys and whitespace": {
+			a:    `{"b":1, "a":[1,2]}`,
+			b:    `{"a":[1,2],"b":1}`,
+			want: true,
+		},
+		"equivalent numbers": {
+			a:    `{"amount":1.50}`,
+			b:    `{"amount":15e-1}`,
+			want: true,
+		},
+		"equivalent escapes": {
+			a:    `"café"`,
+			b:    `"café"`,
+			want: true,
+		},
+		"different values": {
+			a: `{"a":1}`,
+			b: `{"a":2}`,
+		},
+		"array order matters": {
+			a: `[1,2]`,
+			b: `[2,1]`,
+		},
+		"invalid JSON": {
+			a: `{"a":1`,
+			b: `{"a":1`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := CanonicalEqual([]byte(tt.a), []byte(tt.b)); got != tt.want {
+				t.Fatalf("CanonicalEqual(%s, %s) = %t want %t", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
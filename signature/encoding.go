@@ -0,0 +1,97 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Encoding names the base64 variant of a Signature header value. It only
+// applies to Timestamp signatures: the middleware always presents the bytes
+// of RFC 9421 message signatures as [EncodingRawURL].
+type Encoding string
+
+const (
+	// EncodingRawURL is unpadded base64url (RFC 4648 section 5), the default.
+	EncodingRawURL Encoding = "raw-url"
+	// EncodingStd is padded standard base64 (RFC 4648 section 4).
+	EncodingStd Encoding = "std"
+	// EncodingRawStd is unpadded standard base64.
+	EncodingRawStd Encoding = "raw-std"
+)
+
+func (e Encoding) base64() (*base64.Encoding, error) {
+	switch e {
+	case "", EncodingRawURL:
+		return base64.RawURLEncoding, nil
+	case EncodingStd:
+		return base64.StdEncoding, nil
+	case EncodingRawStd:
+		return base64.RawStdEncoding, nil
+	}
+	return nil, fmt.Errorf("signature: unsupported signature encoding %q", string(e))
+}
+
+// String returns the name of the encoding, defaulting to raw-url.
+func (e Encoding) String() string {
+	if e == "" {
+		return string(EncodingRawURL)
+	}
+	return string(e)
+}
+
+// EncodeToString encodes a raw signature.
+func (e Encoding) EncodeToString(sig []byte) (string, error) {
+	enc, err := e.base64()
+	if err != nil {
+		return "", err
+	}
+	return enc.EncodeToString(sig), nil
+}
+
+// DecodeString decodes a Signature header value, naming the expected
+// encoding when value is not valid in it.
+func (e Encoding) DecodeString(value string) ([]byte, error) {
+	enc, err := e.base64()
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := enc.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("signature: decode signature: expected %s base64: %w", e, err)
+	}
+	return decoded, nil
+}
+
+// HMACSigner produces the signatures accepted by an [HMACVerifier] with the
+// same Key and Encoding.
+type HMACSigner struct {
+	Key []byte
+	// Encoding of the signature; defaults to [EncodingRawURL].
+	Encoding Encoding
+}
+
+// Sign returns the encoded HMAC-SHA256 of [Material.SigningString].
+func (s HMACSigner) Sign(material Material) (string, error) {
+	if len(s.Key) == 0 {
+		return "", errors.New("signature: HMACSigner requires a non-empty key")
+	}
+	mac := hmac.New(sha256.New, s.Key)
+	_, _ = mac.Write(material.SigningString())
+	return s.Encoding.EncodeToString(mac.Sum(nil))
+}
+
+// ConstantTimeEqualString reports whether a and b, two unpadded base64url
+// signatures ([EncodingRawURL]), hold the same bytes. The decoded bytes are
+// compared with [hmac.Equal], so the time taken does not reveal how many
+// leading bytes match. Values that are not valid base64url never match.
+func ConstantTimeEqualString(a, b string) bool {
+	decodedA, errA := base64.RawURLEncoding.DecodeString(a)
+	decodedB, errB := base64.RawURLEncoding.DecodeString(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return hmac.Equal(decodedA, decodedB)
+}
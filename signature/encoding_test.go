@@ -0,0 +1,104 @@
+package signature
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACSignerEncodings(t *testing.T) {
+	t.Parallel()
+
+	material := Material{
+		Timestamp:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		CanonicalBody: []byte(`{"a":1}`),
+	}
+	tests := map[string]struct {
+		encoding Encoding
+		padded   bool
+		urlSafe  bool
+	}{
+		"default":  {urlSafe: true},
+		"raw url":  {encoding: EncodingRawURL, urlSafe: true},
+		"standard": {encoding: EncodingStd, padded: true},
+		"raw std":  {encoding: EncodingRawStd},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key := []byte("secret")
+			sig, err := HMACSigner{Key: key, Encoding: tt.encoding}.Sign(material)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if strings.HasSuffix(sig, "=") != tt.padded {
+				t.Fatalf("unexpected padding in %q", sig)
+			}
+			if tt.urlSafe && strings.ContainsAny(sig, "+/") {
+				t.Fatalf("expected url-safe alphabet got %q", sig)
+			}
+			signed := material
+			signed.Signature = sig
+			if err := (HMACVerifier{Key: key, Encoding: tt.encoding}).Verify(context.Background(), signed); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodingDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		encoding Encoding
+		value    string
+		wantErr  string
+	}{
+		"padding for raw url":  {value: "YWJj+w==", wantErr: "expected raw-url base64"},
+		"url alphabet for std": {encoding: EncodingStd, value: "-_-_", wantErr: "expected std base64"},
+		"unknown encoding":     {encoding: "hex", value: "00", wantErr: `unsupported signature encoding "hex"`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tt.encoding.DecodeString(tt.value); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConstantTimeEqualString(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"equal":             {a: "c2lnbmF0dXJl", b: "c2lnbmF0dXJl", want: true},
+		"different":         {a: "c2lnbmF0dXJl", b: "c2lnbmF0dXJm"},
+		"length mismatch":   {a: "c2lnbmF0dXJl", b: "c2lnbmF0"},
+		"empty against set": {a: "", b: "c2lnbmF0dXJl"},
+		"both empty":        {a: "", b: "", want: true},
+		"invalid base64":    {a: "not base64!", b: "not base64!"},
+		"padded":            {a: "c2ln", b: "c2ln=="},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ConstantTimeEqualString(tt.a, tt.b); got != tt.want {
+				t.Fatalf("ConstantTimeEqualString(%q, %q) = %v want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := ConstantTimeEqualString(tt.b, tt.a); got != tt.want {
+				t.Fatalf("expected a symmetric result for %q and %q", tt.b, tt.a)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageSignature is a signature sent in the Signature-Input and Signature
+// header pair of RFC 9421 HTTP Message Signatures.
+type MessageSignature struct {
+	// Label names the signature in both headers, e.g. sig1.
+	Label string
+	// Components lists the covered component identifiers in signing order,
+	// e.g. @method, @path and content-digest.
+	Components []string
+	// Created is the created signature parameter, or the zero time.
+	Created time.Time
+	// Expires is the expires signature parameter, or the zero time.
+	Expires time.Time
+	// KeyID is the keyid signature parameter, or "".
+	KeyID string
+	// Alg is the alg signature parameter, or "".
+	Alg string
+	// Signature holds the decoded signature bytes.
+	Signature []byte
+
+	// params is the serialized signature parameters, signed as @signature-params.
+	params string
+}
+
+// ParseMessageSignature reads the first signature of the Signature-Input
+// header and its value from the Signature header. Component parameters such
+// as ;sf or ;key are not supported.
+func ParseMessageSignature(h http.Header) (MessageSignature, error) {
+	input := strings.TrimSpace(strings.Join(h.Values("Signature-Input"), ", "))
+	if input == "" {
+		return MessageSignature{}, errors.New("signature: Signature-Input header is required")
+	}
+	p := &sfParser{s: input}
+	label, err := p.key()
+	if err != nil {
+		return MessageSignature{}, err
+	}
+	if err := p.expect('='); err != nil {
+		return MessageSignature{}, err
+	}
+	start := p.i
+	sig := MessageSignature{Label: label}
+	if err := p.expect('('); err != nil {
+		return MessageSignature{}, err
+	}
+	seen := make(map[string]bool)
+	for {
+		p.skipSpaces()
+		if p.peek() == ')' {
+			p.i++
+			break
+		}
+		component, err := p.str()
+		if err != nil {
+			return MessageSignature{}, err
+		}
+		if p.peek() == ';' {
+			return MessageSignature{}, fmt.Errorf("signature: component parameters on %q are not supported", component)
+		}
+		if seen[component] {
+			return MessageSignature{}, fmt.Errorf("signature: component %q is covered twice", component)
+		}
+		seen[component] = true
+		sig.Components = append(sig.Components, component)
+	}
+	for p.peek() == ';' {
+		p.i++
+		name, err := p.key()
+		if err != nil {
+			return MessageSignature{}, err
+		}
+		if err := p.expect('='); err != nil {
+			return MessageSignature{}, err
+		}
+		switch name {
+		case "created", "expires":
+			value, err := p.integer()
+			if err != nil {
+				return MessageSignature{}, err
+			}
+			if name == "created" {
+				sig.Created = time.Unix(value, 0).UTC()
+			} else {
+				sig.Expires = time.Unix(value, 0).UTC()
+			}
+		case "keyid", "alg", "nonce", "tag":
+			value, err := p.str()
+			if err != nil {
+				return MessageSignature{}, err
+			}
+			switch name {
+			case "keyid":
+				sig.KeyID = value
+			case "alg":
+				sig.Alg = value
+			}
+		default:
+			return MessageSignature{}, fmt.Errorf("signature: unknown signature parameter %q", name)
+		}
+	}
+	sig.params = input[start:p.i]
+
+	value, err := signatureValue(strings.Join(h.Values("Signature"), ", "), label)
+	if err != nil {
+		return MessageSignature{}, err
+	}
+	sig.Signature = value
+	return sig, nil
+}
+
+// signatureValue returns the byte sequence stored under label in a Signature
+// header dictionary.
+func signatureValue(header, label string) ([]byte, error) {
+	for member := range strings.SplitSeq(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || name != label {
+			continue
+		}
+		encoded, ok := strings.CutPrefix(value, ":")
+		if encoded, ok = strings.CutSuffix(encoded, ":"); !ok {
+			return nil, fmt.Errorf("signature: Signature %s must be a byte sequence", label)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signature: decode Signature %s: %w", label, err)
+		}
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("signature: Signature header has no %s member", label)
+}
+
+// Covers reports whether component is covered by the signature.
+func (sig MessageSignature) Covers(component string) bool {
+	return slices.Contains(sig.Components, component)
+}
+
+// SignatureBase builds the RFC 9421 signature base of r, the bytes the
+// client signed. Derived components @method, @target-uri, @authority,
+// @scheme, @request-target, @path and @query are supported along with any
+// request header; a covered header missing from r is an error.
+func (sig MessageSignature) SignatureBase(r *http.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, component := range sig.Components {
+		value, err := componentValue(r, component)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%q: %s\n", component, value)
+	}
+	buf.WriteString(`"@signature-params": `)
+	buf.WriteString(sig.params)
+	return buf.Bytes(), nil
+}
+
+func componentValue(r *http.Request, component string) (string, error) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	switch component {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		return scheme + "://" + strings.ToLower(r.Host) + r.URL.RequestURI(), nil
+	case "@authority":
+		return strings.ToLower(r.Host), nil
+	case "@scheme":
+		return scheme, nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if path := r.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("signature: component %q is not supported", component)
+	}
+	values := r.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("signature: covered header %q is missing", component)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// VerifyContentDigest checks an RFC 9530 Content-Digest header against body.
+// At least one sha-256 or sha-512 digest must be present and all of them
+// must match.
+func VerifyContentDigest(header string, body []byte) error {
+	checked := false
+	for member := range strings.SplitSeq(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok {
+			continue
+		}
+		var sum []byte
+		switch name {
+		case "sha-256":
+			digest := sha256.Sum256(body)
+			sum = digest[:]
+		case "sha-512":
+			digest := sha512.Sum512(body)
+			sum = digest[:]
+		default:
+			continue
+		}
+		encoded, _ := strings.CutPrefix(value, ":")
+		encoded, _ = strings.CutSuffix(encoded, ":")
+		got, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || subtle.ConstantTimeCompare(got, sum) != 1 {
+			return fmt.Errorf("signature: Content-Digest %s does not match the body", name)
+		}
+		checked = true
+	}
+	if !checked {
+		return errors.New("signature: Content-Digest needs a sha-256 or sha-512 digest")
+	}
+	return nil
+}
+
+// sfParser reads the subset of RFC 8941 structured fields used by Signature-Input.
+type sfParser struct {
+	s string
+	i int
+}
+
+func (p *sfParser) peek() byte {
+	if p.i >= len(p.s) {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *sfParser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.i++
+	}
+}
+
+func (p *sfParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("signature: malformed Signature-Input, expected %q at %d", c, p.i)
+	}
+	p.i++
+	return nil
+}
+
+func (p *sfParser) key() (string, error) {
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		first := p.i == start
+		if c >= 'a' && c <= 'z' || c == '*' || !first && (c >= '0' && c <= '9' || strings.IndexByte("_-.", c) >= 0) {
+			p.i++
+			continue
+		}
+		break
+	}
+	if p.i == start {
+		return "", fmt.Errorf("signature: malformed Signature-Input, expected a key at %d", start)
+	}
+	return p.s[start:p.i], nil
+}
+
+func (p *sfParser) str() (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		p.i++
+		switch c {
+		case '\\':
+			if p.i >= len(p.s) || (p.s[p.i] != '"' && p.s[p.i] != '\\') {
+				return "", errors.New("signature: malformed Signature-Input string escape")
+			}
+			b.WriteByte(p.s[p.i])
+			p.i++
+		case '"':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("signature: unterminated Signature-Input string")
+}
+
+func (p *sfParser) integer() (int64, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.i++
+	}
+	value, err := strconv.ParseInt(p.s[start:p.i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("signature: malformed Signature-Input integer at %d", start)
+	}
+	return value, nil
+}
@@ -0,0 +1,138 @@
+package signature
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMessageSignature(t *testing.T) {
+	t.Parallel()
+
+	h := http.Header{}
+	h.Set("Signature-Input", `sig1=("@method" "@path" "content-digest");created=1735732800;expires=1735733100;keyid="key-1";alg="hmac-sha256"`)
+	h.Set("Signature", "sig1=:c2lnbmF0dXJl:")
+
+	sig, err := ParseMessageSignature(h)
+	if err != nil {
+		t.Fatalf("ParseMessageSignature: %v", err)
+	}
+	if sig.Label != "sig1" {
+		t.Fatalf("expected label sig1 got %q", sig.Label)
+	}
+	if got := strings.Join(sig.Components, ","); got != "@method,@path,content-digest" {
+		t.Fatalf("expected components got %q", got)
+	}
+	if !sig.Created.Equal(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected created %s", sig.Created)
+	}
+	if !sig.Expires.Equal(time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected expires %s", sig.Expires)
+	}
+	if sig.KeyID != "key-1" || sig.Alg != "hmac-sha256" {
+		t.Fatalf("unexpected keyid %q alg %q", sig.KeyID, sig.Alg)
+	}
+	if string(sig.Signature) != "signature" {
+		t.Fatalf("unexpected signature %q", sig.Signature)
+	}
+	if !sig.Covers("content-digest") || sig.Covers("authorization") {
+		t.Fatalf("unexpected Covers result for %v", sig.Components)
+	}
+}
+
+func TestParseMessageSignatureRejectsMalformedInput(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input     string
+		signature string
+	}{
+		"missing input":     {signature: "sig1=:c2ln:"},
+		"missing signature": {input: `sig1=("@method");created=1`},
+		"label mismatch":    {input: `sig1=("@method");created=1`, signature: "sig2=:c2ln:"},
+		"unclosed list":     {input: `sig1=("@method";created=1`, signature: "sig1=:c2ln:"},
+		"string created":    {input: `sig1=("@method");created="1"`, signature: "sig1=:c2ln:"},
+		"invalid base64":    {input: `sig1=("@method");created=1`, signature: "sig1=:!!:"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h := http.Header{}
+			if tc.input != "" {
+				h.Set("Signature-Input", tc.input)
+			}
+			if tc.signature != "" {
+				h.Set("Signature", tc.signature)
+			}
+			if _, err := ParseMessageSignature(h); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestMessageSignatureBase(t *testing.T) {
+	t.Parallel()
+
+	params := `("@method" "@target-uri" "@path" "@query" "content-type");created=1735732800;keyid="key-1"`
+	r := httptest.NewRequest(http.MethodPost, "http://Example.com/checkout_sessions?expand=items", nil)
+	r.Header.Set("Signature-Input", "sig1="+params)
+	r.Header.Set("Signature", "sig1=:c2ln:")
+	r.Header.Set("Content-Type", " application/json ")
+
+	sig, err := ParseMessageSignature(r.Header)
+	if err != nil {
+		t.Fatalf("ParseMessageSignature: %v", err)
+	}
+	base, err := sig.SignatureBase(r)
+	if err != nil {
+		t.Fatalf("SignatureBase: %v", err)
+	}
+	expected := `"@method": POST
+"@target-uri": http://example.com/checkout_sessions?expand=items
+"@path": /checkout_sessions
+"@query": ?expand=items
+"content-type": application/json
+"@signature-params": ` + params
+	if string(base) != expected {
+		t.Fatalf("expected base\n%s\ngot\n%s", expected, base)
+	}
+
+	sig.Components = append(sig.Components, "idempotency-key")
+	if _, err := sig.SignatureBase(r); err == nil {
+		t.Fatalf("expected error for missing covered header")
+	}
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"hello":"world"}`)
+	sum := sha256.Sum256(body)
+	valid := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+
+	tests := map[string]struct {
+		header  string
+		wantErr bool
+	}{
+		"match":              {header: valid},
+		"unknown and match":  {header: "md5=:AAAA:, " + valid},
+		"mismatch":           {header: "sha-256=:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ":", wantErr: true},
+		"unsupported only":   {header: "md5=:AAAA:", wantErr: true},
+		"missing":            {wantErr: true},
+		"malformed encoding": {header: "sha-256=:%%:", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := VerifyContentDigest(tc.header, body)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error=%v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
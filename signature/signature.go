@@ -5,17 +5,28 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	canonicaljson "github.com/gibson042/canonicaljson-go"
 )
 
+// maxPooledBufferSize caps the capacity of buffers returned to bufferPool.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
 // Material captures the inputs needed to validate a signed request.
 type Material struct {
 	Signature     string
@@ -25,6 +36,40 @@ type Material struct {
 	Path          string
 	RawQuery      string
 	Headers       http.Header
+	// SignedHeaders lists the header names covered by the signature in
+	// canonical order (see [BuildSigningPayloadWithHeaders]).
+	SignedHeaders []string
+	// KeyID is the keyid parameter of an RFC 9421 message signature.
+	KeyID string
+	// SignatureBase is the RFC 9421 signature base of a message signature
+	// (see [MessageSignature.SignatureBase]); empty for Timestamp signatures.
+	SignatureBase []byte
+	// Algorithm is the algorithm the client declared in the
+	// Signature-Algorithm header or the alg parameter of a message
+	// signature, if any (see [AlgorithmVerifiers]).
+	Algorithm string
+}
+
+// BodyHash returns the hex-encoded SHA-256 digest of CanonicalBody, suitable
+// for audit logs that must not store the body itself.
+func (m Material) BodyHash() string {
+	sum := sha256.Sum256(m.CanonicalBody)
+	return hex.EncodeToString(sum[:])
+}
+
+// SigningString returns the exact bytes the client signed: the
+// SignatureBase of RFC 9421 message signatures, or the payload built with
+// [BuildSigningPayload] otherwise. Custom [Verifier] implementations, such as
+// ones that delegate to a KMS, should verify against this rather than
+// rebuilding it.
+func (m Material) SigningString() []byte {
+	if len(m.SignatureBase) > 0 {
+		return m.SignatureBase
+	}
+	if len(m.SignedHeaders) == 0 {
+		return BuildSigningPayload(m.Timestamp, m.CanonicalBody)
+	}
+	return BuildSigningPayloadWithHeaders(m.Timestamp, m.SignedHeaders, m.Headers, m.CanonicalBody)
 }
 
 // Verifier validates the authenticity of incoming requests.
@@ -42,8 +87,12 @@ func (f VerifierFunc) Verify(ctx context.Context, material Material) error {
 
 // HMACVerifier validates signatures that were produced by taking the
 // base64url-encoded HMAC-SHA256 of `RFC3339(timestamp) + "." + canonicalJSON`.
+// Set Encoding for signers using another base64 variant; [HMACSigner]
+// produces matching signatures.
 type HMACVerifier struct {
 	Key []byte
+	// Encoding of the signature; defaults to [EncodingRawURL].
+	Encoding Encoding
 }
 
 // Verify implements [Verifier] by recomputing the expected HMAC signature.
@@ -51,15 +100,15 @@ func (v HMACVerifier) Verify(_ context.Context, material Material) error {
 	if len(v.Key) == 0 {
 		return errors.New("signature: HMACSignatureVerifier requires a non-empty key")
 	}
-	signingInput := BuildSigningPayload(material.Timestamp, material.CanonicalBody)
+	signingInput := material.SigningString()
 	mac := hmac.New(sha256.New, v.Key)
 	if _, err := mac.Write(signingInput); err != nil {
 		return fmt.Errorf("signature: compute signature: %w", err)
 	}
 	expected := mac.Sum(nil)
-	decoded, err := base64.RawURLEncoding.DecodeString(material.Signature)
+	decoded, err := v.Encoding.DecodeString(material.Signature)
 	if err != nil {
-		return fmt.Errorf("signature: decode signature: %w", err)
+		return err
 	}
 	if !hmac.Equal(decoded, expected) {
 		return errors.New("signature: invalid signature")
@@ -67,7 +116,33 @@ func (v HMACVerifier) Verify(_ context.Context, material Material) error {
 	return nil
 }
 
+// MultiVerifier accepts a signature when any of its verifiers does, letting
+// operators rotate keys without downtime: configure the new and the old key
+// until every signer switched, then drop the old one.
+type MultiVerifier []Verifier
+
+// Verify implements [Verifier] by trying each verifier in order. It returns
+// nil on the first success and the last error otherwise.
+func (m MultiVerifier) Verify(ctx context.Context, material Material) error {
+	err := errors.New("signature: MultiVerifier requires at least one verifier")
+	for _, verifier := range m {
+		if verifier == nil {
+			continue
+		}
+		if err = verifier.Verify(ctx, material); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// ErrTruncatedBody is returned by [ReadAndBufferBody] when the body ends
+// before the declared Content-Length.
+var ErrTruncatedBody = errors.New("signature: request body shorter than Content-Length")
+
 // ReadAndBufferBody reads the request body while keeping it accessible for later handlers.
+// Bodies shorter than a declared, non-chunked Content-Length are rejected with
+// [ErrTruncatedBody] so a truncated payload is never canonicalized and verified.
 func ReadAndBufferBody(r *http.Request) ([]byte, error) {
 	if r.Body == nil {
 		r.Body = io.NopCloser(bytes.NewReader(nil))
@@ -78,15 +153,50 @@ func ReadAndBufferBody(r *http.Request) ([]byte, error) {
 		return nil, err
 	}
 	_ = r.Body.Close()
+	if len(r.TransferEncoding) == 0 && r.ContentLength > 0 && int64(len(raw)) < r.ContentLength {
+		return nil, ErrTruncatedBody
+	}
 	r.Body = io.NopCloser(bytes.NewReader(raw))
 	return raw, nil
 }
 
-// CanonicalizeJSONBody normalizes arbitrary JSON into canonical form for signing.
-func CanonicalizeJSONBody(raw []byte) ([]byte, error) {
+// CanonicalFormVersion identifies the canonicalization rules implemented by
+// [CanonicalForm]. It only changes if the produced bytes change for any input.
+const CanonicalFormVersion = "acp-canonical-json/v1"
+
+// CanonicalForm returns the canonical JSON encoding of raw, the bytes that are
+// signed. Version [CanonicalFormVersion] is defined as:
+//
+//  1. An empty or whitespace-only body canonicalizes to `null`.
+//  2. The body must hold exactly one JSON document; trailing data is an error.
+//  3. Numbers are read as decimal literals, never as float64. Integral values
+//     (including `1.0` and `1e2`) are written as plain digits with no sign on
+//     zero; other values use capital-E exponential notation with one non-zero
+//     digit before the point, at least one after it, and no exponent `+` or
+//     leading zeros (`1.50` becomes `1.5E0`, `0.01` becomes `1.0E-2`).
+//  4. Object keys are sorted by Unicode code point; for duplicate keys the
+//     last value wins.
+//  5. Strings are UTF-8 with the shortest escapes: `\"`, `\\` and `\b`,
+//     `\f`, `\n`, `\r`, `\t` for those controls, `\u00XX` with uppercase hex
+//     for the other controls, and every other code point written literally.
+//  6. No insignificant whitespace is emitted.
+//
+// These are the rules of the canonical JSON spec at
+// https://gibson042.github.io/canonicaljson-spec/, which third-party signers
+// can implement to match byte-for-byte.
+//
+// Bodies that are already canonical (compact, sorted unique keys, integer
+// numbers, unescaped ASCII strings) are detected with a single linear scan and
+// copied as-is, skipping the decode and re-marshal. Clients that sign the same
+// bytes they send hit this path; other bodies pay for the scan on top of the
+// full round trip, which is small compared to the decode itself.
+func CanonicalForm(raw []byte) ([]byte, error) {
 	if len(bytes.TrimSpace(raw)) == 0 {
 		return []byte("null"), nil
 	}
+	if isCanonicalJSON(raw) {
+		return bytes.Clone(raw), nil
+	}
 	dec := json.NewDecoder(bytes.NewReader(raw))
 	dec.UseNumber()
 	var payload any
@@ -96,7 +206,39 @@ func CanonicalizeJSONBody(raw []byte) ([]byte, error) {
 	if dec.More() {
 		return nil, errors.New("signature: multiple JSON documents in body")
 	}
-	return canonicaljson.Marshal(payload)
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+	if err := canonicaljson.NewEncoder(buf).Encode(payload); err != nil {
+		return nil, err
+	}
+	// The encoder terminates every value with a newline that is not part of
+	// the canonical form, and the pooled buffer must not escape.
+	return bytes.Clone(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// CanonicalEqual reports whether a and b have the same [CanonicalForm]. Invalid
+// JSON is never equal to anything.
+func CanonicalEqual(a, b []byte) bool {
+	ca, err := CanonicalForm(a)
+	if err != nil {
+		return false
+	}
+	cb, err := CanonicalForm(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ca, cb)
+}
+
+// CanonicalizeJSONBody normalizes arbitrary JSON into canonical form for signing.
+// It is equivalent to [CanonicalForm].
+func CanonicalizeJSONBody(raw []byte) ([]byte, error) {
+	return CanonicalForm(raw)
 }
 
 // ParseTimestamp accepts Timestamp header values in RFC3339 or RFC3339Nano format.
@@ -110,6 +252,21 @@ func ParseTimestamp(value string) (time.Time, error) {
 	return time.Parse(time.RFC3339, value)
 }
 
+// ParseTimestampOrEpoch behaves like [ParseTimestamp] but also accepts an
+// integer number of seconds since the Unix epoch, as sent by some partners.
+// The signing payload still uses the RFC3339Nano form of the parsed time.
+func ParseTimestampOrEpoch(value string) (time.Time, error) {
+	ts, err := ParseTimestamp(value)
+	if err == nil {
+		return ts, nil
+	}
+	secs, convErr := strconv.ParseInt(value, 10, 64)
+	if convErr != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
 // AbsDuration returns the absolute value of the supplied duration.
 func AbsDuration(d time.Duration) time.Duration {
 	if d < 0 {
@@ -126,3 +283,41 @@ func BuildSigningPayload(ts time.Time, canonicalBody []byte) []byte {
 	buf.Write(canonicalBody)
 	return buf.Bytes()
 }
+
+// CanonicalHeaderNames lowercases, de-duplicates and sorts header names into
+// the order they appear in the signing payload.
+func CanonicalHeaderNames(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// BuildSigningPayloadWithHeaders extends [BuildSigningPayload] with header
+// values: `RFC3339(timestamp) + "." + name1:value1\n...nameN:valueN\n + canonicalJSON`.
+// Names are emitted lowercase in [CanonicalHeaderNames] order; repeated header
+// values are joined with ", " and a missing header contributes an empty value.
+func BuildSigningPayloadWithHeaders(ts time.Time, names []string, headers http.Header, canonicalBody []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(ts.UTC().Format(time.RFC3339Nano))
+	buf.WriteByte('.')
+	for _, name := range CanonicalHeaderNames(names) {
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(strings.TrimSpace(strings.Join(headers.Values(name), ", ")))
+		buf.WriteByte('\n')
+	}
+	buf.Write(canonicalBody)
+	return buf.Bytes()
+}
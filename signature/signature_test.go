@@ -0,0 +1,107 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestMultiVerifier(t *testing.T) {
+	t.Parallel()
+
+	oldKey, newKey := []byte("old-secret"), []byte("new-secret")
+	verifier := MultiVerifier{HMACVerifier{Key: newKey}, HMACVerifier{Key: oldKey}}
+
+	tests := map[string]struct {
+		verifier MultiVerifier
+		key      []byte
+		wantErr  bool
+	}{
+		"old key valid":      {verifier: verifier, key: oldKey},
+		"new key valid":      {verifier: verifier, key: newKey},
+		"both invalid":       {verifier: verifier, key: []byte("other-secret"), wantErr: true},
+		"no verifiers":       {verifier: MultiVerifier{}, key: newKey, wantErr: true},
+		"nil verifier skips": {verifier: MultiVerifier{nil, HMACVerifier{Key: newKey}}, key: newKey},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			material := Material{
+				Timestamp:     time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+				CanonicalBody: []byte(`{"id":"cs_123"}`),
+			}
+			mac := hmac.New(sha256.New, tt.key)
+			_, _ = mac.Write(material.SigningString())
+			material.Signature = base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+			err := tt.verifier.Verify(context.Background(), material)
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected verification to fail")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected verification to pass got %v", err)
+			}
+		})
+	}
+}
+
+func TestMaterialBodyHash(t *testing.T) {
+	t.Parallel()
+
+	material := Material{CanonicalBody: []byte(`{"id":"cs_123"}`)}
+	sum := sha256.Sum256(material.CanonicalBody)
+	if got, want := material.BodyHash(), hex.EncodeToString(sum[:]); got != want {
+		t.Fatalf("expected %s got %s", want, got)
+	}
+	if got := (Material{}).BodyHash(); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Fatalf("unexpected hash of empty body %s", got)
+	}
+}
+
+func TestParseTimestampOrEpoch(t *testing.T) {
+	t.Parallel()
+
+	want := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		"rfc3339":      {value: "2025-01-01T12:00:00Z", want: want},
+		"rfc3339 nano": {value: "2025-01-01T12:00:00.5Z", want: want.Add(500 * time.Millisecond)},
+		"epoch":        {value: "1735732800", want: want},
+		"fractional":   {value: "1735732800.5", wantErr: true},
+		"garbage":      {value: "yesterday", wantErr: true},
+		"empty":        {value: "", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseTimestampOrEpoch(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTimestampOrEpoch() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %v got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ParseTimestamp("1735732800"); err == nil {
+		t.Fatal("expected ParseTimestamp to reject epoch seconds")
+	}
+}
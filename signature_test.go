@@ -7,11 +7,17 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	canonicaljson "github.com/gibson042/canonicaljson-go"
+
 	"github.com/sumup/acp/signature"
 )
 
@@ -33,7 +39,7 @@ func TestSignatureMiddlewareAllowsValidRequest(t *testing.T) {
 				Links:              []Link{},
 			}, nil
 		},
-	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), checkoutWithClock(func() time.Time {
+	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithClock(func() time.Time {
 		return ts.Add(30 * time.Second)
 	}))
 
@@ -66,7 +72,7 @@ func TestSignatureMiddlewareRejectsInvalidSignature(t *testing.T) {
 		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
 			return &CheckoutSession{}, nil
 		},
-	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), checkoutWithClock(func() time.Time {
+	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithClock(func() time.Time {
 		return ts
 	}))
 
@@ -93,7 +99,7 @@ func TestSignatureMiddlewareRejectsSkew(t *testing.T) {
 		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
 			return &CheckoutSession{}, nil
 		},
-	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithMaxClockSkew(time.Minute), checkoutWithClock(func() time.Time {
+	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithMaxClockSkew(time.Minute), WithClock(func() time.Time {
 		return ts.Add(2 * time.Minute)
 	}))
 
@@ -127,7 +133,7 @@ func TestSignatureMiddlewareRequiresHeadersWhenEnforced(t *testing.T) {
 		get: func(ctx context.Context, id string) (*CheckoutSession, error) {
 			return &CheckoutSession{}, nil
 		},
-	}, WithSignatureVerifier(signature.HMACVerifier{Key: []byte("secret")}), WithRequireSignedRequests(), checkoutWithClock(time.Now))
+	}, WithSignatureVerifier(signature.HMACVerifier{Key: []byte("secret")}), WithRequireSignedRequests(), WithClock(time.Now))
 
 	req := httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil)
 	rec := httptest.NewRecorder()
@@ -142,6 +148,159 @@ func TestSignatureMiddlewareRequiresHeadersWhenEnforced(t *testing.T) {
 	}
 }
 
+func TestMaterialSigningStringMatchesHMACPayload(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	canonical, err := signature.CanonicalizeJSONBody([]byte(`{"items":[{"id":"sku_1","quantity":1}]}`))
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+	material := signature.Material{
+		Timestamp:     ts,
+		CanonicalBody: canonical,
+	}
+
+	want := signature.BuildSigningPayload(ts, canonical)
+	if got := material.SigningString(); !bytes.Equal(got, want) {
+		t.Fatalf("SigningString() = %s want %s", got, want)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(material.SigningString())
+	material.Signature = base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if err := (signature.HMACVerifier{Key: key}).Verify(context.Background(), material); err != nil {
+		t.Fatalf("HMACVerifier rejected signature over SigningString(): %v", err)
+	}
+}
+
+func TestSignatureMiddlewareVerifiesSignedHeaders(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+	signed := http.Header{}
+	signed.Set("Idempotency-Key", "idem_123")
+	payload := signature.BuildSigningPayloadWithHeaders(ts, []string{"Idempotency-Key"}, signed, canonical)
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(payload)
+	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := map[string]struct {
+		idempotencyKey string
+		wantStatus     int
+	}{
+		"matching header": {
+			idempotencyKey: "idem_123",
+			wantStatus:     http.StatusCreated,
+		},
+		"mismatched header": {
+			idempotencyKey: "idem_456",
+			wantStatus:     http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: "cs_123"}, nil
+				},
+			}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithSignedHeaders("idempotency-key"), WithClock(func() time.Time {
+				return ts
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			req.Header.Set("Signature", sig)
+			req.Header.Set("Timestamp", ts.Format(time.RFC3339Nano))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d got %d body=%s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBuildSigningPayloadWithHeadersIsCanonical(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	headers := http.Header{}
+	headers.Set("Request-Id", "req_1")
+	headers.Set("Idempotency-Key", "idem_1")
+
+	got := signature.BuildSigningPayloadWithHeaders(ts, []string{"Request-Id", "idempotency-key", "REQUEST-ID"}, headers, []byte(`{}`))
+	want := "2025-01-01T12:00:00Z.idempotency-key:idem_1\nrequest-id:req_1\n{}"
+	if string(got) != want {
+		t.Fatalf("unexpected payload %q want %q", got, want)
+	}
+}
+
+func TestReadAndBufferBodyRejectsTruncatedBody(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+	req.ContentLength = int64(len(body) + 10)
+
+	if _, err := signature.ReadAndBufferBody(req); !errors.Is(err, signature.ErrTruncatedBody) {
+		t.Fatalf("expected ErrTruncatedBody got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+	req.ContentLength = int64(len(body) + 10)
+	req.TransferEncoding = []string{"chunked"}
+	if _, err := signature.ReadAndBufferBody(req); err != nil {
+		t.Fatalf("chunked bodies must not be checked against Content-Length: %v", err)
+	}
+}
+
+func TestSignatureMiddlewareRejectsTruncatedBody(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewCheckoutHandler(&stubService{
+		create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+			t.Errorf("provider must not be called for truncated bodies")
+			return &CheckoutSession{}, nil
+		},
+	}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithClock(func() time.Time {
+		return ts
+	}))
+
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+	req.ContentLength = int64(len(body) * 2)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Signature", signFixture(key, ts, canonical))
+	req.Header.Set("Timestamp", ts.Format(time.RFC3339Nano))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
 func signFixture(key []byte, ts time.Time, canonical []byte) string {
 	payload := signature.BuildSigningPayload(ts, canonical)
 	mac := hmac.New(sha256.New, key)
@@ -156,3 +315,539 @@ func getErrorCode(body []byte) string {
 	}
 	return string(resp.Code)
 }
+
+func TestCanonicalizeJSONBodyMatchesCanonicalMarshal(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"b":2,"a":{"z":true,"y":[1,2.5,"x"]},"c":null}`)
+	for range 3 {
+		got, err := signature.CanonicalizeJSONBody(body)
+		if err != nil {
+			t.Fatalf("canonicalize: %v", err)
+		}
+		if want := `{"a":{"y":[1,2.5E0,"x"],"z":true},"b":2,"c":null}`; string(got) != want {
+			t.Fatalf("unexpected canonical body %s want %s", got, want)
+		}
+	}
+}
+
+func TestCanonicalizeJSONBodyFastPathMatchesReference(t *testing.T) {
+	t.Parallel()
+
+	inputs := []string{
+		`{}`,
+		`[]`,
+		`0`,
+		`-0`,
+		`-12`,
+		`[0,10]`,
+		`1.50`,
+		`1e3`,
+		`true`,
+		`"plain"`,
+		`"esc\"aped"`,
+		`"caf\u00e9"`,
+		`"café"`,
+		`{"a":1,"b":[1,2,{"c":null,"d":false}]}`,
+		`{"b":1,"a":2}`,
+		`{"a":1,"a":2}`,
+		`{"a": 1}`,
+		`{"a":1}` + "\n",
+		`{"items":[{"id":"sku_1","quantity":1}]}`,
+	}
+	for _, input := range inputs {
+		got, err := signature.CanonicalizeJSONBody([]byte(input))
+		if err != nil {
+			t.Fatalf("canonicalize %s: %v", input, err)
+		}
+		var decoded any
+		dec := json.NewDecoder(bytes.NewReader([]byte(input)))
+		dec.UseNumber()
+		if err := dec.Decode(&decoded); err != nil {
+			t.Fatalf("decode %s: %v", input, err)
+		}
+		want, err := canonicaljson.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", input, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("canonicalize %s = %s want %s", input, got, want)
+		}
+	}
+
+	for _, input := range []string{`{"a":1`, `[1,]`, `{"a"1}`, `tru`} {
+		if _, err := signature.CanonicalizeJSONBody([]byte(input)); err == nil {
+			t.Fatalf("expected error for invalid JSON %s", input)
+		}
+	}
+}
+
+func BenchmarkCanonicalizeJSONBody(b *testing.B) {
+	small, err := json.Marshal(sampleDelegatePaymentRequest())
+	if err != nil {
+		b.Fatalf("marshal request: %v", err)
+	}
+	items := make([]Item, 2000)
+	for i := range items {
+		items[i] = Item{ID: fmt.Sprintf("sku_%d", i), Quantity: i + 1}
+	}
+	large, err := json.MarshalIndent(CheckoutSessionCreateRequest{Items: items}, "", "  ")
+	if err != nil {
+		b.Fatalf("marshal request: %v", err)
+	}
+	largeCanonical, err := signature.CanonicalizeJSONBody(large)
+	if err != nil {
+		b.Fatalf("canonicalize: %v", err)
+	}
+
+	benchmarks := map[string][]byte{
+		"small":           small,
+		"large":           large,
+		"large canonical": largeCanonical,
+	}
+	for name, body := range benchmarks {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for b.Loop() {
+				if _, err := signature.CanonicalizeJSONBody(body); err != nil {
+					b.Fatalf("canonicalize: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithClockRejectsNil(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewCheckoutHandlerWithError(&stubService{}, WithClock(nil)); err == nil {
+		t.Fatalf("expected error for nil clock")
+	}
+}
+
+func TestSignatureMiddlewareHTTPMessageFormat(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+
+	signedRequest := func(mutate func(r *http.Request)) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		digest := sha256.Sum256(body)
+		req.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":")
+		params := fmt.Sprintf(`("@method" "@path" "content-digest");created=%d;keyid="key-1"`, created.Unix())
+		req.Header.Set("Signature-Input", "sig1="+params)
+		base := "\"@method\": POST\n\"@path\": /checkout_sessions\n\"content-digest\": " + req.Header.Get("Content-Digest") + "\n\"@signature-params\": " + params
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write([]byte(base))
+		req.Header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(mac.Sum(nil))+":")
+		if mutate != nil {
+			mutate(req)
+		}
+		return req
+	}
+	// resignedRequest signs the request again with other signature parameters.
+	resignedRequest := func(params string) *http.Request {
+		req := signedRequest(nil)
+		req.Header.Set("Signature-Input", "sig1="+params)
+		sig, err := signature.ParseMessageSignature(req.Header)
+		if err != nil {
+			t.Fatalf("parse signature: %v", err)
+		}
+		base, err := sig.SignatureBase(req)
+		if err != nil {
+			t.Fatalf("signature base: %v", err)
+		}
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write(base)
+		req.Header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(mac.Sum(nil))+":")
+		return req
+	}
+
+	tests := map[string]struct {
+		req          *http.Request
+		expectedCode int
+		errorCode    string
+	}{
+		"valid": {
+			req:          signedRequest(nil),
+			expectedCode: http.StatusCreated,
+		},
+		"tampered body": {
+			req: signedRequest(func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"items":[{"id":"sku_2","quantity":1}]}`))
+			}),
+			expectedCode: http.StatusUnauthorized,
+			errorCode:    "invalid_signature",
+		},
+		"tampered path": {
+			req: signedRequest(func(r *http.Request) {
+				r.URL.Path = "/checkout_sessions/cs_123"
+			}),
+			expectedCode: http.StatusUnauthorized,
+			errorCode:    "invalid_signature",
+		},
+		"legacy headers": {
+			req: signedRequest(func(r *http.Request) {
+				r.Header.Del("Signature-Input")
+				r.Header.Set("Signature", signFixture(key, created, body))
+				r.Header.Set("Timestamp", created.Format(time.RFC3339))
+			}),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"digest not covered": {
+			req: signedRequest(func(r *http.Request) {
+				r.Header.Set("Signature-Input", fmt.Sprintf(`sig1=("@method" "@path");created=%d`, created.Unix()))
+			}),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"missing created": {
+			req: signedRequest(func(r *http.Request) {
+				r.Header.Set("Signature-Input", `sig1=("@method" "@path" "content-digest");keyid="key-1"`)
+			}),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"method not covered": {
+			req:          resignedRequest(fmt.Sprintf(`("@path" "content-digest");created=%d`, created.Unix())),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"path not covered": {
+			req:          resignedRequest(fmt.Sprintf(`("@method" "content-digest");created=%d`, created.Unix())),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"digest only": {
+			req:          resignedRequest(fmt.Sprintf(`("content-digest");created=%d`, created.Unix())),
+			expectedCode: http.StatusBadRequest,
+			errorCode:    "invalid_signature",
+		},
+		"target uri": {
+			req:          resignedRequest(fmt.Sprintf(`("@method" "@target-uri" "content-digest");created=%d`, created.Unix())),
+			expectedCode: http.StatusCreated,
+		},
+		"expired": {
+			req:          resignedRequest(fmt.Sprintf(`("@method" "@path" "content-digest");created=%d;expires=%d`, created.Unix(), created.Add(10*time.Second).Unix())),
+			expectedCode: http.StatusUnauthorized,
+			errorCode:    "stale_timestamp",
+		},
+		"not yet expired": {
+			req:          resignedRequest(fmt.Sprintf(`("@method" "@path" "content-digest");created=%d;expires=%d`, created.Unix(), created.Add(time.Minute).Unix())),
+			expectedCode: http.StatusCreated,
+		},
+		"unsigned": {
+			req: signedRequest(func(r *http.Request) {
+				r.Header.Del("Signature-Input")
+				r.Header.Del("Signature")
+			}),
+			expectedCode: http.StatusUnauthorized,
+			errorCode:    "signature_required",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusInProgress}, nil
+				},
+			},
+				WithSignatureVerifier(signature.HMACVerifier{Key: key}),
+				WithSignatureFormat(SignatureFormatHTTPMessage),
+				WithRequireSignedRequests(),
+				WithClock(func() time.Time { return created.Add(30 * time.Second) }),
+			)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, tc.req)
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected %d got %d body=%s", tc.expectedCode, rec.Code, rec.Body.String())
+			}
+			if tc.errorCode != "" {
+				if got := getErrorCode(rec.Body.Bytes()); got != tc.errorCode {
+					t.Fatalf("expected code %s got %s", tc.errorCode, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSignatureFormatRejectsUnknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewCheckoutHandlerWithError(&stubService{}, WithSignatureFormat(SignatureFormat(42))); err == nil {
+		t.Fatalf("expected error for unknown signature format")
+	}
+}
+
+func TestSignatureMiddlewareDispatchesOnAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+
+	tests := map[string]struct {
+		algorithm    string
+		expectedCode int
+		message      string
+	}{
+		"absent defaults to hmac": {expectedCode: http.StatusCreated},
+		"declared hmac":           {algorithm: "hmac-sha256", expectedCode: http.StatusCreated},
+		"unconfigured algorithm": {
+			algorithm:    "ed25519",
+			expectedCode: http.StatusUnauthorized,
+			message:      `signature algorithm "ed25519" is not supported`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: "cs_123"}, nil
+				},
+			}, WithSignatureVerifier(signature.AlgorithmVerifiers{
+				signature.AlgorithmHMACSHA256: signature.HMACVerifier{Key: key},
+			}), WithClock(func() time.Time { return ts }))
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Signature", signFixture(key, ts, canonical))
+			req.Header.Set("Timestamp", ts.Format(time.RFC3339))
+			if tc.algorithm != "" {
+				req.Header.Set("Signature-Algorithm", tc.algorithm)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected %d got %d body=%s", tc.expectedCode, rec.Code, rec.Body.String())
+			}
+			if tc.message == "" {
+				return
+			}
+			var payload Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if payload.Code != InvalidSignature || payload.Message != tc.message {
+				t.Fatalf("expected %s %q got %s %q", InvalidSignature, tc.message, payload.Code, payload.Message)
+			}
+		})
+	}
+}
+
+func TestCanonicalBodyFromContext(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{ "items": [ { "quantity": 1, "id": "sku_1" } ] }`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+
+	tests := map[string]struct {
+		signed bool
+		want   []byte
+	}{
+		"signed":   {signed: true, want: canonical},
+		"unsigned": {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				got []byte
+				ok  bool
+			)
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					got, ok = CanonicalBodyFromContext(ctx)
+					return &CheckoutSession{ID: "cs_123"}, nil
+				},
+			}, WithSignatureVerifier(signature.HMACVerifier{Key: key}), WithClock(func() time.Time { return ts }))
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			if tt.signed {
+				req.Header.Set("Signature", signFixture(key, ts, canonical))
+				req.Header.Set("Timestamp", ts.Format(time.RFC3339))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected 201 got %d body=%s", rec.Code, rec.Body.String())
+			}
+			if ok != tt.signed || !bytes.Equal(got, tt.want) {
+				t.Fatalf("expected %q (%v) got %q (%v)", tt.want, tt.signed, got, ok)
+			}
+		})
+	}
+}
+
+func TestSignatureMiddlewareTimestampParser(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+	sig := signFixture(key, ts, canonical)
+
+	rejectAll := func(string) (time.Time, error) { return time.Time{}, errors.New("not a unix timestamp") }
+	tests := map[string]struct {
+		opts      []Option
+		timestamp string
+		want      int
+		wantMsg   string
+	}{
+		"default accepts rfc3339": {
+			timestamp: ts.Format(time.RFC3339),
+			want:      http.StatusCreated,
+		},
+		"default rejects epoch": {
+			timestamp: "1735732800",
+			want:      http.StatusBadRequest,
+			wantMsg:   "Timestamp is invalid",
+		},
+		"epoch parser accepts epoch": {
+			opts:      []Option{WithTimestampParser(signature.ParseTimestampOrEpoch)},
+			timestamp: "1735732800",
+			want:      http.StatusCreated,
+		},
+		"epoch parser accepts rfc3339": {
+			opts:      []Option{WithTimestampParser(signature.ParseTimestampOrEpoch)},
+			timestamp: ts.Format(time.RFC3339),
+			want:      http.StatusCreated,
+		},
+		"custom parser rejects rfc3339": {
+			opts:      []Option{WithTimestampParser(rejectAll)},
+			timestamp: ts.Format(time.RFC3339),
+			want:      http.StatusBadRequest,
+			wantMsg:   "Timestamp is invalid",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{
+				WithSignatureVerifier(signature.HMACVerifier{Key: key}),
+				WithClock(func() time.Time { return ts }),
+			}, tt.opts...)
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusInProgress, Currency: "usd"}, nil
+				},
+			}, opts...)
+
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Signature", sig)
+			req.Header.Set("Timestamp", tt.timestamp)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d body=%s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.wantMsg != "" && !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Fatalf("expected message %q got %s", tt.wantMsg, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSignatureStatusFromContext(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"items":[{"id":"sku_1","quantity":1}]}`)
+	canonical, err := signature.CanonicalizeJSONBody(body)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+
+	tests := map[string]struct {
+		verifier  bool
+		signature string
+		want      SignatureStatus
+	}{
+		"no verifier":   {signature: signFixture(key, ts, canonical), want: SignatureStatusUnsigned},
+		"unsigned":      {verifier: true, want: SignatureStatusUnsigned},
+		"verified":      {verifier: true, signature: signFixture(key, ts, canonical), want: SignatureStatusVerified},
+		"bad signature": {verifier: true, signature: signFixture([]byte("other"), ts, canonical), want: SignatureStatusFailed},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var fromProvider, fromMiddleware SignatureStatus
+			opts := []Option{
+				WithClock(func() time.Time { return ts }),
+				// Outer middleware observes the status after the request was handled.
+				WithMiddleware(func(next http.HandlerFunc) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						next(w, r)
+						fromMiddleware = SignatureStatusFromContext(r.Context())
+					}
+				}),
+			}
+			if tt.verifier {
+				opts = append(opts, WithSignatureVerifier(signature.HMACVerifier{Key: key}))
+			}
+			handler := NewCheckoutHandler(&stubService{
+				create: func(ctx context.Context, req CheckoutSessionCreateRequest) (*CheckoutSession, error) {
+					fromProvider = SignatureStatusFromContext(ctx)
+					return &CheckoutSession{ID: "cs_123", Status: CheckoutSessionStatusInProgress, Currency: "usd"}, nil
+				},
+			}, opts...)
+
+			req := httptest.NewRequest(http.MethodPost, "/checkout_sessions", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signature != "" {
+				req.Header.Set("Signature", tt.signature)
+				req.Header.Set("Timestamp", ts.Format(time.RFC3339))
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if fromMiddleware != tt.want {
+				t.Fatalf("expected status %s got %s", tt.want, fromMiddleware)
+			}
+			if tt.want != SignatureStatusFailed && fromProvider != tt.want {
+				t.Fatalf("expected provider to see %s got %s", tt.want, fromProvider)
+			}
+		})
+	}
+
+	if got := SignatureStatusFromContext(context.Background()); got != SignatureStatusUnsigned {
+		t.Fatalf("expected unsigned outside a handler got %s", got)
+	}
+}
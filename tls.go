@@ -0,0 +1,46 @@
+package acp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequireTLSMiddleware rejects requests that did not arrive over HTTPS with a
+// 400 [TLSRequired] error. A request qualifies when r.TLS is set or, when
+// forwardedProtoHeader is not empty, when that header, such as
+// X-Forwarded-Proto, names https as its last entry, the one added by the
+// nearest proxy. Only pass a header that the proxy in front of the handler
+// sets or appends to: clients reaching the handler directly could otherwise
+// spoof it. With an empty forwardedProtoHeader only r.TLS is trusted.
+// Install it with [WithMiddleware].
+func RequireTLSMiddleware(forwardedProtoHeader string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !requestIsHTTPS(r, forwardedProtoHeader) {
+				writeJSONError(w, NewHTTPError(http.StatusBadRequest, InvalidRequest, TLSRequired, "requests must be made over HTTPS"))
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+func requestIsHTTPS(r *http.Request, forwardedProtoHeader string) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if forwardedProtoHeader == "" {
+		return false
+	}
+	// Proxies chaining the header append to it, so only the last entry was
+	// set by the trusted proxy; earlier ones may come from the client.
+	values := r.Header.Values(forwardedProtoHeader)
+	if len(values) == 0 {
+		return false
+	}
+	last := values[len(values)-1]
+	if i := strings.LastIndexByte(last, ','); i >= 0 {
+		last = last[i+1:]
+	}
+	return strings.EqualFold(strings.TrimSpace(last), "https")
+}
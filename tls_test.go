@@ -0,0 +1,60 @@
+package acp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireTLSMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		header     string
+		tls        bool
+		proto      string
+		wantStatus int
+	}{
+		"direct tls":                 {tls: true, wantStatus: http.StatusOK},
+		"plaintext":                  {wantStatus: http.StatusBadRequest},
+		"forwarded https":            {header: "X-Forwarded-Proto", proto: "https", wantStatus: http.StatusOK},
+		"forwarded https mixed case": {header: "X-Forwarded-Proto", proto: "HTTPS", wantStatus: http.StatusOK},
+		"forwarded chain":            {header: "X-Forwarded-Proto", proto: "http, https", wantStatus: http.StatusOK},
+		"spoofed forwarded https":    {header: "X-Forwarded-Proto", proto: "https, http", wantStatus: http.StatusBadRequest},
+		"forwarded http":             {header: "X-Forwarded-Proto", proto: "http", wantStatus: http.StatusBadRequest},
+		"forwarded header missing":   {header: "X-Forwarded-Proto", wantStatus: http.StatusBadRequest},
+		"untrusted forwarded header": {proto: "https", wantStatus: http.StatusBadRequest},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewCheckoutHandler(&stubService{
+				get: func(ctx context.Context, id string) (*CheckoutSession, error) {
+					return &CheckoutSession{ID: id}, nil
+				},
+			}, WithMiddleware(RequireTLSMiddleware(tt.header)))
+			req := httptest.NewRequest(http.MethodGet, "/checkout_sessions/cs_123", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				if got := getErrorCode(rec.Body.Bytes()); got != string(TLSRequired) {
+					t.Fatalf("expected code %s got %s", TLSRequired, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package acp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationMode selects how strictly delegated payment requests are validated.
+type ValidationMode int
+
+const (
+	// ValidationModeStrict rejects every request that violates the spec. It is
+	// the default and the mode production deployments should use.
+	ValidationModeStrict ValidationMode = iota
+	// ValidationModeLenient accepts requests that only miss the card display
+	// metadata listed on [WithValidationMode], reporting each relaxed rule as
+	// an info message instead. Meant for sandbox integrations.
+	ValidationModeLenient
+)
+
+// lenientRules lists the validator tags relaxed by [ValidationModeLenient],
+// keyed by the field path reported in errors.
+var lenientRules = map[string]map[string]bool{
+	"payment_method.display_card_funding_type": {"required": true},
+	"payment_method.display_last4":             {"len": true},
+}
+
+// WithValidationMode controls how delegated payment requests are validated.
+// In [ValidationModeLenient] the following rules become warnings, returned in
+// the messages of the response instead of failing the request:
+//
+//   - payment_method.display_card_funding_type may be omitted
+//   - payment_method.display_last4 may have a length other than 4
+//
+// A display_card_funding_type that is present but not credit, debit or
+// prepaid is still rejected, as is every other rule. Defaults to
+// [ValidationModeStrict].
+func WithValidationMode(mode ValidationMode) Option {
+	switch mode {
+	case ValidationModeStrict, ValidationModeLenient:
+	default:
+		return invalidOption(fmt.Errorf("acp: unknown validation mode %d", mode))
+	}
+	return func(cfg *config) {
+		cfg.validationMode = mode
+	}
+}
+
+// validateLenient validates r like [PaymentRequest.Validate] but turns the
+// failures of [lenientRules] into info messages.
+func (r PaymentRequest) validateLenient() ([]MessageInfo, error) {
+	err := validate.Struct(r)
+	if err == nil {
+		return nil, nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+	var (
+		warnings []MessageInfo
+		failures validator.ValidationErrors
+	)
+	for _, fe := range validationErrs {
+		path := jsonPath(fe)
+		if !lenientRules[path][fe.Tag()] {
+			failures = append(failures, fe)
+			continue
+		}
+		param := "$." + path
+		warnings = append(warnings, MessageInfo{
+			Type:        "info",
+			ContentType: MessageInfoContentTypePlain,
+			Content:     fmt.Sprintf("%s %s", path, validationMessage(fe)),
+			Param:       &param,
+		})
+	}
+	if len(failures) > 0 {
+		return nil, normalizeValidationError(failures)
+	}
+	return warnings, nil
+}
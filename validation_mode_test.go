@@ -0,0 +1,108 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithValidationMode(t *testing.T) {
+	t.Parallel()
+
+	shortLast4 := "42"
+	tests := map[string]struct {
+		mode         ValidationMode
+		mutate       func(*PaymentRequest)
+		wantStatus   int
+		wantWarnings []string
+	}{
+		"strict rejects missing funding type": {
+			mode:       ValidationModeStrict,
+			mutate:     func(r *PaymentRequest) { updateCard(r, func(c *PaymentMethodCard) { c.DisplayCardFundingType = "" }) },
+			wantStatus: http.StatusBadRequest,
+		},
+		"lenient warns on missing funding type": {
+			mode:         ValidationModeLenient,
+			mutate:       func(r *PaymentRequest) { updateCard(r, func(c *PaymentMethodCard) { c.DisplayCardFundingType = "" }) },
+			wantStatus:   http.StatusCreated,
+			wantWarnings: []string{"$.payment_method.display_card_funding_type"},
+		},
+		"lenient warns on every relaxed field": {
+			mode: ValidationModeLenient,
+			mutate: func(r *PaymentRequest) {
+				updateCard(r, func(c *PaymentMethodCard) { c.DisplayCardFundingType = "" })
+				updateCard(r, func(c *PaymentMethodCard) { c.DisplayLast4 = &shortLast4 })
+			},
+			wantStatus:   http.StatusCreated,
+			wantWarnings: []string{"$.payment_method.display_last4", "$.payment_method.display_card_funding_type"},
+		},
+		"lenient rejects unknown funding type": {
+			mode: ValidationModeLenient,
+			mutate: func(r *PaymentRequest) {
+				updateCard(r, func(c *PaymentMethodCard) { c.DisplayCardFundingType = "charge" })
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		"lenient rejects other rules": {
+			mode: ValidationModeLenient,
+			mutate: func(r *PaymentRequest) {
+				updateCard(r, func(c *PaymentMethodCard) { c.DisplayCardFundingType = "" })
+				r.Allowance.MerchantID = ""
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		"lenient valid request has no warnings": {
+			mode:       ValidationModeLenient,
+			mutate:     func(*PaymentRequest) {},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := sampleDelegatePaymentRequest()
+			tt.mutate(&payload)
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/agentic_commerce/delegate_payment", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			NewDelegatedPaymentHandler(successService(), WithValidationMode(tt.mode)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+			var token VaultToken
+			if err := json.Unmarshal(rec.Body.Bytes(), &token); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(token.Messages) != len(tt.wantWarnings) {
+				t.Fatalf("expected %d warnings got %+v", len(tt.wantWarnings), token.Messages)
+			}
+			for i, want := range tt.wantWarnings {
+				msg := token.Messages[i]
+				if msg.Type != "info" || msg.Param == nil || *msg.Param != want {
+					t.Fatalf("expected info warning for %s got %+v", want, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestWithValidationModeRejectsUnknownMode(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewDelegatedPaymentHandlerWithError(successService(), WithValidationMode(ValidationMode(7))); err == nil {
+		t.Fatalf("expected error for unknown validation mode")
+	}
+}
@@ -0,0 +1,106 @@
+package acp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldRule is a single validator constraint on a request field.
+type FieldRule struct {
+	// Path is the JSONPath of the field, matching [Error] params. Elements of
+	// validated slices and maps are addressed with [*].
+	Path string `json:"path"`
+	// Tag is the go-playground/validator tag, such as required or gt.
+	Tag string `json:"tag"`
+	// Param is the tag parameter, such as 0 for gt=0, or empty.
+	Param string `json:"param,omitempty"`
+}
+
+// ValidationRules lists the validator rules that apply to v, a struct or a
+// pointer to one, in field order. Nested structs are walked so SDKs in other
+// languages can mirror the Go validation.
+func ValidationRules(v any) []FieldRule {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var rules []FieldRule
+	collectValidationRules(t, "$", map[reflect.Type]bool{}, &rules)
+	return rules
+}
+
+func collectValidationRules(t reflect.Type, prefix string, visiting map[reflect.Type]bool, rules *[]FieldRule) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		path := prefix + "." + name
+		for tag := range strings.SplitSeq(field.Tag.Get("validate"), ",") {
+			if tag == "" || tag == "-" {
+				continue
+			}
+			if tag == "dive" {
+				path += "[*]"
+				continue
+			}
+			tag, param, _ := strings.Cut(tag, "=")
+			*rules = append(*rules, FieldRule{Path: path, Tag: tag, Param: param})
+		}
+		if nested := structType(field.Type); nested != nil {
+			elemPath := prefix + "." + name
+			if nested != derefType(field.Type) {
+				elemPath += "[*]"
+			}
+			if nested == reflect.TypeFor[PaymentMethod]() {
+				for _, variant := range paymentMethodVariants {
+					collectValidationRules(variant, elemPath, visiting, rules)
+				}
+				continue
+			}
+			collectValidationRules(nested, elemPath, visiting, rules)
+		}
+	}
+	for _, rule := range conditionalRules[t] {
+		rule.Path = prefix + "." + rule.Path
+		*rules = append(*rules, rule)
+	}
+}
+
+// structType returns the struct type reached through pointers, slices and
+// map values of t, or nil.
+func structType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
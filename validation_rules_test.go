@@ -0,0 +1,58 @@
+package acp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidationRules(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		v    any
+		want []FieldRule
+	}{
+		"allowance": {
+			v: Allowance{},
+			want: []FieldRule{
+				{Path: "$.currency", Tag: "required"},
+				{Path: "$.currency", Tag: "currency"},
+				{Path: "$.max_amount", Tag: "gt", Param: "0"},
+				{Path: "$.reason", Tag: "eq", Param: "one_time"},
+			},
+		},
+		"nested payment request": {
+			v: &PaymentRequest{},
+			want: []FieldRule{
+				{Path: "$.allowance.max_amount", Tag: "gt", Param: "0"},
+				{Path: "$.payment_method.card_number_type", Tag: "oneof", Param: "fpan network_token"},
+				{Path: "$.risk_signals", Tag: "min", Param: "1"},
+				{Path: "$.risk_signals[*].score", Tag: "gte", Param: "0"},
+				{Path: "$.payment_method.exp_year", Tag: "required_with", Param: "exp_month"},
+				{Path: "$.payment_method.cryptogram", Tag: "required_if", Param: "card_number_type network_token"},
+				{Path: "$.payment_method.eci_value", Tag: "excluded_if", Param: "card_number_type fpan"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rules := ValidationRules(tt.v)
+			for _, want := range tt.want {
+				if !slices.Contains(rules, want) {
+					t.Fatalf("expected rule %+v in %+v", want, rules)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationRulesNonStruct(t *testing.T) {
+	t.Parallel()
+
+	if rules := ValidationRules("allowance"); rules != nil {
+		t.Fatalf("expected no rules for non-struct got %+v", rules)
+	}
+}